@@ -0,0 +1,5 @@
+// Package studentsv1 holds the protobuf messages and gRPC stubs of the
+// StudentService, generated from students.proto.
+package studentsv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative students.proto
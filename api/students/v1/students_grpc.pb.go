@@ -0,0 +1,398 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.23.4
+// source: students.proto
+
+package studentsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	StudentService_GetStudent_FullMethodName         = "/students.v1.StudentService/GetStudent"
+	StudentService_BatchGetStudents_FullMethodName   = "/students.v1.StudentService/BatchGetStudents"
+	StudentService_ListCourseStudents_FullMethodName = "/students.v1.StudentService/ListCourseStudents"
+	StudentService_CreateStudent_FullMethodName      = "/students.v1.StudentService/CreateStudent"
+	StudentService_UpdateStudent_FullMethodName      = "/students.v1.StudentService/UpdateStudent"
+	StudentService_EnrollStudent_FullMethodName      = "/students.v1.StudentService/EnrollStudent"
+	StudentService_UnenrollStudent_FullMethodName    = "/students.v1.StudentService/UnenrollStudent"
+	StudentService_ValidateToken_FullMethodName      = "/students.v1.StudentService/ValidateToken"
+)
+
+// StudentServiceClient is the client API for StudentService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StudentServiceClient interface {
+	// GetStudent requires students:read unless callers read themselves.
+	GetStudent(ctx context.Context, in *GetStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	// BatchGetStudents returns the known students of up to 100 IDs, in the
+	// order asked for, and lists the unknown IDs. Requires students:read.
+	BatchGetStudents(ctx context.Context, in *BatchGetStudentsRequest, opts ...grpc.CallOption) (*BatchGetStudentsResponse, error)
+	// ListCourseStudents returns the roster of a course. Requires
+	// rosters:read unless the caller is an instructor of the course.
+	ListCourseStudents(ctx context.Context, in *ListCourseStudentsRequest, opts ...grpc.CallOption) (*ListCourseStudentsResponse, error)
+	// CreateStudent registers a student. Requires students:write.
+	CreateStudent(ctx context.Context, in *CreateStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	// UpdateStudent changes the profile of a student; courses only change
+	// through enrollment. Requires students:write unless callers update
+	// themselves.
+	UpdateStudent(ctx context.Context, in *UpdateStudentRequest, opts ...grpc.CallOption) (*Student, error)
+	// EnrollStudent adds a course to a student. Enrolling twice is no error.
+	// Requires students:write.
+	EnrollStudent(ctx context.Context, in *EnrollmentRequest, opts ...grpc.CallOption) (*Student, error)
+	// UnenrollStudent removes a course from a student. Requires
+	// students:write.
+	UnenrollStudent(ctx context.Context, in *EnrollmentRequest, opts ...grpc.CallOption) (*Student, error)
+	// ValidateToken tells whether an access or refresh token is active, like
+	// the token introspection endpoint. Requires tokens:introspect.
+	ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenResponse, error)
+}
+
+type studentServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStudentServiceClient(cc grpc.ClientConnInterface) StudentServiceClient {
+	return &studentServiceClient{cc}
+}
+
+func (c *studentServiceClient) GetStudent(ctx context.Context, in *GetStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	err := c.cc.Invoke(ctx, StudentService_GetStudent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) BatchGetStudents(ctx context.Context, in *BatchGetStudentsRequest, opts ...grpc.CallOption) (*BatchGetStudentsResponse, error) {
+	out := new(BatchGetStudentsResponse)
+	err := c.cc.Invoke(ctx, StudentService_BatchGetStudents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) ListCourseStudents(ctx context.Context, in *ListCourseStudentsRequest, opts ...grpc.CallOption) (*ListCourseStudentsResponse, error) {
+	out := new(ListCourseStudentsResponse)
+	err := c.cc.Invoke(ctx, StudentService_ListCourseStudents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) CreateStudent(ctx context.Context, in *CreateStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	err := c.cc.Invoke(ctx, StudentService_CreateStudent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) UpdateStudent(ctx context.Context, in *UpdateStudentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	err := c.cc.Invoke(ctx, StudentService_UpdateStudent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) EnrollStudent(ctx context.Context, in *EnrollmentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	err := c.cc.Invoke(ctx, StudentService_EnrollStudent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) UnenrollStudent(ctx context.Context, in *EnrollmentRequest, opts ...grpc.CallOption) (*Student, error) {
+	out := new(Student)
+	err := c.cc.Invoke(ctx, StudentService_UnenrollStudent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *studentServiceClient) ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenResponse, error) {
+	out := new(ValidateTokenResponse)
+	err := c.cc.Invoke(ctx, StudentService_ValidateToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StudentServiceServer is the server API for StudentService service.
+// All implementations must embed UnimplementedStudentServiceServer
+// for forward compatibility
+type StudentServiceServer interface {
+	// GetStudent requires students:read unless callers read themselves.
+	GetStudent(context.Context, *GetStudentRequest) (*Student, error)
+	// BatchGetStudents returns the known students of up to 100 IDs, in the
+	// order asked for, and lists the unknown IDs. Requires students:read.
+	BatchGetStudents(context.Context, *BatchGetStudentsRequest) (*BatchGetStudentsResponse, error)
+	// ListCourseStudents returns the roster of a course. Requires
+	// rosters:read unless the caller is an instructor of the course.
+	ListCourseStudents(context.Context, *ListCourseStudentsRequest) (*ListCourseStudentsResponse, error)
+	// CreateStudent registers a student. Requires students:write.
+	CreateStudent(context.Context, *CreateStudentRequest) (*Student, error)
+	// UpdateStudent changes the profile of a student; courses only change
+	// through enrollment. Requires students:write unless callers update
+	// themselves.
+	UpdateStudent(context.Context, *UpdateStudentRequest) (*Student, error)
+	// EnrollStudent adds a course to a student. Enrolling twice is no error.
+	// Requires students:write.
+	EnrollStudent(context.Context, *EnrollmentRequest) (*Student, error)
+	// UnenrollStudent removes a course from a student. Requires
+	// students:write.
+	UnenrollStudent(context.Context, *EnrollmentRequest) (*Student, error)
+	// ValidateToken tells whether an access or refresh token is active, like
+	// the token introspection endpoint. Requires tokens:introspect.
+	ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenResponse, error)
+	mustEmbedUnimplementedStudentServiceServer()
+}
+
+// UnimplementedStudentServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedStudentServiceServer struct {
+}
+
+func (UnimplementedStudentServiceServer) GetStudent(context.Context, *GetStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) BatchGetStudents(context.Context, *BatchGetStudentsRequest) (*BatchGetStudentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetStudents not implemented")
+}
+func (UnimplementedStudentServiceServer) ListCourseStudents(context.Context, *ListCourseStudentsRequest) (*ListCourseStudentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCourseStudents not implemented")
+}
+func (UnimplementedStudentServiceServer) CreateStudent(context.Context, *CreateStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) UpdateStudent(context.Context, *UpdateStudentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) EnrollStudent(context.Context, *EnrollmentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnrollStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) UnenrollStudent(context.Context, *EnrollmentRequest) (*Student, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnenrollStudent not implemented")
+}
+func (UnimplementedStudentServiceServer) ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateToken not implemented")
+}
+func (UnimplementedStudentServiceServer) mustEmbedUnimplementedStudentServiceServer() {}
+
+// UnsafeStudentServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StudentServiceServer will
+// result in compilation errors.
+type UnsafeStudentServiceServer interface {
+	mustEmbedUnimplementedStudentServiceServer()
+}
+
+func RegisterStudentServiceServer(s grpc.ServiceRegistrar, srv StudentServiceServer) {
+	s.RegisterService(&StudentService_ServiceDesc, srv)
+}
+
+func _StudentService_GetStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).GetStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_GetStudent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).GetStudent(ctx, req.(*GetStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_BatchGetStudents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetStudentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).BatchGetStudents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_BatchGetStudents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).BatchGetStudents(ctx, req.(*BatchGetStudentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_ListCourseStudents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCourseStudentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).ListCourseStudents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_ListCourseStudents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).ListCourseStudents(ctx, req.(*ListCourseStudentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_CreateStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).CreateStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_CreateStudent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).CreateStudent(ctx, req.(*CreateStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_UpdateStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateStudentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).UpdateStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_UpdateStudent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).UpdateStudent(ctx, req.(*UpdateStudentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_EnrollStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnrollmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).EnrollStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_EnrollStudent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).EnrollStudent(ctx, req.(*EnrollmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_UnenrollStudent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnrollmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).UnenrollStudent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_UnenrollStudent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).UnenrollStudent(ctx, req.(*EnrollmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StudentService_ValidateToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StudentServiceServer).ValidateToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StudentService_ValidateToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StudentServiceServer).ValidateToken(ctx, req.(*ValidateTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// StudentService_ServiceDesc is the grpc.ServiceDesc for StudentService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StudentService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "students.v1.StudentService",
+	HandlerType: (*StudentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStudent",
+			Handler:    _StudentService_GetStudent_Handler,
+		},
+		{
+			MethodName: "BatchGetStudents",
+			Handler:    _StudentService_BatchGetStudents_Handler,
+		},
+		{
+			MethodName: "ListCourseStudents",
+			Handler:    _StudentService_ListCourseStudents_Handler,
+		},
+		{
+			MethodName: "CreateStudent",
+			Handler:    _StudentService_CreateStudent_Handler,
+		},
+		{
+			MethodName: "UpdateStudent",
+			Handler:    _StudentService_UpdateStudent_Handler,
+		},
+		{
+			MethodName: "EnrollStudent",
+			Handler:    _StudentService_EnrollStudent_Handler,
+		},
+		{
+			MethodName: "UnenrollStudent",
+			Handler:    _StudentService_UnenrollStudent_Handler,
+		},
+		{
+			MethodName: "ValidateToken",
+			Handler:    _StudentService_ValidateToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "students.proto",
+}
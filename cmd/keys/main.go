@@ -0,0 +1,104 @@
+// Command keys manages the token signing keys of the students service.
+//
+//	keys -dir ./keys generate -alg RS256
+//	keys -dir ./keys list
+//	keys -dir ./keys retire [-force] <kid>
+//
+// A generated key is published in the JWKS once the service reloads the key
+// directory, and becomes the signing key after keys.PublishDelay, when the
+// caches of other services have picked it up. Keep the previous key active
+// for at least one access-token TTL after that before retiring it, otherwise
+// tokens it signed are rejected early. The signing key is only retired once
+// another key has been published for keys.PublishDelay, unless -force is
+// given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/keys"
+)
+
+func main() {
+	dir := flag.String("dir", "./keys", "key directory")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: keys [-dir path] generate [-alg RS256|EdDSA] | list | retire [-force] <kid>...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "generate":
+		err = generate(*dir, flag.Args()[1:])
+	case "list":
+		err = list(*dir)
+	case "retire":
+		err = retire(*dir, flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(dir string, args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	alg := fs.String("alg", keys.AlgRS256, "signing algorithm (RS256 or EdDSA)")
+	fs.Parse(args)
+
+	entry, err := keys.Generate(dir, *alg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("generated %s key %s\n", entry.Algorithm, entry.ID)
+	return nil
+}
+
+func list(dir string) error {
+	entries, err := keys.ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tCREATED\tSTATUS")
+	for _, entry := range entries {
+		status := "active"
+		if !entry.Active() {
+			status = "retired " + entry.RetiredAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.ID, entry.Algorithm, entry.CreatedAt.Format(time.RFC3339), status)
+	}
+	return w.Flush()
+}
+
+func retire(dir string, args []string) error {
+	fs := flag.NewFlagSet("retire", flag.ExitOnError)
+	force := fs.Bool("force", false, "retire the signing key before its successor has been published for long enough")
+	fs.Parse(args)
+
+	ids := fs.Args()
+	if len(ids) == 0 {
+		return fmt.Errorf("retire: at least one kid is required")
+	}
+	for _, id := range ids {
+		if err := keys.Retire(dir, id, *force); err != nil {
+			return fmt.Errorf("retire %s: %v", id, err)
+		}
+		fmt.Printf("retired key %s\n", id)
+	}
+	return nil
+}
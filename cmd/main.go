@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
 	"github.com/joho/godotenv"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/graph"
+	"github.com/nurmeden/students-service/internal/app/grpcserver"
 	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/keys"
 	"github.com/nurmeden/students-service/internal/app/repository"
 	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/nurmeden/students-service/internal/courses"
 	"github.com/nurmeden/students-service/internal/database"
+	"github.com/nurmeden/students-service/internal/mailer"
+	"github.com/nurmeden/students-service/internal/oidc"
+	"github.com/nurmeden/students-service/internal/ratelimit"
+	"github.com/nurmeden/students-service/internal/requestid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
@@ -39,15 +52,29 @@ func main() {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetOutput(logfile)
 	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(requestid.Hook{})
 
 	if err := godotenv.Load(".env"); err != nil {
 		log.Print("No .env file found")
 	}
 
+	configName := os.Getenv("CONFIG")
+	if configName == "" {
+		configName = "config-local"
+	}
+	v, err := config.LoadConfig(configName)
+	if err != nil {
+		log.Fatalf("LoadConfig: %v", err)
+	}
+	cfg, err := config.ParseConfig(v)
+	if err != nil {
+		log.Fatalf("ParseConfig: %v", err)
+	}
+
 	redisClient := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 	})
 
 	defer redisClient.Close()
@@ -65,7 +92,7 @@ func main() {
 	// mongoURI := os.Getenv("MONGODB_URI")
 	// // collectionName := os.Getenv("COLLECTION_NAME")
 
-	client, err := database.SetupDatabase(context.Background())
+	client, err := database.SetupDatabase(context.Background(), cfg.Mongo.URI)
 	if err != nil {
 		fmt.Println(err.Error())
 		return
@@ -79,36 +106,111 @@ func main() {
 
 	prometheus.MustRegister(counter)
 
-	studentRepo, _ := repository.NewStudentRepository(client, "studentsdb", "students", redisClient, logger)
+	studentRepo, _ := repository.NewStudentRepository(client, cfg.Mongo.DBName, cfg.Mongo.CollectionName, redisClient, logger)
+
+	keySet, err := loadKeySet(cfg)
+	if err != nil {
+		logger.Fatalf("Failed to load signing keys: %v", err)
+	}
+	stopKeyWatch := make(chan struct{})
+	defer close(stopKeyWatch)
+	go keySet.Watch(cfg.JWT.KeysReloadInterval, stopKeyWatch, func(err error) {
+		logger.Errorf("Failed to reload signing keys: %v", err)
+	})
 
-	studentUsecase := usecase.NewStudentUsecase(*studentRepo, logger, redisClient)
+	tokenRepo := repository.NewTokenRepository(redisClient, logger)
+	attemptRepo := repository.NewLoginAttemptRepository(redisClient, logger)
+	oneTimeRepo := repository.NewOneTimeTokenRepository(redisClient, logger)
+	twoFactorRepo := repository.NewTwoFactorRepository(client, cfg.Mongo.DBName, logger)
+	identityRepo := repository.NewIdentityRepository(client, cfg.Mongo.DBName, logger)
+	apiKeyRepo := repository.NewAPIKeyRepository(client, cfg.Mongo.DBName, logger)
+	idempotencyRepo := repository.NewIdempotencyRepository(redisClient, logger)
+	if err := apiKeyRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Fatalf("Failed to create API key indexes: %v", err)
+	}
 
-	studentHandler := handler.NewStudentHandler(studentUsecase, logger)
+	mail, err := mailer.New(cfg.Mailer, logger)
+	if err != nil {
+		logger.Fatalf("Failed to create mailer: %v", err)
+	}
 
-	router := gin.Default()
-	docs.SwaggerInfo.BasePath = "/api"
-	api := router.Group("/api/")
-	{
-		api.POST("/students/", studentHandler.CreateStudent)
-		api.GET("/students/:id", studentHandler.GetStudentByID)
-		api.GET("/students/:id/students", studentHandler.GetStudentsByCourseID)
-		studentsGroup := api.Group("/students")
-		studentsGroup.Use(handler.AuthMiddleware())
-		{
-			studentsGroup.PUT("/:id", studentHandler.UpdateStudents)
-			studentsGroup.DELETE("/:id", studentHandler.DeleteStudent)
-			studentsGroup.GET("/:id/courses", studentHandler.GetStudentCourses)
-		}
-		auth := api.Group("/auth/")
-		{
-			auth.POST("/sign-up", studentHandler.CreateStudent)
-			auth.POST("/sign-in", studentHandler.SignIn)
-			auth.POST("/logout", studentHandler.Logout)
-			auth.POST("/refresh-token", studentHandler.RefreshToken)
+	var oidcClient *oidc.Client
+	if cfg.OIDC.Enabled {
+		oidcClient = oidc.NewClient(cfg.OIDC, &http.Client{Timeout: 10 * time.Second})
+		if _, err := oidcClient.Provider(context.Background()); err != nil {
+			// Discovery is retried on the first sign-in.
+			logger.Errorf("Failed to discover the OIDC provider: %v", err)
 		}
+	}
 
-		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	studentUsecase, err := usecase.NewStudentUsecase(*studentRepo, *tokenRepo, *attemptRepo, *oneTimeRepo, *twoFactorRepo, *identityRepo, *apiKeyRepo, mail, logger, cfg, keySet, oidcClient)
+	if err != nil {
+		logger.Fatalf("Failed to create student usecase: %v", err)
 	}
+
+	cookies := handler.NewCookies(cfg.Server, cfg.JWT)
+	coursesClient := courses.NewClient(cfg.Courses, &http.Client{Timeout: cfg.Courses.Timeout})
+	studentHandler := handler.NewStudentHandler(studentUsecase, coursesClient, logger, cookies)
+	graphHandler, err := graph.NewHandler(studentUsecase, coursesClient, cfg.GraphQL, logger)
+	if err != nil {
+		logger.Fatalf("Failed to build the GraphQL schema: %v", err)
+	}
+
+	// Rate limits are counted in process while Redis is unavailable.
+	limiter := ratelimit.NewFallback(ratelimit.NewRedis(redisClient), ratelimit.NewLocal(), logger)
+
+	if cfg.Server.Mode == config.ModeProduction {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(handler.AccessLog(), gin.Recovery(), handler.RequestID(), handler.Language(), handler.ErrorHandler(logger))
+	docs.SwaggerInfo.BasePath = "/api"
+	r := &routes{
+		students:       studentHandler,
+		authMiddleware: handler.AuthMiddleware(studentUsecase, cookies),
+		authorizer:     handler.NewAuthorizer(studentUsecase, logger),
+		graph:          graphHandler,
+		idempotency:    handler.Idempotency(idempotencyRepo, cfg.Idempotency, logger),
+		rateLimiter:    handler.NewRateLimiter(limiter, cfg.RateLimit, logger),
+		timeouts:       cfg.Server,
+	}
+	r.registerV1(router.Group("/api/", handler.APIVersion(1), handler.Deprecated(cfg.Server.V1DeprecatedAt, cfg.Server.V1Sunset, "/api/v2")))
+	r.registerV2(router.Group("/api/v2", handler.APIVersion(2)))
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/.well-known/jwks.json", handler.JWKS(keySet))
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	router.Run(":8001")
+
+	if cfg.GRPC.Enabled {
+		listener, err := net.Listen("tcp", cfg.GRPC.Port)
+		if err != nil {
+			logger.Fatalf("Failed to listen on the gRPC port: %v", err)
+		}
+		grpcServer := grpcserver.New(studentUsecase, logger)
+		defer grpcServer.GracefulStop()
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Errorf("gRPC server stopped: %v", err)
+			}
+		}()
+	}
+
+	router.Run(cfg.Server.Port)
+}
+
+// loadKeySet loads the signing keys. Outside of production a missing key
+// directory is bootstrapped with a fresh key so local runs need no setup;
+// production must provision keys with the keys command.
+func loadKeySet(cfg *config.Config) (*keys.KeySet, error) {
+	keySet, err := keys.Load(cfg.JWT.KeysDir)
+	if err == nil || cfg.Server.Mode == config.ModeProduction {
+		return keySet, err
+	}
+	if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, keys.ErrNoActiveKey) {
+		return nil, err
+	}
+	if _, err := keys.Generate(cfg.JWT.KeysDir, cfg.JWT.KeyAlgorithm); err != nil {
+		return nil, err
+	}
+	return keys.Load(cfg.JWT.KeysDir)
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/graph"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/model"
+)
+
+// routes mounts the handlers for both API versions. The versions share
+// handlers and usecases; v2 has resource-oriented paths for students and
+// courses and wraps every response in the envelope.
+//
+// Requests creating students or enrollments take an Idempotency-Key, so
+// clients can retry them after a timeout. Rate limits and request deadlines
+// apply per group: "signup", "auth", "api" and "graphql".
+type routes struct {
+	students       *handler.StudentHandler
+	authMiddleware gin.HandlerFunc
+	authorizer     *handler.Authorizer
+	graph          *graph.Handler
+	idempotency    gin.HandlerFunc
+	rateLimiter    *handler.RateLimiter
+	timeouts       config.ServerConfig
+}
+
+// deadline bounds the requests of the route group by its timeout.
+func (r *routes) deadline(group string) gin.HandlerFunc {
+	return handler.Deadline(r.timeouts.TimeoutOf(group))
+}
+
+// registerV1 mounts the original routes under /api. They are deprecated in
+// favour of /api/v2 but keep their paths and responses.
+func (r *routes) registerV1(api *gin.RouterGroup) {
+	api.POST("/students/", r.deadline("signup"), r.rateLimiter.Limit("signup"), r.idempotency, r.students.CreateStudent)
+	studentsGroup := api.Group("/students")
+	studentsGroup.Use(r.deadline("api"), r.authMiddleware, r.rateLimiter.Limit("api"))
+	{
+		studentsGroup.GET("/:id", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsRead), r.students.GetStudentByID)
+		studentsGroup.POST("/:id/email", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsWrite), r.students.RequestEmailChange)
+		verified := studentsGroup.Group("/")
+		verified.Use(r.authorizer.RequireVerifiedEmail())
+		{
+			// :id is the course here.
+			verified.GET("/:id/students", r.authorizer.RequireRosterAccess("id"), r.students.GetStudentsByCourseID)
+			verified.PUT("/:id", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsWrite), r.students.UpdateStudents)
+			verified.DELETE("/:id", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsDelete), r.students.DeleteStudent)
+			verified.GET("/:id/courses", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsRead), r.students.GetStudentCourses)
+		}
+	}
+	r.registerShared(api)
+}
+
+// registerV2 mounts /api/v2. Students sign up only through the auth routes,
+// course rosters live under the course, and enrollment under the student. The GraphQL endpoint answers
+// in GraphQL results rather than the envelope.
+func (r *routes) registerV2(api *gin.RouterGroup) {
+	students := api.Group("/students")
+	students.Use(r.deadline("api"), r.authMiddleware, r.rateLimiter.Limit("api"))
+	{
+		students.GET("/:id", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsRead), r.students.GetStudentByID)
+		students.POST("/:id/email", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsWrite), r.students.RequestEmailChange)
+		verified := students.Group("/")
+		verified.Use(r.authorizer.RequireVerifiedEmail())
+		{
+			verified.PUT("/:id", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsWrite), r.students.UpdateStudents)
+			verified.DELETE("/:id", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsDelete), r.students.DeleteStudent)
+			verified.GET("/:id/courses", r.authorizer.RequireSelfOrPermission("id", handler.PermStudentsRead), r.students.GetStudentCourses)
+			verified.POST("/:id/courses", r.authorizer.RequirePermission(handler.PermStudentsWrite), r.idempotency, r.students.EnrollStudent)
+			verified.DELETE("/:id/courses/:courseId", r.authorizer.RequirePermission(handler.PermStudentsWrite), r.students.UnenrollStudent)
+		}
+	}
+	courses := api.Group("/courses")
+	courses.Use(r.deadline("api"), r.authMiddleware, r.rateLimiter.Limit("api"), r.authorizer.RequireVerifiedEmail())
+	{
+		courses.GET("/:id/students", r.authorizer.RequireRosterAccess("id"), r.students.GetStudentsByCourseID)
+	}
+	api.POST("/graphql", r.deadline("graphql"), r.authMiddleware, r.rateLimiter.Limit("graphql"), r.graph.Serve)
+	r.registerShared(api)
+}
+
+// registerShared mounts the admin, auth and account routes, which have the
+// same paths in both versions.
+func (r *routes) registerShared(api *gin.RouterGroup) {
+	admin := api.Group("/admin")
+	admin.Use(r.deadline("api"), r.authMiddleware, r.rateLimiter.Limit("api"), r.authorizer.RequireVerifiedEmail(), r.authorizer.RequireRole(model.RoleAdmin))
+	{
+		admin.PUT("/students/:id/role", r.authorizer.RequirePermission(handler.PermRolesManage), r.students.SetRole)
+		admin.POST("/students/:id/unlock", r.authorizer.RequirePermission(handler.PermAccountsUnlock), r.students.UnlockAccount)
+		admin.GET("/two-factor/roles", r.authorizer.RequirePermission(handler.PermSecurityManage), r.students.GetTwoFactorRoles)
+		admin.PUT("/two-factor/roles", r.authorizer.RequirePermission(handler.PermSecurityManage), r.students.SetTwoFactorRoles)
+		admin.POST("/api-keys", r.authorizer.RequirePermission(handler.PermSecurityManage), r.students.CreateAPIKey)
+		admin.GET("/api-keys", r.authorizer.RequirePermission(handler.PermSecurityManage), r.students.ListAPIKeys)
+		admin.DELETE("/api-keys/:id", r.authorizer.RequirePermission(handler.PermSecurityManage), r.students.RevokeAPIKey)
+	}
+	auth := api.Group("/auth/")
+	auth.Use(r.deadline("auth"), r.rateLimiter.Limit("auth"))
+	{
+		auth.POST("/sign-up", r.rateLimiter.Limit("signup"), r.idempotency, r.students.CreateStudent)
+		auth.POST("/sign-in", r.students.SignIn)
+		auth.POST("/sign-in/2fa", r.students.CompleteTwoFactorSignIn)
+		auth.POST("/sign-in/2fa/enroll", r.students.BeginChallengeEnrollment)
+		auth.GET("/oidc/login", r.students.OIDCLogin)
+		auth.GET("/oidc/callback", r.students.OIDCCallback)
+		auth.POST("/refresh-token", r.students.RefreshToken)
+		auth.POST("/password/forgot", r.students.ForgotPassword)
+		auth.POST("/password/reset", r.students.ResetPassword)
+		auth.POST("/verify-email", r.students.VerifyEmail)
+		auth.POST("/introspect", r.authMiddleware, r.authorizer.RequireAPIKey(), r.authorizer.RequirePermission(handler.PermTokensIntrospect), r.students.IntrospectToken)
+		session := auth.Group("/")
+		session.Use(r.authMiddleware, r.authorizer.RequireUser())
+		{
+			session.POST("/logout", r.students.Logout)
+			session.POST("/logout-all", r.students.LogoutAll)
+			session.POST("/verify-email/resend", r.students.ResendVerification)
+			session.POST("/2fa/enroll", r.students.BeginTwoFactorEnrollment)
+			session.POST("/2fa/enable", r.students.EnableTwoFactor)
+			session.POST("/2fa/disable", r.students.DisableTwoFactor)
+			session.POST("/2fa/recovery-codes", r.students.RegenerateRecoveryCodes)
+		}
+	}
+
+	me := api.Group("/me")
+	me.Use(r.deadline("api"), r.authMiddleware, r.rateLimiter.Limit("api"), r.authorizer.RequireUser())
+	{
+		me.GET("", r.students.GetMe)
+		me.PATCH("", r.students.UpdateMe)
+		me.DELETE("", r.students.DeleteMe)
+		me.POST("/password", r.students.ChangePassword)
+		me.POST("/email", r.students.ChangeMyEmail)
+		me.GET("/sessions", r.students.ListSessions)
+		me.DELETE("/sessions/:id", r.students.RevokeSession)
+	}
+}
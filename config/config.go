@@ -2,15 +2,48 @@ package config
 
 import (
 	"errors"
-	"github.com/spf13/viper"
+	"fmt"
 	"log"
+	"strings"
 	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+const (
+	ModeDevelopment = "Development"
+	ModeProduction  = "Production"
 )
 
+// defaultSecrets lists placeholder values that must never be used to sign
+// tokens outside of development.
+var defaultSecrets = map[string]struct{}{
+	"":              {},
+	"supersecret":   {},
+	"secret":        {},
+	"changeme":      {},
+	"dfhdfjhgdjkff": {},
+}
+
 type Config struct {
-	JWTKey string `env:"JWT_KEY" envDefault:"supersecret"`
-	Logger Logger
-	Server ServerConfig
+	Logger        Logger
+	Server        ServerConfig
+	GRPC          GRPCConfig
+	GraphQL       GraphQLConfig
+	Courses       CoursesConfig
+	Idempotency   IdempotencyConfig
+	RateLimit     RateLimitConfig
+	JWT           JWTConfig
+	Lockout       LockoutConfig
+	Password      PasswordConfig
+	TwoFactor     TwoFactorConfig
+	OIDC          OIDCConfig
+	PasswordReset PasswordResetConfig
+	Verification  VerificationConfig
+	Mailer        MailerConfig
+	Mongo         MongoConfig
+	Redis         RedisConfig
 }
 
 type Logger struct {
@@ -26,7 +59,6 @@ type ServerConfig struct {
 	Port              string
 	PprofPort         string
 	Mode              string
-	JwtSecretKey      string
 	CookieName        string
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
@@ -34,6 +66,186 @@ type ServerConfig struct {
 	CtxDefaultTimeout time.Duration
 	CSRF              bool
 	Debug             bool
+	// RouteTimeouts overrides CtxDefaultTimeout, the deadline of API
+	// requests, per route group such as "graphql". Zero means no deadline.
+	RouteTimeouts map[string]time.Duration
+	// V1DeprecatedAt and V1Sunset are announced on the responses of the v1
+	// routes under /api in the Deprecation and Sunset headers, as RFC 3339
+	// times. Zero leaves the header out.
+	V1DeprecatedAt time.Time
+	V1Sunset       time.Time
+}
+
+// TimeoutOf returns the deadline of requests to the route group.
+func (c ServerConfig) TimeoutOf(group string) time.Duration {
+	if timeout, ok := c.RouteTimeouts[group]; ok {
+		return timeout
+	}
+	return c.CtxDefaultTimeout
+}
+
+// GRPCConfig serves the gRPC StudentService on Port, next to the REST API.
+type GRPCConfig struct {
+	Enabled bool
+	Port    string
+}
+
+// GraphQLConfig limits the queries of the GraphQL endpoint. Complexity
+// counts every selected field, multiplied by ListMultiplier below list
+// fields. Zero values select the defaults.
+type GraphQLConfig struct {
+	MaxDepth       int
+	MaxComplexity  int
+	ListMultiplier int
+}
+
+// CoursesConfig locates the API of the courses service, such as
+// http://courses:8080/api.
+type CoursesConfig struct {
+	BaseURL string
+	Timeout time.Duration
+}
+
+// IdempotencyConfig keeps the responses of requests with an Idempotency-Key
+// for Window. LockTTL bounds how long a request holds its key before a
+// retry may run it again, in case it never finishes. A zero Window turns
+// Idempotency-Key support off.
+type IdempotencyConfig struct {
+	Window  time.Duration
+	LockTTL time.Duration
+}
+
+// RateLimitConfig limits requests per route group, such as "auth" or
+// "api". Groups without a rule are not limited.
+type RateLimitConfig struct {
+	Enabled bool
+	Groups  map[string]RateLimitRule
+}
+
+// RateLimitRule allows Requests per Window. By is RateLimitByIP to count
+// per client IP, or RateLimitByCaller, the default, to count per API key or
+// user and per IP for anonymous requests. Roles overrides Requests for
+// callers with the role; the highest of their roles applies.
+type RateLimitRule struct {
+	Requests int
+	Window   time.Duration
+	By       string
+	Roles    map[string]int
+}
+
+const (
+	RateLimitByCaller = "caller"
+	RateLimitByIP     = "ip"
+)
+
+type JWTConfig struct {
+	KeysDir            string
+	KeyAlgorithm       string
+	KeysReloadInterval time.Duration
+	RefreshSecret      string
+	Issuer             string
+	Audience           string
+	AccessTTL          time.Duration
+	RefreshTTL         time.Duration
+	// ClockSkew is the leeway allowed on exp, nbf and iat of access tokens.
+	ClockSkew time.Duration
+	// Tenant is put into access tokens and required of them when set.
+	Tenant string
+}
+
+// LockoutConfig throttles password guessing. After DelayAfter failures of an
+// account every further failure is answered after BaseDelay, doubling per
+// failure up to MaxDelay. Reaching MaxAccountFailures or MaxIPFailures inside
+// Window locks the account or IP for LockoutDuration.
+type LockoutConfig struct {
+	MaxAccountFailures int64
+	MaxIPFailures      int64
+	Window             time.Duration
+	LockoutDuration    time.Duration
+	DelayAfter         int64
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+}
+
+// PasswordConfig sets the password policy and the hash algorithm for new
+// hashes. Existing hashes made with another algorithm or other parameters
+// are upgraded on the next successful sign-in. Algorithm is "bcrypt" or
+// "argon2id"; Argon2Memory is in KiB. Zero values select the defaults.
+type PasswordConfig struct {
+	MinLength int
+	MaxLength int
+	// BlocklistFile optionally names an offline list of breached passwords,
+	// one per line, checked in addition to the built-in list.
+	BlocklistFile     string
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// TwoFactorConfig controls TOTP two-factor authentication. Skew is the
+// number of 30 second steps a code may be off by. RequiredRoles applies
+// until an admin sets the required roles through the API.
+type TwoFactorConfig struct {
+	Issuer        string
+	ChallengeTTL  time.Duration
+	Skew          int64
+	RecoveryCodes int
+	RequiredRoles []string
+}
+
+// OIDCConfig enables sign-in through an external OpenID Connect provider.
+// The provider endpoints are discovered from IssuerURL. ClientSecret may be
+// empty for public clients, which rely on PKCE alone.
+type OIDCConfig struct {
+	Enabled      bool
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	StateTTL     time.Duration
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration
+	// LinkURL is the frontend page that receives the token as its token
+	// query parameter.
+	LinkURL string
+}
+
+// VerificationConfig controls email verification links. Resends, and
+// password reset mails too, are limited to one per ResendCooldown and
+// MaxResends per ResendWindow.
+type VerificationConfig struct {
+	TokenTTL       time.Duration
+	LinkURL        string
+	ResendCooldown time.Duration
+	MaxResends     int64
+	ResendWindow   time.Duration
+}
+
+type MailerConfig struct {
+	Driver   string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	FilePath string
+}
+
+type MongoConfig struct {
+	URI            string
+	DBName         string
+	CollectionName string
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
 }
 
 type HTTP struct {
@@ -52,6 +264,8 @@ func LoadConfig(filename string) (*viper.Viper, error) {
 
 	v.SetConfigName(filename)
 	v.AddConfigPath(".")
+	v.AddConfigPath("./config")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
 	if err := v.ReadInConfig(); err != nil {
@@ -68,11 +282,101 @@ func LoadConfig(filename string) (*viper.Viper, error) {
 func ParseConfig(v *viper.Viper) (*Config, error) {
 	var c Config
 
-	err := v.Unmarshal(&c)
+	err := v.Unmarshal(&c, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+	)))
 	if err != nil {
 		log.Printf("unable to decode into struct, %v", err)
 		return nil, err
 	}
 
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &c, err
 }
+
+// Validate rejects settings the service cannot run with. Token secrets are
+// only allowed to keep their placeholder values in development.
+func (c *Config) Validate() error {
+	if c.JWT.AccessTTL <= 0 || c.JWT.RefreshTTL <= 0 {
+		return errors.New("jwt: accessTTL and refreshTTL must be positive")
+	}
+	if c.JWT.ClockSkew < 0 {
+		return errors.New("jwt: clockSkew must not be negative")
+	}
+	if c.JWT.KeysDir == "" {
+		return errors.New("jwt: keysDir is required")
+	}
+	if (c.Lockout.MaxAccountFailures > 0 || c.Lockout.MaxIPFailures > 0) && (c.Lockout.Window <= 0 || c.Lockout.LockoutDuration <= 0) {
+		return errors.New("lockout: window and lockoutDuration must be positive")
+	}
+	if c.Password.Algorithm != "" && c.Password.Algorithm != "bcrypt" && c.Password.Algorithm != "argon2id" {
+		return errors.New("password: algorithm must be bcrypt or argon2id")
+	}
+	if c.Password.MinLength < 0 || (c.Password.MaxLength > 0 && c.Password.MaxLength < c.Password.MinLength) {
+		return errors.New("password: maxLength must not be below minLength")
+	}
+	if c.TwoFactor.ChallengeTTL < 0 || c.TwoFactor.Skew < 0 || c.TwoFactor.RecoveryCodes < 0 {
+		return errors.New("twoFactor: challengeTTL, skew and recoveryCodes must not be negative")
+	}
+	if c.OIDC.Enabled && (c.OIDC.IssuerURL == "" || c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "") {
+		return errors.New("oidc: issuerURL, clientID and redirectURL are required when enabled")
+	}
+	if !c.Server.V1Sunset.IsZero() && c.Server.V1Sunset.Before(c.Server.V1DeprecatedAt) {
+		return errors.New("server: v1Sunset must not be before v1DeprecatedAt")
+	}
+	if c.Server.CtxDefaultTimeout < 0 {
+		return errors.New("server: ctxDefaultTimeout must not be negative")
+	}
+	for group, timeout := range c.Server.RouteTimeouts {
+		if timeout < 0 {
+			return fmt.Errorf("server: timeout of route group %s must not be negative", group)
+		}
+	}
+	if c.GRPC.Enabled && (c.GRPC.Port == "" || c.GRPC.Port == c.Server.Port) {
+		return errors.New("grpc: port is required and must differ from the server port when enabled")
+	}
+	if c.GraphQL.MaxDepth < 0 || c.GraphQL.MaxComplexity < 0 || c.GraphQL.ListMultiplier < 0 {
+		return errors.New("graphql: limits must not be negative")
+	}
+	if c.Verification.ResendCooldown <= 0 || c.Verification.MaxResends <= 0 || c.Verification.ResendWindow <= 0 {
+		return errors.New("verification: resendCooldown, maxResends and resendWindow must be positive")
+	}
+	if c.Idempotency.Window > 0 && c.Idempotency.LockTTL <= 0 {
+		return errors.New("idempotency: lockTTL must be positive when a window is set")
+	}
+	for group, rule := range c.RateLimit.Groups {
+		if rule.Requests <= 0 || rule.Window <= 0 {
+			return fmt.Errorf("rateLimit: group %s needs positive requests and window", group)
+		}
+		if rule.By != "" && rule.By != RateLimitByCaller && rule.By != RateLimitByIP {
+			return fmt.Errorf("rateLimit: group %s: by must be caller or ip", group)
+		}
+		for role, requests := range rule.Roles {
+			if requests <= 0 {
+				return fmt.Errorf("rateLimit: group %s: requests of role %s must be positive", group, role)
+			}
+		}
+	}
+	if c.Server.Mode != ModeProduction {
+		return nil
+	}
+	if isDefaultSecret(c.JWT.RefreshSecret) {
+		return errors.New("jwt: refreshSecret is empty or a default value in production mode")
+	}
+	// The log and file drivers keep whole messages, with live reset and
+	// verification links.
+	if c.Mailer.Driver != "smtp" || c.Mailer.Host == "" {
+		return errors.New("mailer: the smtp driver with a host is required in production mode")
+	}
+	return nil
+}
+
+func isDefaultSecret(secret string) bool {
+	_, ok := defaultSecrets[strings.TrimSpace(secret)]
+	return ok
+}
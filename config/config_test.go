@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	jwtConfig := JWTConfig{
+		KeysDir:       "./keys",
+		RefreshSecret: "refresh-93ad2e",
+		AccessTTL:     time.Hour,
+		RefreshTTL:    24 * time.Hour,
+	}
+
+	verificationConfig := VerificationConfig{ResendCooldown: time.Minute, MaxResends: 5, ResendWindow: time.Hour}
+
+	tests := []struct {
+		name    string
+		mode    string
+		mutate  func(c *Config)
+		wantErr bool
+	}{
+		{name: "production with custom secrets", mode: ModeProduction},
+		{name: "development with default secret", mode: ModeDevelopment, mutate: func(c *Config) { c.JWT.RefreshSecret = "supersecret" }},
+		{name: "production with empty refresh secret", mode: ModeProduction, mutate: func(c *Config) { c.JWT.RefreshSecret = "" }, wantErr: true},
+		{name: "production with default refresh secret", mode: ModeProduction, mutate: func(c *Config) { c.JWT.RefreshSecret = "changeme" }, wantErr: true},
+		{name: "missing keys dir", mode: ModeDevelopment, mutate: func(c *Config) { c.JWT.KeysDir = "" }, wantErr: true},
+		{name: "argon2id hashing", mode: ModeProduction, mutate: func(c *Config) { c.Password.Algorithm = "argon2id" }},
+		{name: "unknown hash algorithm", mode: ModeDevelopment, mutate: func(c *Config) { c.Password.Algorithm = "md5" }, wantErr: true},
+		{name: "max length below min length", mode: ModeDevelopment, mutate: func(c *Config) { c.Password.MinLength, c.Password.MaxLength = 12, 8 }, wantErr: true},
+		{name: "oidc without client id", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.OIDC = OIDCConfig{Enabled: true, IssuerURL: "https://sso.example.edu", RedirectURL: "http://localhost/cb"}
+		}, wantErr: true},
+		{name: "zero access ttl", mode: ModeDevelopment, mutate: func(c *Config) { c.JWT.AccessTTL = 0 }, wantErr: true},
+		{name: "negative clock skew", mode: ModeDevelopment, mutate: func(c *Config) { c.JWT.ClockSkew = -time.Second }, wantErr: true},
+		{name: "v1 sunset before deprecation", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.Server.V1DeprecatedAt = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+			c.Server.V1Sunset = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		}, wantErr: true},
+		{name: "grpc on its own port", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.Server.Port, c.GRPC = ":8001", GRPCConfig{Enabled: true, Port: ":9001"}
+		}},
+		{name: "grpc on the server port", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.Server.Port, c.GRPC = ":8001", GRPCConfig{Enabled: true, Port: ":8001"}
+		}, wantErr: true},
+		{name: "idempotency without lock TTL", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.Idempotency.Window = time.Hour
+		}, wantErr: true},
+		{name: "rate limit", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.RateLimit.Groups = map[string]RateLimitRule{"auth": {Requests: 10, Window: time.Minute, By: RateLimitByIP, Roles: map[string]int{"admin": 100}}}
+		}},
+		{name: "rate limit without window", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.RateLimit.Groups = map[string]RateLimitRule{"auth": {Requests: 10}}
+		}, wantErr: true},
+		{name: "rate limit by unknown key", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.RateLimit.Groups = map[string]RateLimitRule{"auth": {Requests: 10, Window: time.Minute, By: "session"}}
+		}, wantErr: true},
+		{name: "negative route timeout", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.Server.RouteTimeouts = map[string]time.Duration{"graphql": -time.Second}
+		}, wantErr: true},
+		{name: "log mailer in production", mode: ModeProduction, mutate: func(c *Config) { c.Mailer.Driver = "log" }, wantErr: true},
+		{name: "default mailer in production", mode: ModeProduction, mutate: func(c *Config) { c.Mailer.Driver = "" }, wantErr: true},
+		{name: "file mailer in development", mode: ModeDevelopment, mutate: func(c *Config) { c.Mailer.Driver = "file" }},
+		{name: "verification mails without cooldown", mode: ModeDevelopment, mutate: func(c *Config) { c.Verification.ResendCooldown = 0 }, wantErr: true},
+		{name: "negative graphql limit", mode: ModeDevelopment, mutate: func(c *Config) {
+			c.GraphQL.MaxDepth = -1
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Server: ServerConfig{Mode: tt.mode}, JWT: jwtConfig, Mailer: MailerConfig{Driver: "smtp", Host: "smtp.example.com"}, Verification: verificationConfig}
+			if tt.mutate != nil {
+				tt.mutate(&c)
+			}
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Config.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerConfig_TimeoutOf(t *testing.T) {
+	cfg := ServerConfig{CtxDefaultTimeout: 10 * time.Second, RouteTimeouts: map[string]time.Duration{"graphql": 30 * time.Second, "auth": 0}}
+
+	for group, want := range map[string]time.Duration{"graphql": 30 * time.Second, "auth": 0, "api": 10 * time.Second} {
+		if got := cfg.TimeoutOf(group); got != want {
+			t.Errorf("TimeoutOf(%q) = %v, want %v", group, got, want)
+		}
+	}
+}
+
+func TestParseConfig_V1Schedule(t *testing.T) {
+	v, err := LoadConfig("config-local")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	c, err := ParseConfig(v)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	if want := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC); !c.Server.V1Sunset.Equal(want) {
+		t.Errorf("V1Sunset = %v, want %v", c.Server.V1Sunset, want)
+	}
+	if c.Server.CtxDefaultTimeout != 12*time.Second {
+		t.Errorf("CtxDefaultTimeout = %v, want 12s", c.Server.CtxDefaultTimeout)
+	}
+	if got := c.Server.TimeoutOf("graphql"); got != 30*time.Second {
+		t.Errorf("TimeoutOf(graphql) = %v, want 30s", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Package apperror defines the domain errors shared by the repository,
+// usecase and handler layers. Every domain error has a kind, which decides
+// the HTTP status, and a stable code clients can switch on.
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. errors.Is(err, ErrNotFound) holds for every
+// *Error of that kind in err's chain.
+var (
+	ErrValidation      = errors.New("validation failed")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrUnprocessable   = errors.New("unprocessable")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUnavailable     = errors.New("unavailable")
+	ErrInternal        = errors.New("internal error")
+)
+
+const CodeInternal = "internal_error"
+
+// ErrDeadlineExceeded is the error of work cut short by the deadline of its
+// request.
+var ErrDeadlineExceeded = Unavailable("deadline_exceeded", "the request took too long")
+
+// Error is a domain error. Code is stable and meant for clients, Message
+// for people; Err is the cause and is never shown to clients. Args are the
+// values formatted into Message, kept for translations of it.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Args    []interface{}
+	Err     error
+}
+
+func New(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error   { return New(ErrValidation, code, message) }
+func Unauthorized(code, message string) *Error { return New(ErrUnauthorized, code, message) }
+func Forbidden(code, message string) *Error    { return New(ErrForbidden, code, message) }
+func NotFound(code, message string) *Error     { return New(ErrNotFound, code, message) }
+func Conflict(code, message string) *Error     { return New(ErrConflict, code, message) }
+func TooManyRequests(code, message string) *Error {
+	return New(ErrTooManyRequests, code, message)
+}
+func Unavailable(code, message string) *Error { return New(ErrUnavailable, code, message) }
+
+// Unprocessable is for well-formed requests that cannot be carried out as
+// sent, such as a reused idempotency key.
+func Unprocessable(code, message string) *Error { return New(ErrUnprocessable, code, message) }
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the kind of the error and any domain error with the same
+// code, so a wrapped copy still matches the sentinel it was made from.
+func (e *Error) Is(target error) bool {
+	if target == e.Kind {
+		return true
+	}
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error with err as its cause.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// With returns a copy of the error with args formatted into its message.
+func (e *Error) With(args ...interface{}) *Error {
+	formatted := *e
+	formatted.Message = fmt.Sprintf(e.Message, args...)
+	formatted.Args = args
+	return &formatted
+}
+
+// From returns the domain error in err's chain. Error types of other
+// packages take part by implementing AppError. Exceeded deadlines are
+// ErrDeadlineExceeded; anything else is an internal error that keeps err
+// only as its cause.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	var carrier interface{ AppError() *Error }
+	if errors.As(err, &carrier) {
+		return carrier.AppError()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrDeadlineExceeded.Wrap(err)
+	}
+	return &Error{Kind: ErrInternal, Code: CodeInternal, Message: "internal server error", Err: err}
+}
@@ -0,0 +1,58 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type carrierError struct{}
+
+func (carrierError) Error() string { return "locked" }
+
+func (carrierError) AppError() *Error {
+	return TooManyRequests("account_locked", "account locked")
+}
+
+func TestError_Is(t *testing.T) {
+	errStudentNotFound := NotFound("student_not_found", "student not found")
+	wrapped := fmt.Errorf("update student: %w", errStudentNotFound.Wrap(errors.New("no documents")))
+
+	assert.ErrorIs(t, wrapped, errStudentNotFound)
+	assert.ErrorIs(t, wrapped, ErrNotFound)
+	assert.NotErrorIs(t, wrapped, ErrConflict)
+	assert.NotErrorIs(t, wrapped, NotFound("session_not_found", "session not found"))
+	assert.Equal(t, "student not found: no documents", errStudentNotFound.Wrap(errors.New("no documents")).Error())
+	assert.Equal(t, "student not found", errStudentNotFound.Error(), "Wrap must not change the sentinel")
+}
+
+func TestFrom(t *testing.T) {
+	conflict := Conflict("email_taken", "email already exists")
+	assert.Same(t, conflict, From(fmt.Errorf("create student: %w", conflict)))
+
+	assert.Equal(t, "account_locked", From(fmt.Errorf("sign in: %w", carrierError{})).Code)
+
+	cause := errors.New("connection reset")
+	internal := From(cause)
+	assert.Equal(t, CodeInternal, internal.Code)
+	assert.ErrorIs(t, internal, ErrInternal)
+	assert.NotContains(t, internal.Message, "connection reset")
+	assert.ErrorIs(t, internal, cause)
+
+	timeout := From(fmt.Errorf("find student: %w", context.DeadlineExceeded))
+	assert.ErrorIs(t, timeout, ErrDeadlineExceeded)
+	assert.ErrorIs(t, timeout, ErrUnavailable)
+}
+
+func TestError_With(t *testing.T) {
+	errInvalidScope := Validation("invalid_scope", "invalid scope %s")
+	err := errInvalidScope.With("roles:manage")
+
+	assert.Equal(t, "invalid scope roles:manage", err.Error())
+	assert.Equal(t, []interface{}{"roles:manage"}, err.Args)
+	assert.ErrorIs(t, err, errInvalidScope)
+	assert.Equal(t, "invalid scope %s", errInvalidScope.Message)
+}
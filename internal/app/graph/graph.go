@@ -0,0 +1,250 @@
+// Package graph serves a GraphQL API of students, their courses from the
+// courses service and enrollment, next to the REST API and behind the same
+// AuthMiddleware. Lookups are batched per request, and queries are measured
+// against depth and complexity limits before they run.
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/i18n"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errInvalidRequest   = apperror.Validation("invalid_request", "invalid request body")
+	errQueryTooDeep     = apperror.Validation("query_too_deep", "query depth %d exceeds the limit of %d")
+	errQueryTooComplex  = apperror.Validation("query_too_complex", "query complexity %d exceeds the limit of %d")
+	errForbidden        = apperror.Forbidden("forbidden", "forbidden")
+	errEmailNotVerified = apperror.Forbidden("email_not_verified", "email address is not verified")
+)
+
+// Codes of the errors graphql-go reports itself, with its English
+// messages.
+const (
+	codeParseFailed      = "graphql_parse_failed"
+	codeValidationFailed = "graphql_validation_failed"
+)
+
+// CourseFetcher fetches courses by ID. *courses.Client implements it.
+type CourseFetcher interface {
+	GetCourses(ctx context.Context, ids []string) ([]model.Course, error)
+}
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type Handler struct {
+	schema         graphql.Schema
+	limits         limits
+	studentUsecase usecase.StudentUsecase
+	courses        CourseFetcher
+	logger         *logrus.Logger
+}
+
+func NewHandler(studentUsecase usecase.StudentUsecase, courses CourseFetcher, cfg config.GraphQLConfig, logger *logrus.Logger) (*Handler, error) {
+	h := &Handler{
+		limits:         newLimits(cfg),
+		studentUsecase: studentUsecase,
+		courses:        courses,
+		logger:         logger,
+	}
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// Serve answers POST requests with a JSON body. Requests that reach the
+// executor are answered with 200 and report their errors in the result,
+// with the error code in the extensions and the message in the language
+// of the request.
+func (h *Handler) Serve(c *gin.Context) {
+	lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{
+			Errors: []gqlerrors.FormattedError{h.present(c.Request.Context(), gqlerrors.FormatError(errInvalidRequest.Wrap(err)), lang)},
+		})
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), stateKey{}, h.newRequestState(handler.CurrentPrincipal(c), handler.RequestIDOf(c)))
+	result := h.execute(ctx, &req)
+	for i, err := range result.Errors {
+		result.Errors[i] = h.present(ctx, err, lang)
+	}
+	h.logger.WithFields(logrus.Fields{
+		"requestID":     handler.RequestIDOf(c),
+		"operationName": req.OperationName,
+		"errors":        len(result.Errors),
+	}).Debug("GraphQL request served")
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) execute(ctx context.Context, req *Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: withCode(gqlerrors.FormatErrors(err), codeParseFailed)}
+	}
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: withCode(validation.Errors, codeValidationFailed)}
+	}
+	if err := h.limits.check(&h.schema, doc); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+// present turns an error of the result into one for clients. Domain errors
+// keep their code and get the message of the language; anything else the
+// resolvers return is an internal error, logged and not shown. Errors
+// graphql-go reports about the request itself are shown as they are.
+func (h *Handler) present(ctx context.Context, err gqlerrors.FormattedError, lang string) gqlerrors.FormattedError {
+	if _, ok := err.Extensions["code"]; ok {
+		return err
+	}
+	locations := err.Locations
+	if locations == nil {
+		locations = []location.SourceLocation{}
+	}
+
+	original := originalError(err)
+	var graphqlErr *gqlerrors.Error
+	if errors.As(original, &graphqlErr) && len(err.Path) == 0 {
+		return gqlerrors.FormattedError{
+			Message:    err.Message,
+			Locations:  locations,
+			Extensions: map[string]interface{}{"code": codeValidationFailed},
+		}
+	}
+
+	appErr := apperror.From(original)
+	if handler.StatusOf(appErr) >= http.StatusInternalServerError {
+		h.logger.WithError(original).WithFields(logrus.Fields{
+			"requestID": requestIDOf(ctx),
+			"path":      err.Path,
+			"code":      appErr.Code,
+		}).Error("GraphQL field failed")
+	}
+	message, ok := i18n.Message(lang, appErr.Code, appErr.Args...)
+	if !ok {
+		message = appErr.Message
+	}
+	return gqlerrors.FormattedError{
+		Message:    message,
+		Locations:  locations,
+		Path:       err.Path,
+		Extensions: map[string]interface{}{"code": appErr.Code},
+	}
+}
+
+// originalError digs the error a resolver returned out of the wrappers
+// graphql-go puts around it. Errors of thunks are wrapped twice.
+func originalError(err error) error {
+	for {
+		var next error
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			next = e.OriginalError()
+		case *gqlerrors.Error:
+			next = e.OriginalError
+		}
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+func withCode(errs []gqlerrors.FormattedError, code string) []gqlerrors.FormattedError {
+	for i := range errs {
+		errs[i].Extensions = map[string]interface{}{"code": code}
+	}
+	return errs
+}
+
+type stateKey struct{}
+
+// requestState is what the resolvers of one request share: the caller and
+// the loaders.
+type requestState struct {
+	principal *handler.Principal
+	requestID string
+	students  *loader[*model.Student]
+	courses   *loader[*model.Course]
+	rosters   *loader[[]*model.Student]
+}
+
+func (h *Handler) newRequestState(principal *handler.Principal, requestID string) *requestState {
+	return &requestState{
+		principal: principal,
+		requestID: requestID,
+		students:  newLoader(usecase.MaxBatchSize, h.fetchStudents),
+		courses:   newLoader(usecase.MaxBatchSize, h.fetchCourses),
+		rosters:   newLoader(usecase.MaxBatchSize, h.studentUsecase.GetStudentsByCourseIDs),
+	}
+}
+
+func stateOf(ctx context.Context) *requestState {
+	if state, ok := ctx.Value(stateKey{}).(*requestState); ok {
+		return state
+	}
+	return &requestState{principal: &handler.Principal{}}
+}
+
+func requestIDOf(ctx context.Context) string {
+	return stateOf(ctx).requestID
+}
+
+func (h *Handler) fetchStudents(ctx context.Context, ids []string) (map[string]*model.Student, error) {
+	students, err := h.studentUsecase.GetStudentsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.Student, len(students))
+	for _, student := range students {
+		byID[student.ID.Hex()] = student
+	}
+	return byID, nil
+}
+
+func (h *Handler) fetchCourses(ctx context.Context, ids []string) (map[string]*model.Course, error) {
+	courses, err := h.courses.GetCourses(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.Course, len(courses))
+	for i := range courses {
+		byID[courses[i].ID] = &courses[i]
+	}
+	return byID, nil
+}
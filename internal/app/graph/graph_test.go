@@ -0,0 +1,247 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/courses"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeCourses struct {
+	calls   [][]string
+	courses map[string]model.Course
+	err     error
+}
+
+func (f *fakeCourses) GetCourses(ctx context.Context, ids []string) ([]model.Course, error) {
+	f.calls = append(f.calls, ids)
+	var courses []model.Course
+	for _, id := range ids {
+		if course, ok := f.courses[id]; ok {
+			courses = append(courses, course)
+		}
+	}
+	return courses, f.err
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string        `json:"message"`
+		Path       []interface{} `json:"path"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// serve runs one GraphQL request as principal, standing in for
+// AuthMiddleware.
+func serve(t *testing.T, m *mocks.MockStudentUsecase, courses CourseFetcher, principal *handler.Principal, body string, lang string) (int, *response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h, err := NewHandler(m, courses, config.GraphQLConfig{}, logrus.New())
+	require.NoError(t, err)
+	router := gin.New()
+	router.POST("/graphql", func(c *gin.Context) { handler.SetPrincipal(c, principal) }, h.Serve)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", lang)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	resp := &response{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp), w.Body.String())
+	return w.Code, resp
+}
+
+func apiKey(scopes ...string) *handler.Principal {
+	return &handler.Principal{AuthMethod: handler.AuthMethodAPIKey, Permissions: scopes, EmailVerified: true}
+}
+
+// sameKeys matches a batch of the keys in any order, as the executor
+// resolves sibling fields in no particular order.
+func sameKeys(keys ...string) interface{} {
+	want := append([]string(nil), keys...)
+	sort.Strings(want)
+	return mock.MatchedBy(func(batch []string) bool {
+		got := append([]string(nil), batch...)
+		sort.Strings(got)
+		return reflect.DeepEqual(want, got)
+	})
+}
+
+func query(q string) string {
+	body, _ := json.Marshal(map[string]string{"query": q})
+	return string(body)
+}
+
+func TestHandler_BatchesLookups(t *testing.T) {
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("GetStudentsByIDs", mock.Anything, []string{first.Hex(), second.Hex()}).Return([]*model.Student{
+		{ID: first, FirstName: "Dulat", Courses: []string{"c1", "c2"}},
+		{ID: second, FirstName: "Aruzhan", Courses: []string{"c2"}},
+	}, nil).Once()
+	mockStudentUsecase.On("GetStudentsByCourseIDs", mock.Anything, []string{"c1", "c2"}).Return(map[string][]*model.Student{
+		"c1": {{ID: first}},
+		"c2": {{ID: first}, {ID: second}},
+	}, nil).Once()
+	courses := &fakeCourses{courses: map[string]model.Course{"c1": {ID: "c1", Name: "Algebra"}, "c2": {ID: "c2", Name: "Physics"}}}
+
+	code, resp := serve(t, mockStudentUsecase, courses, apiKey(handler.PermStudentsRead, handler.PermRostersRead),
+		query(`{ students(ids: ["`+first.Hex()+`", "`+second.Hex()+`"]) { firstName courses { name students { id } } } }`), "")
+
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[
+		{"firstName": "Dulat", "courses": [
+			{"name": "Algebra", "students": [{"id": "`+first.Hex()+`"}]},
+			{"name": "Physics", "students": [{"id": "`+first.Hex()+`"}, {"id": "`+second.Hex()+`"}]}
+		]},
+		{"firstName": "Aruzhan", "courses": [
+			{"name": "Physics", "students": [{"id": "`+first.Hex()+`"}, {"id": "`+second.Hex()+`"}]}
+		]}
+	]`, string(resp.Data["students"]))
+	assert.Equal(t, [][]string{{"c1", "c2"}}, courses.calls, "courses are fetched in one call")
+	mockStudentUsecase.AssertExpectations(t)
+}
+
+func TestHandler_Authorization(t *testing.T) {
+	self, other := primitive.NewObjectID(), primitive.NewObjectID()
+	student := &handler.Principal{AuthMethod: handler.AuthMethodJWT, UserID: self.Hex(), Roles: []string{model.RoleStudent}, EmailVerified: true}
+
+	t.Run("Student reading themselves", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		mockStudentUsecase.On("GetStudentsByIDs", mock.Anything, []string{self.Hex()}).Return([]*model.Student{{ID: self, Email: "self@example.com"}}, nil)
+
+		_, resp := serve(t, mockStudentUsecase, &fakeCourses{}, student, query(`{ me { email } student(id: "`+self.Hex()+`") { email } }`), "")
+
+		require.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"email": "self@example.com"}`, string(resp.Data["me"]))
+		assert.JSONEq(t, `{"email": "self@example.com"}`, string(resp.Data["student"]))
+		mockStudentUsecase.AssertNumberOfCalls(t, "GetStudentsByIDs", 1)
+	})
+
+	t.Run("Student reading someone else", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+
+		_, resp := serve(t, mockStudentUsecase, &fakeCourses{}, student, query(`{ student(id: "`+other.Hex()+`") { email } }`), "")
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "forbidden", resp.Errors[0].Extensions.Code)
+		assert.Equal(t, []interface{}{"student"}, resp.Errors[0].Path)
+		assert.Equal(t, "null", string(resp.Data["student"]))
+	})
+
+	t.Run("Instructor reading rosters", func(t *testing.T) {
+		instructor := &handler.Principal{AuthMethod: handler.AuthMethodJWT, UserID: self.Hex(), Roles: []string{model.RoleInstructor}, EmailVerified: true}
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		mockStudentUsecase.On("GetStudentsByIDs", mock.Anything, []string{self.Hex()}).Return([]*model.Student{{ID: self, Courses: []string{"c1"}}}, nil).Once()
+		mockStudentUsecase.On("GetStudentsByCourseIDs", mock.Anything, sameKeys("c1", "c2")).Return(map[string][]*model.Student{"c1": {{ID: other}}}, nil).Once()
+		courses := &fakeCourses{courses: map[string]model.Course{"c1": {ID: "c1"}, "c2": {ID: "c2"}}}
+
+		_, resp := serve(t, mockStudentUsecase, courses, instructor, query(`{ a: course(id: "c1") { students { id } } b: course(id: "c2") { students { id } } }`), "")
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "forbidden", resp.Errors[0].Extensions.Code)
+		assert.Equal(t, []interface{}{"b", "students"}, resp.Errors[0].Path)
+		assert.JSONEq(t, `{"students": [{"id": "`+other.Hex()+`"}]}`, string(resp.Data["a"]))
+		mockStudentUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Unverified account enrolling", func(t *testing.T) {
+		unverified := &handler.Principal{AuthMethod: handler.AuthMethodJWT, UserID: self.Hex(), Roles: []string{model.RoleAdmin}, Permissions: []string{handler.PermStudentsWrite}}
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+
+		_, resp := serve(t, mockStudentUsecase, &fakeCourses{}, unverified, query(`mutation { enrollStudent(studentId: "`+self.Hex()+`", courseId: "c1") { id } }`), "")
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "email_not_verified", resp.Errors[0].Extensions.Code)
+		mockStudentUsecase.AssertNotCalled(t, "EnrollStudent", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandler_Enrollment(t *testing.T) {
+	studentID := primitive.NewObjectID()
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("EnrollStudent", mock.Anything, studentID.Hex(), "c1").Return(&model.Student{ID: studentID, Courses: []string{"c1"}}, nil)
+	courses := &fakeCourses{courses: map[string]model.Course{"c1": {ID: "c1", Name: "Algebra"}}}
+
+	_, resp := serve(t, mockStudentUsecase, courses, apiKey(handler.PermStudentsWrite),
+		query(`mutation { enrollStudent(studentId: "`+studentID.Hex()+`", courseId: "c1") { courseIds courses { name } } }`), "")
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"courseIds": ["c1"], "courses": [{"name": "Algebra"}]}`, string(resp.Data["enrollStudent"]))
+}
+
+func TestHandler_Errors(t *testing.T) {
+	studentID := primitive.NewObjectID()
+
+	t.Run("Store failure is hidden", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		mockStudentUsecase.On("GetStudentsByIDs", mock.Anything, []string{studentID.Hex()}).Return(nil, errors.New("mongo: connection refused"))
+
+		code, resp := serve(t, mockStudentUsecase, &fakeCourses{}, apiKey(handler.PermStudentsRead), query(`{ student(id: "`+studentID.Hex()+`") { id } }`), "ru")
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "internal_error", resp.Errors[0].Extensions.Code)
+		assert.Equal(t, "внутренняя ошибка сервера", resp.Errors[0].Message)
+	})
+
+	t.Run("Courses service down", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		mockStudentUsecase.On("GetStudentsByIDs", mock.Anything, []string{studentID.Hex()}).Return([]*model.Student{{ID: studentID, FirstName: "Dulat", Courses: []string{"c1"}}}, nil)
+		unavailable := &fakeCourses{err: courses.ErrUnavailable.Wrap(errors.New("GET /courses: 502 Bad Gateway"))}
+
+		_, resp := serve(t, mockStudentUsecase, unavailable, apiKey(handler.PermStudentsRead), query(`{ student(id: "`+studentID.Hex()+`") { firstName courses { id } } }`), "")
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "courses_unavailable", resp.Errors[0].Extensions.Code)
+		assert.Equal(t, []interface{}{"student", "courses"}, resp.Errors[0].Path)
+		assert.JSONEq(t, `{"firstName": "Dulat", "courses": null}`, string(resp.Data["student"]), "the student is kept")
+	})
+
+	t.Run("Too deep", func(t *testing.T) {
+		_, resp := serve(t, &mocks.MockStudentUsecase{}, &fakeCourses{}, apiKey(),
+			query(`{ me { courses { students { courses { students { courses { id } } } } } } }`), "")
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "query_too_deep", resp.Errors[0].Extensions.Code)
+		assert.Equal(t, "query depth 7 exceeds the limit of 6", resp.Errors[0].Message)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		_, resp := serve(t, &mocks.MockStudentUsecase{}, &fakeCourses{}, apiKey(), query(`{ me { password } }`), "")
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, codeValidationFailed, resp.Errors[0].Extensions.Code)
+	})
+
+	t.Run("Malformed body", func(t *testing.T) {
+		code, resp := serve(t, &mocks.MockStudentUsecase{}, &fakeCourses{}, apiKey(), `{"variables": {}}`, "")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "invalid_request", resp.Errors[0].Extensions.Code)
+	})
+}
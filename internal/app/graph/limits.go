@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/nurmeden/students-service/config"
+)
+
+// Defaults of the zero values of config.GraphQLConfig.
+const (
+	defaultMaxDepth       = 6
+	defaultMaxComplexity  = 2000
+	defaultListMultiplier = 10
+)
+
+// limits bounds the cost of queries before they run. Depth counts nested
+// fields; fragments add no depth. Complexity counts every field once and
+// multiplies the fields below a list by listMultiplier, since a list
+// resolves them once per element. Introspection fields are free.
+type limits struct {
+	maxDepth       int
+	maxComplexity  int
+	listMultiplier int
+}
+
+func newLimits(cfg config.GraphQLConfig) limits {
+	l := limits{
+		maxDepth:       cfg.MaxDepth,
+		maxComplexity:  cfg.MaxComplexity,
+		listMultiplier: cfg.ListMultiplier,
+	}
+	if l.maxDepth == 0 {
+		l.maxDepth = defaultMaxDepth
+	}
+	if l.maxComplexity == 0 {
+		l.maxComplexity = defaultMaxComplexity
+	}
+	if l.listMultiplier == 0 {
+		l.listMultiplier = defaultListMultiplier
+	}
+	return l
+}
+
+// check measures every operation of a validated document.
+func (l limits) check(schema *graphql.Schema, doc *ast.Document) error {
+	m := &measure{
+		multiplier: l.listMultiplier,
+		fragments:  map[string]*ast.FragmentDefinition{},
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		root := schema.QueryType()
+		if operation.Operation == ast.OperationTypeMutation {
+			root = schema.MutationType()
+		}
+		depth, complexity := m.selectionSet(schema, operation.SelectionSet, root)
+		if depth > l.maxDepth {
+			return errQueryTooDeep.With(depth, l.maxDepth)
+		}
+		if complexity > l.maxComplexity {
+			return errQueryTooComplex.With(complexity, l.maxComplexity)
+		}
+	}
+	return nil
+}
+
+type measure struct {
+	multiplier int
+	fragments  map[string]*ast.FragmentDefinition
+}
+
+// selectionSet returns the depth and complexity of the selections on
+// parent. Validation has ruled out unknown fields and fragment cycles.
+func (m *measure) selectionSet(schema *graphql.Schema, set *ast.SelectionSet, parent *graphql.Object) (depth, complexity int) {
+	if set == nil || parent == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = m.field(schema, selection, parent)
+		case *ast.InlineFragment:
+			d, c = m.selectionSet(schema, selection.SelectionSet, typeCondition(schema, selection.TypeCondition, parent))
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				d, c = m.selectionSet(schema, fragment.SelectionSet, typeCondition(schema, fragment.TypeCondition, parent))
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += c
+	}
+	return depth, complexity
+}
+
+func (m *measure) field(schema *graphql.Schema, field *ast.Field, parent *graphql.Object) (depth, complexity int) {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return 0, 0
+	}
+	definition, ok := parent.Fields()[name]
+	if !ok {
+		return 1, 1
+	}
+
+	multiplier := 1
+	fieldType := definition.Type
+	for {
+		if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nonNull.OfType
+			continue
+		}
+		if list, ok := fieldType.(*graphql.List); ok {
+			multiplier *= m.multiplier
+			fieldType = list.OfType
+			continue
+		}
+		break
+	}
+
+	object, _ := fieldType.(*graphql.Object)
+	childDepth, childComplexity := m.selectionSet(schema, field.SelectionSet, object)
+	return 1 + childDepth, 1 + multiplier*childComplexity
+}
+
+func typeCondition(schema *graphql.Schema, condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	object, _ := schema.Type(condition.Name.Value).(*graphql.Object)
+	return object
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_Check(t *testing.T) {
+	h, err := NewHandler(&mocks.MockStudentUsecase{}, nil, config.GraphQLConfig{MaxDepth: 4, MaxComplexity: 200}, logrus.New())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr error
+	}{
+		{
+			name:  "Within limits",
+			query: `{ me { id courses { id name } } }`,
+		},
+		{
+			name:    "Too deep",
+			query:   `{ me { courses { students { courses { id } } } } }`,
+			wantErr: errQueryTooDeep,
+		},
+		{
+			name:  "Fragments add no depth",
+			query: `{ me { ...withCourses } } fragment withCourses on Student { courses { students { id } } }`,
+		},
+		{
+			name:    "Fields below lists are multiplied",
+			query:   `{ students(ids: ["a"]) { courses { students { id } } } }`,
+			wantErr: errQueryTooComplex,
+		},
+		{
+			name:  "Introspection is free",
+			query: `{ __schema { types { name fields { name type { name ofType { name } } } } } }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+
+			err = h.limits.check(&h.schema, doc)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewLimits_Defaults(t *testing.T) {
+	assert.Equal(t, limits{maxDepth: defaultMaxDepth, maxComplexity: defaultMaxComplexity, listMultiplier: defaultListMultiplier}, newLimits(config.GraphQLConfig{}))
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// fetchFunc fetches the values of keys in one call. Keys it leaves out of
+// the map have no value.
+type fetchFunc[V any] func(ctx context.Context, keys []string) (map[string]V, error)
+
+// loader batches the lookups of one request, dataloader style. Resolvers
+// register keys with load and return thunks; the executor calls the thunks
+// only after it resolved every field of the level, so the first thunk
+// fetches the keys of all of them at once. Results are kept for the rest of
+// the request.
+type loader[V any] struct {
+	fetch    fetchFunc[V]
+	maxBatch int
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	values  map[string]V
+	errs    map[string]error
+}
+
+func newLoader[V any](maxBatch int, fetch fetchFunc[V]) *loader[V] {
+	return &loader[V]{
+		fetch:    fetch,
+		maxBatch: maxBatch,
+		queued:   map[string]bool{},
+		values:   map[string]V{},
+		errs:     map[string]error{},
+	}
+}
+
+// load queues key for the next batch and returns a thunk for its value.
+// The thunk reports false for keys without a value.
+func (l *loader[V]) load(ctx context.Context, key string) func() (V, bool, error) {
+	l.mu.Lock()
+	l.queue(key)
+	l.mu.Unlock()
+
+	return func() (V, bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.dispatch(ctx)
+		value, ok := l.values[key]
+		return value, ok, l.errs[key]
+	}
+}
+
+// loadMany is load for several keys. The values keep the order of keys and
+// leave out keys without a value.
+func (l *loader[V]) loadMany(ctx context.Context, keys []string) func() ([]V, error) {
+	l.mu.Lock()
+	for _, key := range keys {
+		l.queue(key)
+	}
+	l.mu.Unlock()
+
+	return func() ([]V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.dispatch(ctx)
+		values := make([]V, 0, len(keys))
+		for _, key := range keys {
+			if err := l.errs[key]; err != nil {
+				return nil, err
+			}
+			if value, ok := l.values[key]; ok {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+}
+
+func (l *loader[V]) queue(key string) {
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+}
+
+// dispatch fetches the pending keys in batches of at most maxBatch. A
+// failed batch fails each of its keys.
+func (l *loader[V]) dispatch(ctx context.Context) {
+	for len(l.pending) > 0 {
+		n := len(l.pending)
+		if n > l.maxBatch {
+			n = l.maxBatch
+		}
+		batch := l.pending[:n]
+		l.pending = l.pending[n:]
+
+		values, err := l.fetch(ctx, batch)
+		for _, key := range batch {
+			if err != nil {
+				l.errs[key] = err
+			} else if value, ok := values[key]; ok {
+				l.values[key] = value
+			}
+		}
+	}
+}
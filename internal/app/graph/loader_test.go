@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_Batches(t *testing.T) {
+	var batches [][]string
+	l := newLoader(2, func(ctx context.Context, keys []string) (map[string]int, error) {
+		batches = append(batches, append([]string(nil), keys...))
+		values := map[string]int{}
+		for _, key := range keys {
+			if key != "missing" {
+				values[key] = len(key)
+			}
+		}
+		return values, nil
+	})
+	ctx := context.Background()
+
+	a := l.load(ctx, "a")
+	many := l.loadMany(ctx, []string{"bb", "a", "missing"})
+	again := l.load(ctx, "bb")
+
+	value, ok, err := a()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	values, err := many()
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, values)
+	_, _, err = again()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "bb"}, {"missing"}}, batches, "keys are fetched once, in batches of maxBatch")
+
+	_, ok, err = l.load(ctx, "a")()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Len(t, batches, 2, "loaded keys are not fetched again")
+}
+
+func TestLoader_FailedBatch(t *testing.T) {
+	errFetch := errors.New("connection refused")
+	l := newLoader(10, func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, errFetch
+	})
+
+	_, err := l.loadMany(context.Background(), []string{"a", "b"})()
+
+	assert.ErrorIs(t, err, errFetch)
+}
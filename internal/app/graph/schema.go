@@ -0,0 +1,332 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+// newSchema builds the schema. Student and Course refer to each other, so
+// their fields are thunks. Fields that may fail are nullable, so a failed
+// field does not take its parent down with it.
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	var courseType *graphql.Object
+	studentType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Student",
+		Description: "A student, without the password hash.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":            {Type: graphql.NewNonNull(graphql.ID), Resolve: studentField(func(s *model.Student) interface{} { return s.ID.Hex() })},
+				"firstName":     {Type: graphql.NewNonNull(graphql.String), Resolve: studentField(func(s *model.Student) interface{} { return s.FirstName })},
+				"lastName":      {Type: graphql.NewNonNull(graphql.String), Resolve: studentField(func(s *model.Student) interface{} { return s.LastName })},
+				"email":         {Type: graphql.NewNonNull(graphql.String), Resolve: studentField(func(s *model.Student) interface{} { return s.Email })},
+				"age":           {Type: graphql.NewNonNull(graphql.String), Resolve: studentField(func(s *model.Student) interface{} { return s.Age })},
+				"role":          {Type: graphql.NewNonNull(graphql.String), Resolve: studentField(func(s *model.Student) interface{} { return s.EffectiveRole() })},
+				"emailVerified": {Type: graphql.NewNonNull(graphql.Boolean), Resolve: studentField(func(s *model.Student) interface{} { return s.EmailVerified })},
+				"courseIds": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+					Description: "IDs of the courses the student is enrolled in.",
+					Resolve:     studentField(func(s *model.Student) interface{} { return nonNilStrings(s.Courses) }),
+				},
+				"courses": {
+					Type:        graphql.NewList(graphql.NewNonNull(courseType)),
+					Description: "Courses the student is enrolled in, from the courses service. Courses it does not know are left out.",
+					Resolve:     h.resolveStudentCourses,
+				},
+			}
+		}),
+	})
+	courseType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Course",
+		Description: "A course of the courses service.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          {Type: graphql.NewNonNull(graphql.ID), Resolve: courseField(func(c *model.Course) interface{} { return c.ID })},
+				"name":        {Type: graphql.NewNonNull(graphql.String), Resolve: courseField(func(c *model.Course) interface{} { return c.Name })},
+				"description": {Type: graphql.NewNonNull(graphql.String), Resolve: courseField(func(c *model.Course) interface{} { return c.Description })},
+				"students": {
+					Type:        graphql.NewList(graphql.NewNonNull(studentType)),
+					Description: "The roster of the course. Requires the rosters:read permission or teaching the course.",
+					Resolve:     h.resolveCourseStudents,
+				},
+			}
+		}),
+	})
+
+	idArg := func(name string) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{name: {Type: graphql.NewNonNull(graphql.ID)}}
+	}
+	enrollmentArgs := graphql.FieldConfigArgument{
+		"studentId": {Type: graphql.NewNonNull(graphql.ID)},
+		"courseId":  {Type: graphql.NewNonNull(graphql.ID)},
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"me": {
+					Type:        studentType,
+					Description: "The signed-in student.",
+					Resolve:     h.resolveMe,
+				},
+				"student": {
+					Type:        studentType,
+					Description: "A student by ID. Requires the students:read permission unless it is the caller.",
+					Args:        idArg("id"),
+					Resolve:     h.resolveStudent,
+				},
+				"students": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(studentType))),
+					Description: "Students by ID, in the order of ids, leaving out unknown IDs. Requires the students:read permission.",
+					Args: graphql.FieldConfigArgument{
+						"ids": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))},
+					},
+					Resolve: h.resolveStudents,
+				},
+				"course": {
+					Type:        courseType,
+					Description: "A course by ID.",
+					Args:        idArg("id"),
+					Resolve:     h.resolveCourse,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"enrollStudent": {
+					Type:        studentType,
+					Description: "Enrolls a student in a course. Requires the students:write permission.",
+					Args:        enrollmentArgs,
+					Resolve:     h.resolveEnrollment(h.studentUsecase.EnrollStudent, "enroll student"),
+				},
+				"unenrollStudent": {
+					Type:        studentType,
+					Description: "Removes a student from a course. Requires the students:write permission.",
+					Args:        enrollmentArgs,
+					Resolve:     h.resolveEnrollment(h.studentUsecase.UnenrollStudent, "unenroll student"),
+				},
+			},
+		}),
+	})
+}
+
+func (h *Handler) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	principal := stateOf(p.Context).principal
+	if principal.IsAPIKey() || principal.UserID == "" {
+		return nil, h.deny(p, "field requires a signed-in user")
+	}
+	return h.loadStudent(p.Context, principal.UserID), nil
+}
+
+func (h *Handler) resolveStudent(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	principal := stateOf(p.Context).principal
+	if id != principal.UserID && !principal.HasPermission(handler.PermStudentsRead) {
+		return nil, h.deny(p, "not the owner and missing permission "+handler.PermStudentsRead)
+	}
+	return h.loadStudent(p.Context, id), nil
+}
+
+func (h *Handler) resolveStudents(p graphql.ResolveParams) (interface{}, error) {
+	if err := h.requirePermission(p, handler.PermStudentsRead); err != nil {
+		return nil, err
+	}
+	args, _ := p.Args["ids"].([]interface{})
+	if len(args) > usecase.MaxBatchSize {
+		return nil, usecase.ErrTooManyIDs.With(usecase.MaxBatchSize)
+	}
+	ids := make([]string, 0, len(args))
+	for _, arg := range args {
+		if id, ok := arg.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	thunk := stateOf(p.Context).students.loadMany(p.Context, ids)
+	return func() (interface{}, error) {
+		return thunk()
+	}, nil
+}
+
+func (h *Handler) resolveCourse(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	thunk := stateOf(p.Context).courses.load(p.Context, id)
+	return func() (interface{}, error) {
+		course, ok, err := thunk()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return course, nil
+	}, nil
+}
+
+func (h *Handler) resolveStudentCourses(p graphql.ResolveParams) (interface{}, error) {
+	student, _ := p.Source.(*model.Student)
+	if student == nil {
+		return nil, nil
+	}
+	thunk := stateOf(p.Context).courses.loadMany(p.Context, student.Courses)
+	return func() (interface{}, error) {
+		return thunk()
+	}, nil
+}
+
+// resolveCourseStudents checks roster access like handler.CanReadRoster,
+// but loads the record of an instructor through the students loader, so
+// rosters of many courses cost one lookup of it instead of one per course.
+func (h *Handler) resolveCourseStudents(p graphql.ResolveParams) (interface{}, error) {
+	course, _ := p.Source.(*model.Course)
+	if course == nil {
+		return nil, nil
+	}
+	if err := h.requireVerifiedEmail(p); err != nil {
+		return nil, err
+	}
+	state := stateOf(p.Context)
+	principal := state.principal
+	allowed := principal.HasPermission(handler.PermRostersRead)
+	if !allowed && !principal.HasRole(model.RoleInstructor) {
+		return nil, h.deny(p, "not an instructor of the course and missing permission "+handler.PermRostersRead)
+	}
+
+	var instructor func() (*model.Student, bool, error)
+	if !allowed {
+		instructor = state.students.load(p.Context, principal.UserID)
+	}
+	roster := state.rosters.load(p.Context, course.ID)
+	return func() (interface{}, error) {
+		if instructor != nil {
+			record, ok, err := instructor()
+			if err != nil {
+				return nil, fmt.Errorf("load instructor: %w", err)
+			}
+			if !ok || !contains(record.Courses, course.ID) {
+				return nil, h.deny(p, "not an instructor of the course and missing permission "+handler.PermRostersRead)
+			}
+		}
+		students, _, err := roster()
+		if err != nil {
+			return nil, fmt.Errorf("load roster: %w", err)
+		}
+		if students == nil {
+			students = []*model.Student{}
+		}
+		return students, nil
+	}, nil
+}
+
+type enrollmentFunc func(ctx context.Context, studentID, courseID string) (*model.Student, error)
+
+func (h *Handler) resolveEnrollment(enroll enrollmentFunc, action string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if err := h.requirePermission(p, handler.PermStudentsWrite); err != nil {
+			return nil, err
+		}
+		studentID, _ := p.Args["studentId"].(string)
+		courseID, _ := p.Args["courseId"].(string)
+
+		student, err := enroll(p.Context, studentID, courseID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", action, err)
+		}
+		return student, nil
+	}
+}
+
+// loadStudent is a thunk for the student with the given ID, or null if
+// there is none.
+func (h *Handler) loadStudent(ctx context.Context, id string) func() (interface{}, error) {
+	thunk := stateOf(ctx).students.load(ctx, id)
+	return func() (interface{}, error) {
+		student, ok, err := thunk()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return student, nil
+	}
+}
+
+func (h *Handler) requirePermission(p graphql.ResolveParams, permission string) error {
+	if err := h.requireVerifiedEmail(p); err != nil {
+		return err
+	}
+	if !stateOf(p.Context).principal.HasPermission(permission) {
+		return h.deny(p, "missing permission "+permission)
+	}
+	return nil
+}
+
+// requireVerifiedEmail holds back accounts whose email is not verified
+// yet, as on the REST routes. API keys count as verified.
+func (h *Handler) requireVerifiedEmail(p graphql.ResolveParams) error {
+	state := stateOf(p.Context)
+	if !state.principal.EmailVerified {
+		h.logger.WithFields(logrus.Fields{
+			"requestID": state.requestID,
+			"userID":    state.principal.UserID,
+			"field":     fieldOf(p),
+		}).Info("Request from unverified account refused")
+		return errEmailNotVerified
+	}
+	return nil
+}
+
+func (h *Handler) deny(p graphql.ResolveParams, reason string) error {
+	state := stateOf(p.Context)
+	h.logger.WithFields(logrus.Fields{
+		"requestID": state.requestID,
+		"userID":    state.principal.UserID,
+		"apiKeyID":  state.principal.APIKeyID,
+		"roles":     state.principal.Roles,
+		"field":     fieldOf(p),
+		"reason":    reason,
+	}).Warn("Unauthorized access attempt")
+	return errForbidden
+}
+
+func fieldOf(p graphql.ResolveParams) string {
+	return p.Info.ParentType.Name() + "." + p.Info.FieldName
+}
+
+func studentField(get func(*model.Student) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		student, _ := p.Source.(*model.Student)
+		if student == nil {
+			return nil, nil
+		}
+		return get(student), nil
+	}
+}
+
+func courseField(get func(*model.Course) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		course, _ := p.Source.(*model.Course)
+		if course == nil {
+			return nil, nil
+		}
+		return get(course), nil
+	}
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/i18n"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/nurmeden/students-service/internal/requestid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys are the lowercase REST headers.
+const (
+	apiKeyMetadata         = "x-api-key"
+	authorizationMetadata  = "authorization"
+	requestIDMetadata      = "x-request-id"
+	acceptLanguageMetadata = "accept-language"
+)
+
+var (
+	errMalformedAuth = apperror.Unauthorized("malformed_authorization", "malformed authorization metadata")
+	errMissingAuth   = apperror.Unauthorized("missing_authorization", "authorization metadata is missing")
+)
+
+type principalKey struct{}
+
+// PrincipalOf returns the caller AuthInterceptor authenticated, or the zero
+// Principal, which holds no roles or permissions.
+func PrincipalOf(ctx context.Context) *handler.Principal {
+	if principal, ok := ctx.Value(principalKey{}).(*handler.Principal); ok {
+		return principal
+	}
+	return &handler.Principal{}
+}
+
+// AuthInterceptor authenticates every call like AuthMiddleware: by an API
+// key in the x-api-key metadata or by an access token in the authorization
+// metadata.
+func AuthInterceptor(studentUsecase usecase.StudentUsecase) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		principal, err := authenticate(ctx, studentUsecase)
+		if err != nil {
+			return nil, err
+		}
+		return next(context.WithValue(ctx, principalKey{}, principal), req)
+	}
+}
+
+func authenticate(ctx context.Context, studentUsecase usecase.StudentUsecase) (*handler.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if apiKey := firstValue(md, apiKeyMetadata); apiKey != "" {
+		key, err := studentUsecase.AuthenticateAPIKey(ctx, apiKey)
+		if err != nil {
+			return nil, fmt.Errorf("authenticate API key: %w", err)
+		}
+		return handler.APIKeyPrincipal(key), nil
+	}
+
+	header := firstValue(md, authorizationMetadata)
+	if header == "" {
+		return nil, errMissingAuth
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errMalformedAuth
+	}
+	claims, err := studentUsecase.ValidateAccessToken(ctx, token)
+	if err != nil {
+		if !errors.Is(err, apperror.ErrUnauthorized) {
+			err = fmt.Errorf("validate access token: %w", err)
+		}
+		return nil, err
+	}
+	return handler.TokenPrincipal(claims), nil
+}
+
+// LoggingInterceptor must run first. It takes or makes up the request ID,
+// echoes it in the response header and puts it into the context, recovers
+// panics, turns the errors of the calls into statuses with localized
+// messages, and logs every call. Server errors are logged at error level
+// and their text is not sent.
+func LoggingInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (resp interface{}, err error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		requestID := handler.EnsureRequestID(firstValue(md, requestIDMetadata))
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+		ctx = requestid.NewContext(ctx, requestID)
+		start := time.Now()
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				resp, err = nil, fmt.Errorf("panic: %v", recovered)
+			}
+			entry := logger.WithFields(logrus.Fields{
+				"requestID": requestID,
+				"method":    info.FullMethod,
+				"duration":  time.Since(start),
+			})
+			if err == nil {
+				entry.WithField("status", codes.OK.String()).Info("Request served")
+				return
+			}
+
+			appErr := apperror.From(err)
+			st := statusOf(err, requestID, i18n.Negotiate(firstValue(md, acceptLanguageMetadata)))
+			entry = entry.WithError(err).WithFields(logrus.Fields{
+				"status": st.Code().String(),
+				"code":   appErr.Code,
+			})
+			if isServerError(st.Code()) {
+				entry.Error("Request failed")
+			} else {
+				entry.Debug("Request failed")
+			}
+			err = st.Err()
+		}()
+
+		return next(ctx, req)
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,274 @@
+// Package grpcserver serves the StudentService of api/students/v1 with the
+// same usecase, authentication and permissions as the REST API.
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+
+	studentsv1 "github.com/nurmeden/students-service/api/students/v1"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+var (
+	errCredentialsMissing = apperror.Validation("credentials_missing", "email and password are required")
+	errForbidden          = apperror.Forbidden("forbidden", "forbidden")
+	errEmailNotVerified   = apperror.Forbidden("email_not_verified", "email address is not verified")
+)
+
+// New returns a gRPC server with the StudentService behind the logging and
+// auth interceptors.
+func New(studentUsecase usecase.StudentUsecase, logger *logrus.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(LoggingInterceptor(logger), AuthInterceptor(studentUsecase)),
+	}, opts...)
+	server := grpc.NewServer(opts...)
+	studentsv1.RegisterStudentServiceServer(server, NewStudentServer(studentUsecase, logger))
+	return server
+}
+
+// StudentServer implements the StudentService. It expects the principal
+// AuthInterceptor puts into the context and returns domain errors, which
+// LoggingInterceptor turns into statuses.
+type StudentServer struct {
+	studentsv1.UnimplementedStudentServiceServer
+	studentUsecase usecase.StudentUsecase
+	logger         *logrus.Logger
+}
+
+func NewStudentServer(studentUsecase usecase.StudentUsecase, logger *logrus.Logger) *StudentServer {
+	return &StudentServer{
+		studentUsecase: studentUsecase,
+		logger:         logger,
+	}
+}
+
+func (s *StudentServer) GetStudent(ctx context.Context, req *studentsv1.GetStudentRequest) (*studentsv1.Student, error) {
+	principal := PrincipalOf(ctx)
+	if req.GetId() != principal.UserID && !principal.HasPermission(handler.PermStudentsRead) {
+		return nil, s.deny(ctx, "not the owner and missing permission "+handler.PermStudentsRead)
+	}
+
+	student, err := s.studentUsecase.GetStudentByID(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toStudent(student), nil
+}
+
+func (s *StudentServer) BatchGetStudents(ctx context.Context, req *studentsv1.BatchGetStudentsRequest) (*studentsv1.BatchGetStudentsResponse, error) {
+	if err := s.requirePermission(ctx, handler.PermStudentsRead); err != nil {
+		return nil, err
+	}
+
+	students, err := s.studentUsecase.GetStudentsByIDs(ctx, req.GetIds())
+	if err != nil {
+		return nil, err
+	}
+	resp := &studentsv1.BatchGetStudentsResponse{Students: toStudents(students)}
+	found := make(map[string]bool, len(students))
+	for _, student := range students {
+		found[student.ID.Hex()] = true
+	}
+	for _, id := range req.GetIds() {
+		if !found[id] {
+			found[id] = true
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+	return resp, nil
+}
+
+func (s *StudentServer) ListCourseStudents(ctx context.Context, req *studentsv1.ListCourseStudentsRequest) (*studentsv1.ListCourseStudentsResponse, error) {
+	if err := s.requireVerifiedEmail(ctx); err != nil {
+		return nil, err
+	}
+	if req.GetCourseId() == "" {
+		return nil, usecase.ErrInvalidCourseID
+	}
+	allowed, err := handler.CanReadRoster(ctx, s.studentUsecase, PrincipalOf(ctx), req.GetCourseId())
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, s.deny(ctx, "not an instructor of the course and missing permission "+handler.PermRostersRead)
+	}
+
+	students, err := s.studentUsecase.GetStudentsByCourseID(ctx, req.GetCourseId())
+	if err != nil {
+		return nil, fmt.Errorf("list course students: %w", err)
+	}
+	return &studentsv1.ListCourseStudentsResponse{Students: toStudents(students)}, nil
+}
+
+func (s *StudentServer) CreateStudent(ctx context.Context, req *studentsv1.CreateStudentRequest) (*studentsv1.Student, error) {
+	if err := s.requirePermission(ctx, handler.PermStudentsWrite); err != nil {
+		return nil, err
+	}
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		return nil, errCredentialsMissing
+	}
+
+	exists, err := s.studentUsecase.CheckEmailExistence(ctx, req.GetEmail())
+	if err != nil {
+		return nil, fmt.Errorf("check email existence: %w", err)
+	}
+	if exists {
+		return nil, usecase.ErrEmailTaken
+	}
+	student, err := s.studentUsecase.CreateStudent(ctx, &model.Student{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+		Password:  req.GetPassword(),
+		Age:       req.GetAge(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create student: %w", err)
+	}
+	return toStudent(student), nil
+}
+
+// UpdateStudent keeps the courses of the student; they only change through
+// enrollment.
+func (s *StudentServer) UpdateStudent(ctx context.Context, req *studentsv1.UpdateStudentRequest) (*studentsv1.Student, error) {
+	if err := s.requireVerifiedEmail(ctx); err != nil {
+		return nil, err
+	}
+	principal := PrincipalOf(ctx)
+	if req.GetId() != principal.UserID && !principal.HasPermission(handler.PermStudentsWrite) {
+		return nil, s.deny(ctx, "not the owner and missing permission "+handler.PermStudentsWrite)
+	}
+
+	current, err := s.studentUsecase.GetStudentByID(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	student, err := s.studentUsecase.UpdateStudent(ctx, req.GetId(), &model.Student{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Age:       req.GetAge(),
+		Courses:   current.Courses,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update student: %w", err)
+	}
+	return toStudent(student), nil
+}
+
+func (s *StudentServer) EnrollStudent(ctx context.Context, req *studentsv1.EnrollmentRequest) (*studentsv1.Student, error) {
+	if err := s.requirePermission(ctx, handler.PermStudentsWrite); err != nil {
+		return nil, err
+	}
+
+	student, err := s.studentUsecase.EnrollStudent(ctx, req.GetStudentId(), req.GetCourseId())
+	if err != nil {
+		return nil, fmt.Errorf("enroll student: %w", err)
+	}
+	return toStudent(student), nil
+}
+
+func (s *StudentServer) UnenrollStudent(ctx context.Context, req *studentsv1.EnrollmentRequest) (*studentsv1.Student, error) {
+	if err := s.requirePermission(ctx, handler.PermStudentsWrite); err != nil {
+		return nil, err
+	}
+
+	student, err := s.studentUsecase.UnenrollStudent(ctx, req.GetStudentId(), req.GetCourseId())
+	if err != nil {
+		return nil, fmt.Errorf("unenroll student: %w", err)
+	}
+	return toStudent(student), nil
+}
+
+// ValidateToken is token introspection for services, so like the REST
+// endpoint it is open to API keys only.
+func (s *StudentServer) ValidateToken(ctx context.Context, req *studentsv1.ValidateTokenRequest) (*studentsv1.ValidateTokenResponse, error) {
+	if !PrincipalOf(ctx).IsAPIKey() {
+		return nil, s.deny(ctx, "method requires an API key")
+	}
+	if err := s.requirePermission(ctx, handler.PermTokensIntrospect); err != nil {
+		return nil, err
+	}
+
+	introspection, err := s.studentUsecase.IntrospectToken(ctx, req.GetToken(), req.GetTokenTypeHint())
+	if err != nil {
+		return nil, fmt.Errorf("introspect token: %w", err)
+	}
+	return &studentsv1.ValidateTokenResponse{
+		Active:    introspection.Active,
+		Revoked:   introspection.Revoked,
+		TokenType: introspection.TokenType,
+		Subject:   introspection.Subject,
+		Roles:     introspection.Roles,
+		Tenant:    introspection.Tenant,
+		SessionId: introspection.SessionID,
+		ExpiresAt: introspection.ExpiresAt,
+	}, nil
+}
+
+func (s *StudentServer) requirePermission(ctx context.Context, permission string) error {
+	if err := s.requireVerifiedEmail(ctx); err != nil {
+		return err
+	}
+	if !PrincipalOf(ctx).HasPermission(permission) {
+		return s.deny(ctx, "missing permission "+permission)
+	}
+	return nil
+}
+
+// requireVerifiedEmail holds back accounts whose email is not verified
+// yet, as on the REST routes. API keys count as verified.
+func (s *StudentServer) requireVerifiedEmail(ctx context.Context) error {
+	if !PrincipalOf(ctx).EmailVerified {
+		s.logger.WithFields(logrus.Fields{
+			"userID": PrincipalOf(ctx).UserID,
+			"method": methodOf(ctx),
+		}).Info("Request from unverified account refused")
+		return errEmailNotVerified
+	}
+	return nil
+}
+
+func (s *StudentServer) deny(ctx context.Context, reason string) error {
+	principal := PrincipalOf(ctx)
+	s.logger.WithFields(logrus.Fields{
+		"userID":   principal.UserID,
+		"apiKeyID": principal.APIKeyID,
+		"roles":    principal.Roles,
+		"method":   methodOf(ctx),
+		"reason":   reason,
+	}).Warn("Unauthorized access attempt")
+	return errForbidden
+}
+
+func methodOf(ctx context.Context) string {
+	method, _ := grpc.Method(ctx)
+	return method
+}
+
+// toStudent converts a student for the wire, without the password hash.
+func toStudent(student *model.Student) *studentsv1.Student {
+	return &studentsv1.Student{
+		Id:            student.ID.Hex(),
+		FirstName:     student.FirstName,
+		LastName:      student.LastName,
+		Email:         student.Email,
+		Age:           student.Age,
+		Courses:       student.Courses,
+		Role:          student.EffectiveRole(),
+		EmailVerified: student.EmailVerified,
+	}
+}
+
+func toStudents(students []*model.Student) []*studentsv1.Student {
+	converted := make([]*studentsv1.Student, 0, len(students))
+	for _, student := range students {
+		converted = append(converted, toStudent(student))
+	}
+	return converted
+}
@@ -0,0 +1,303 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	studentsv1 "github.com/nurmeden/students-service/api/students/v1"
+	"github.com/nurmeden/students-service/config"
+	handler "github.com/nurmeden/students-service/internal/app/handlers"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/keys"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the StudentService over an in-memory connection.
+func newTestClient(t *testing.T, studentUsecase usecase.StudentUsecase) studentsv1.StudentServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := New(studentUsecase, logrus.New())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return studentsv1.NewStudentServiceClient(conn)
+}
+
+// newTokenUsecase is a usecase that validates access tokens with a real key
+// set; its stores are never reached for tokens that fail to parse.
+func newTokenUsecase(t *testing.T) usecase.StudentUsecase {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { cache.Close() })
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	_, err = keys.Generate(dir, keys.AlgEdDSA)
+	require.NoError(t, err)
+	keySet, err := keys.Load(dir)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	studentRepo, err := repository.NewStudentRepository(client, "studentsdb", "students", cache, logger)
+	require.NoError(t, err)
+	cfg := &config.Config{
+		JWT:      config.JWTConfig{Issuer: "students-service", Audience: "students-api", AccessTTL: time.Minute, RefreshTTL: time.Hour},
+		Password: config.PasswordConfig{BcryptCost: bcrypt.MinCost},
+	}
+	studentUsecase, err := usecase.NewStudentUsecase(*studentRepo,
+		*repository.NewTokenRepository(cache, logger),
+		*repository.NewLoginAttemptRepository(cache, logger),
+		*repository.NewOneTimeTokenRepository(cache, logger),
+		*repository.NewTwoFactorRepository(client, "studentsdb", logger),
+		*repository.NewIdentityRepository(client, "studentsdb", logger),
+		*repository.NewAPIKeyRepository(client, "studentsdb", logger),
+		nil, logger, cfg, keySet, nil)
+	require.NoError(t, err)
+	return studentUsecase
+}
+
+func withAPIKey(m *mocks.MockStudentUsecase, scopes ...string) context.Context {
+	m.On("AuthenticateAPIKey", mock.Anything, "sk_test").Return(&model.APIKey{ID: primitive.NewObjectID(), Scopes: scopes}, nil)
+	return metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, "sk_test")
+}
+
+func withToken(m *mocks.MockStudentUsecase, claims *usecase.AccessClaims) context.Context {
+	m.On("ValidateAccessToken", mock.Anything, "access_token").Return(claims, nil)
+	return metadata.AppendToOutgoingContext(context.Background(), authorizationMetadata, "Bearer access_token")
+}
+
+func errorInfoOf(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return nil
+}
+
+func TestStudentServer_GetStudent(t *testing.T) {
+	studentID := primitive.NewObjectID()
+	student := &model.Student{ID: studentID, FirstName: "Dulat", Email: "dulat@example.com", Password: "$2a$hash", Courses: []string{"c1"}}
+
+	tests := []struct {
+		name     string
+		ctx      func(m *mocks.MockStudentUsecase) context.Context
+		id       string
+		mockFn   func(m *mocks.MockStudentUsecase)
+		wantCode codes.Code
+		wantErr  string
+	}{
+		{
+			name: "API key with students:read",
+			ctx:  func(m *mocks.MockStudentUsecase) context.Context { return withAPIKey(m, handler.PermStudentsRead) },
+			id:   studentID.Hex(),
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentByID", mock.Anything, studentID.Hex()).Return(student, nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Student reading themselves",
+			ctx: func(m *mocks.MockStudentUsecase) context.Context {
+				return withToken(m, &usecase.AccessClaims{Subject: studentID.Hex(), Roles: []string{model.RoleStudent}})
+			},
+			id: studentID.Hex(),
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentByID", mock.Anything, studentID.Hex()).Return(student, nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "Student reading someone else",
+			ctx: func(m *mocks.MockStudentUsecase) context.Context {
+				return withToken(m, &usecase.AccessClaims{Subject: primitive.NewObjectID().Hex(), EmailVerified: true})
+			},
+			id:       studentID.Hex(),
+			mockFn:   func(m *mocks.MockStudentUsecase) {},
+			wantCode: codes.PermissionDenied,
+			wantErr:  "forbidden",
+		},
+		{
+			name: "Unknown student",
+			ctx:  func(m *mocks.MockStudentUsecase) context.Context { return withAPIKey(m, handler.PermStudentsRead) },
+			id:   "s2",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentByID", mock.Anything, "s2").Return(nil, usecase.ErrStudentNotFound)
+			},
+			wantCode: codes.NotFound,
+			wantErr:  "student_not_found",
+		},
+		{
+			name:     "No credentials",
+			ctx:      func(m *mocks.MockStudentUsecase) context.Context { return context.Background() },
+			id:       studentID.Hex(),
+			mockFn:   func(m *mocks.MockStudentUsecase) {},
+			wantCode: codes.Unauthenticated,
+			wantErr:  "missing_authorization",
+		},
+		{
+			name: "Store failure",
+			ctx:  func(m *mocks.MockStudentUsecase) context.Context { return withAPIKey(m, handler.PermStudentsRead) },
+			id:   studentID.Hex(),
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentByID", mock.Anything, studentID.Hex()).Return(nil, errors.New("mongo: connection refused"))
+			},
+			wantCode: codes.Internal,
+			wantErr:  "internal_error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			tt.mockFn(mockStudentUsecase)
+			client := newTestClient(t, mockStudentUsecase)
+
+			got, err := client.GetStudent(tt.ctx(mockStudentUsecase), &studentsv1.GetStudentRequest{Id: tt.id})
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantErr != "" {
+				assert.Equal(t, tt.wantErr, errorInfoOf(t, err).Reason)
+				assert.NotContains(t, status.Convert(err).Message(), "mongo")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, studentID.Hex(), got.Id)
+				assert.Equal(t, []string{"c1"}, got.Courses)
+				assert.Equal(t, model.RoleStudent, got.Role)
+			}
+			mockStudentUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStudentServer_BatchGetStudents(t *testing.T) {
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	ids := []string{first.Hex(), "unknown", second.Hex()}
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	ctx := withAPIKey(mockStudentUsecase, handler.PermStudentsRead)
+	mockStudentUsecase.On("GetStudentsByIDs", mock.Anything, ids).Return([]*model.Student{{ID: first}, {ID: second}}, nil)
+	client := newTestClient(t, mockStudentUsecase)
+
+	resp, err := client.BatchGetStudents(ctx, &studentsv1.BatchGetStudentsRequest{Ids: ids})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Students, 2)
+	assert.Equal(t, first.Hex(), resp.Students[0].Id)
+	assert.Equal(t, []string{"unknown"}, resp.MissingIds)
+}
+
+func TestStudentServer_Enrollment(t *testing.T) {
+	studentID := primitive.NewObjectID()
+
+	t.Run("Enroll with students:write", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		ctx := withAPIKey(mockStudentUsecase, handler.PermStudentsWrite)
+		mockStudentUsecase.On("EnrollStudent", mock.Anything, studentID.Hex(), "c1").Return(&model.Student{ID: studentID, Courses: []string{"c1"}}, nil)
+		client := newTestClient(t, mockStudentUsecase)
+
+		got, err := client.EnrollStudent(ctx, &studentsv1.EnrollmentRequest{StudentId: studentID.Hex(), CourseId: "c1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c1"}, got.Courses)
+	})
+
+	t.Run("Unenroll without students:write", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		ctx := withAPIKey(mockStudentUsecase, handler.PermStudentsRead)
+		client := newTestClient(t, mockStudentUsecase)
+
+		_, err := client.UnenrollStudent(ctx, &studentsv1.EnrollmentRequest{StudentId: studentID.Hex(), CourseId: "c1"})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		mockStudentUsecase.AssertNotCalled(t, "UnenrollStudent", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid course in Russian", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		ctx := metadata.AppendToOutgoingContext(withAPIKey(mockStudentUsecase, handler.PermStudentsWrite), acceptLanguageMetadata, "ru")
+		mockStudentUsecase.On("EnrollStudent", mock.Anything, studentID.Hex(), "").Return(nil, usecase.ErrInvalidCourseID)
+		client := newTestClient(t, mockStudentUsecase)
+
+		_, err := client.EnrollStudent(ctx, &studentsv1.EnrollmentRequest{StudentId: studentID.Hex()})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, "некорректный идентификатор курса", status.Convert(err).Message())
+	})
+}
+
+func TestStudentServer_ValidateToken(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	ctx := withAPIKey(mockStudentUsecase, handler.PermTokensIntrospect)
+	mockStudentUsecase.On("IntrospectToken", mock.Anything, "token", usecase.TokenTypeAccess).Return(&model.TokenIntrospection{
+		Active:    true,
+		TokenType: usecase.TokenTypeAccess,
+		Subject:   "s1",
+		Roles:     []string{model.RoleStudent},
+		ExpiresAt: 1700000000,
+	}, nil)
+	client := newTestClient(t, mockStudentUsecase)
+
+	resp, err := client.ValidateToken(ctx, &studentsv1.ValidateTokenRequest{Token: "token", TokenTypeHint: usecase.TokenTypeAccess})
+
+	require.NoError(t, err)
+	assert.True(t, resp.Active)
+	assert.Equal(t, "s1", resp.Subject)
+	assert.Equal(t, int64(1700000000), resp.ExpiresAt)
+}
+
+func TestAuthInterceptor_InvalidBearerToken(t *testing.T) {
+	client := newTestClient(t, newTokenUsecase(t))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationMetadata, "Bearer not-a-jwt")
+	_, err := client.GetStudent(ctx, &studentsv1.GetStudentRequest{Id: "s1"})
+
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "invalid_token", errorInfoOf(t, err).Reason)
+}
+
+func TestLoggingInterceptor_RequestID(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	client := newTestClient(t, mockStudentUsecase)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDMetadata, "req-123")
+	_, err := client.GetStudent(ctx, &studentsv1.GetStudentRequest{Id: "s1"}, grpc.Header(&header))
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"req-123"}, header.Get(requestIDMetadata))
+	assert.Equal(t, "req-123", errorInfoOf(t, err).Metadata["request_id"])
+}
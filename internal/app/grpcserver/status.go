@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/i18n"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details of the statuses.
+const ErrorDomain = "students-service"
+
+// CodeOf maps the kind of a domain error to its gRPC code, as StatusOf does
+// to HTTP statuses.
+func CodeOf(err *apperror.Error) codes.Code {
+	switch err.Kind {
+	case apperror.ErrValidation:
+		return codes.InvalidArgument
+	case apperror.ErrUnauthorized:
+		return codes.Unauthenticated
+	case apperror.ErrForbidden:
+		return codes.PermissionDenied
+	case apperror.ErrNotFound:
+		return codes.NotFound
+	case apperror.ErrConflict:
+		return codes.AlreadyExists
+	case apperror.ErrUnprocessable:
+		return codes.FailedPrecondition
+	case apperror.ErrTooManyRequests:
+		return codes.ResourceExhausted
+	case apperror.ErrUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// statusOf is the status for err, with the message in lang and the error
+// code and request ID in an ErrorInfo. Statuses pass through as they are.
+func statusOf(err error, requestID, lang string) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	appErr := apperror.From(err)
+	message, ok := i18n.Message(lang, appErr.Code, appErr.Args...)
+	if !ok {
+		message = appErr.Message
+	}
+
+	details := []protoiface.MessageV1{&errdetails.ErrorInfo{
+		Reason:   appErr.Code,
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{"request_id": requestID},
+	}}
+	if retryAfter, ok := retryAfterOf(err); ok {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+	st := status.New(CodeOf(appErr), message)
+	if detailed, err := st.WithDetails(details...); err == nil {
+		return detailed
+	}
+	return st
+}
+
+func retryAfterOf(err error) (time.Duration, bool) {
+	var lockoutErr *usecase.LockoutError
+	if errors.As(err, &lockoutErr) {
+		return lockoutErr.RetryAfter, true
+	}
+	var rateLimitErr *usecase.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
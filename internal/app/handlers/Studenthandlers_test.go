@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
 	"github.com/nurmeden/students-service/internal/app/model"
 	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/nurmeden/students-service/internal/requestid"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -45,9 +47,9 @@ func TestStudentHandler_SignIn(t *testing.T) {
 				c: &gin.Context{}, // Replace with actual context
 			},
 			expectedCode: http.StatusOK,
-			expectedBody: "{\"token\":\"auth_token\"}", // \"refresh_token\":\"refresh_token\"
+			expectedBody: "{\"refresh_token\":\"refresh_token\",\"token\":\"auth_token\"}",
 			mockFn: func() {
-				mockStudentUsecase.On("SignIn", mock.Anything, mock.Anything).Return(&model.AuthToken{Token: "auth_token"}, nil)
+				mockStudentUsecase.On("SignIn", mock.Anything, mock.Anything).Return(&model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"}, nil)
 			},
 			jsonBody: "{\"email\":\"test@test.com\",\"password\":\"password\"}",
 		},
@@ -104,11 +106,17 @@ func TestStudentHandler_CreateStudent(t *testing.T) {
 				c: &gin.Context{},
 			},
 			expectedCode: http.StatusCreated,
-			expectedBody: "{\"_id\":\"gdfhdhd\", \"firstName\":\"Dulat\", \"lastName\":\"Nurmeden\", \"password\":\"qwerty\", \"email\":\"nurmeden@gmail.com\", \"age\":\"eht\", \"courses\":null}",
+			expectedBody: "{\"ID\":\"000000000000000000000000\",\"firstName\":\"Dulat\",\"lastName\":\"Nurmeden\",\"email\":\"test@test.com\",\"age\":\"eht\",\"courses\":null,\"role\":\"student\",\"emailVerified\":false}",
 			mockFn: func() {
-				mockStudentUsecase.On("CreateStudent", mock.Anything, mock.Anything).Return(nil)
-
-				// mockStudentUsecase.On("Create", mock.Anything, mock.Anything).Return(&model.Student{}, nil)
+				mockStudentUsecase.On("CheckEmailExistence", mock.Anything, "test@test.com").Return(false, nil)
+				mockStudentUsecase.On("CreateStudent", mock.Anything, mock.Anything).Return(&model.Student{
+					FirstName: "Dulat",
+					LastName:  "Nurmeden",
+					Password:  "qwerty",
+					Email:     "test@test.com",
+					Age:       "eht",
+					Role:      model.RoleStudent,
+				}, nil)
 			},
 			jsonBody: "{\"email\":\"test@test.com\",\"password\":\"password\"}",
 		},
@@ -134,3 +142,61 @@ func TestStudentHandler_CreateStudent(t *testing.T) {
 		})
 	}
 }
+
+type fakeStudentCourses struct {
+	requestID string
+}
+
+func (f *fakeStudentCourses) GetStudentCourses(ctx context.Context, studentID string) (*model.CourseResponse, error) {
+	f.requestID = requestid.FromContext(ctx)
+	return &model.CourseResponse{CourseData: []model.Course{{ID: "c1", Name: "Algebra", Students: []string{studentID}}}}, nil
+}
+
+func TestStudentHandler_RequestContext(t *testing.T) {
+	fromRequest := mock.MatchedBy(func(ctx context.Context) bool { return requestid.FromContext(ctx) == "req-1" })
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("GetStudentByID", fromRequest, "s1").Return(&model.Student{FirstName: "Dulat"}, nil)
+	courses := &fakeStudentCourses{}
+	h := NewStudentHandler(mockStudentUsecase, courses, logrus.New(), testCookies())
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/students/:id", h.GetStudentByID)
+	router.GET("/students/:id/courses", h.GetStudentCourses)
+
+	for _, path := range []string{"/students/s1", "/students/s1/courses"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+	assert.Equal(t, "req-1", courses.requestID)
+	mockStudentUsecase.AssertExpectations(t)
+}
+
+func TestStudentHandler_UpdateStudentsKeepsCourses(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("GetStudentByID", mock.Anything, "i1").Return(&model.Student{FirstName: "Dulat", Courses: []string{"c1"}, Role: model.RoleInstructor}, nil)
+	mockStudentUsecase.On("UpdateStudent", mock.Anything, "i1", mock.MatchedBy(func(student *model.Student) bool {
+		return student.FirstName == "Aru" && len(student.Courses) == 1 && student.Courses[0] == "c1"
+	})).Return(&model.Student{FirstName: "Aru", Courses: []string{"c1"}, Role: model.RoleInstructor}, nil)
+	h := NewStudentHandler(mockStudentUsecase, &fakeStudentCourses{}, logrus.New(), nil)
+	authorizer := NewAuthorizer(mockStudentUsecase, logrus.New())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		roles := []string{model.RoleInstructor}
+		SetPrincipal(c, &Principal{AuthMethod: AuthMethodJWT, UserID: "i1", Roles: roles, Permissions: PermissionsFor(roles)})
+	})
+	router.PUT("/students/:id", authorizer.RequireSelfOrPermission("id", PermStudentsWrite), h.UpdateStudents)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/students/i1", strings.NewReader(`{"firstName":"Aru","courses":["c1","c2"]}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "c2")
+	mockStudentUsecase.AssertExpectations(t)
+}
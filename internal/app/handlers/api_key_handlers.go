@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+)
+
+var errInvalidScope = apperror.Validation("invalid_scope", "invalid scope %s")
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Admin only. The key is shown once in the response; send it in the X-API-Key header. Scopes are students:read, students:write, rosters:read and tokens:introspect.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.APIKeyRequest true "Name, scopes and optional expiry"
+// @Success 201 {object} model.APIKey
+// @Router /admin/api-keys [post]
+func (h *StudentHandler) CreateAPIKey(c *gin.Context) {
+	var request model.APIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !IsAPIKeyScope(scope) {
+			respondError(c, errInvalidScope.With(scope))
+			return
+		}
+	}
+
+	key, err := h.studentUsecase.CreateAPIKey(c.Request.Context(), request.Name, request.Scopes, request.ExpiresAt, CurrentPrincipal(c).UserID)
+	if err != nil {
+		respondError(c, fmt.Errorf("create API key: %w", err))
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{"adminID": CurrentPrincipal(c).UserID, "apiKeyID": key.ID.Hex(), "scopes": key.Scopes}).Info("API key created")
+	respondJSON(c, http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Admin only. Revoked and expired keys are included.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.APIKey
+// @Router /admin/api-keys [get]
+func (h *StudentHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.studentUsecase.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		respondError(c, fmt.Errorf("list API keys: %w", err))
+		return
+	}
+	respondList(c, keys, len(keys))
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {string} string "message: API key revoked"
+// @Router /admin/api-keys/{id} [delete]
+func (h *StudentHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.studentUsecase.RevokeAPIKey(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, fmt.Errorf("revoke API key: %w", err))
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{"adminID": CurrentPrincipal(c).UserID, "apiKeyID": c.Param("id")}).Info("API key revoked")
+	respondMessage(c, http.StatusOK, "api_key_revoked")
+}
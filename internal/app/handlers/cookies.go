@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/model"
+)
+
+const (
+	// AuthModeHeader with the value "cookie" asks for the tokens of a
+	// sign-in or refresh in cookies instead of the response body.
+	AuthModeHeader = "X-Auth-Mode"
+	AuthModeCookie = "cookie"
+
+	// CSRFHeader carries the value of the CSRF cookie back on unsafe
+	// requests authenticated by cookie.
+	CSRFHeader = "X-CSRF-Token"
+
+	// refreshCookiePath limits the refresh cookie to the auth routes;
+	// refreshCookiePathV2 to those of v2.
+	refreshCookiePath   = "/api/auth"
+	refreshCookiePathV2 = "/api/v2/auth"
+
+	// cookieModeKey marks a request whose tokens go into cookies although
+	// it carries no AuthModeHeader.
+	cookieModeKey = "cookieMode"
+
+	// oidcStateCookie holds the hash of the state of a single sign-on
+	// started by this browser.
+	oidcStateCookie = "oidc_state"
+)
+
+// Cookies issues and reads the auth cookies of browser clients. Cookie mode
+// is on when ServerConfig.CookieName is set: the access token goes into an
+// HttpOnly cookie of that name, the refresh token into one restricted to
+// the auth routes, and a CSRF token into a cookie the frontend can read and
+// has to echo in the X-CSRF-Token header (double submit). The cookies are
+// Secure with SSL and always in production mode, where TLS usually ends at
+// a proxy in front of the service. A nil *Cookies is cookie mode off.
+type Cookies struct {
+	name       string
+	secure     bool
+	csrf       bool
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewCookies(server config.ServerConfig, jwt config.JWTConfig) *Cookies {
+	if server.CookieName == "" {
+		return nil
+	}
+	return &Cookies{
+		name:       server.CookieName,
+		secure:     server.SSL || server.Mode == config.ModeProduction,
+		csrf:       server.CSRF,
+		accessTTL:  jwt.AccessTTL,
+		refreshTTL: jwt.RefreshTTL,
+	}
+}
+
+func (k *Cookies) Enabled() bool {
+	return k != nil
+}
+
+func (k *Cookies) refreshName() string { return k.name + "_refresh" }
+func (k *Cookies) csrfName() string    { return k.name + "_csrf" }
+
+// wanted reports whether the tokens of this request's response go into
+// cookies.
+func (k *Cookies) wanted(c *gin.Context) bool {
+	return k.Enabled() && (c.GetHeader(AuthModeHeader) == AuthModeCookie || c.GetBool(cookieModeKey))
+}
+
+// setTokens puts the tokens into cookies and returns the new CSRF token.
+func (k *Cookies) setTokens(c *gin.Context, authToken *model.AuthToken) (string, error) {
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+	k.set(c, k.name, authToken.Token, "/", k.accessTTL, true)
+	k.set(c, k.refreshName(), authToken.RefreshToken, refreshPath(c), k.refreshTTL, true)
+	k.set(c, k.csrfName(), csrfToken, "/", k.refreshTTL, false)
+	return csrfToken, nil
+}
+
+// clear expires the auth cookies, if cookie mode is on.
+func (k *Cookies) clear(c *gin.Context) {
+	if !k.Enabled() {
+		return
+	}
+	k.set(c, k.name, "", "/", -1, true)
+	k.set(c, k.refreshName(), "", refreshPath(c), -1, true)
+	k.set(c, k.csrfName(), "", "/", -1, false)
+}
+
+func (k *Cookies) set(c *gin.Context, name, value, path string, ttl time.Duration, httpOnly bool) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   k.secure,
+		HttpOnly: httpOnly,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// setOIDCState binds a single sign-on to the browser that starts it, so
+// that a callback URL planted in another browser cannot sign that browser
+// in (login CSRF). The cookie is set whether or not cookie mode is on.
+func (k *Cookies) setOIDCState(c *gin.Context, state string, ttl time.Duration) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    hashOIDCState(state),
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   k.Enabled() && k.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// checkOIDCState reports whether the callback with state comes back to the
+// browser that started the sign-on, and expires the cookie.
+func (k *Cookies) checkOIDCState(c *gin.Context, state string) bool {
+	cookie, err := c.Cookie(oidcStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   k.Enabled() && k.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return err == nil && subtle.ConstantTimeCompare([]byte(cookie), []byte(hashOIDCState(state))) == 1
+}
+
+func hashOIDCState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// refreshPath is the path of the refresh cookie for the API version of the
+// request, so that the refresh route of that version receives it.
+func refreshPath(c *gin.Context) string {
+	if isV2(c) {
+		return refreshCookiePathV2
+	}
+	return refreshCookiePath
+}
+
+func (k *Cookies) accessToken(c *gin.Context) string {
+	return k.value(c, k.name)
+}
+
+func (k *Cookies) refreshToken(c *gin.Context) string {
+	return k.value(c, k.refreshName())
+}
+
+func (k *Cookies) value(c *gin.Context, name string) string {
+	if !k.Enabled() {
+		return ""
+	}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// checkCSRF reports whether a request authenticated by cookie may go on.
+// Safe methods always may; others must echo the CSRF cookie in the header.
+func (k *Cookies) checkCSRF(c *gin.Context) bool {
+	if !k.csrf {
+		return true
+	}
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	cookie, header := k.value(c, k.csrfName()), c.GetHeader(CSRFHeader)
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// respondTokens answers a sign-in or refresh with the tokens in the body,
+// or in cookies with only the CSRF token in the body. extra is merged into
+// the body either way.
+func (h *StudentHandler) respondTokens(c *gin.Context, authToken *model.AuthToken, extra gin.H) {
+	response := gin.H{}
+	if h.cookies.wanted(c) {
+		csrfToken, err := h.cookies.setTokens(c, authToken)
+		if err != nil {
+			respondError(c, fmt.Errorf("create CSRF token: %w", err))
+			return
+		}
+		response["csrf_token"] = csrfToken
+	} else {
+		response["token"] = authToken.Token
+		response["refresh_token"] = authToken.RefreshToken
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	respondJSON(c, http.StatusOK, response)
+}
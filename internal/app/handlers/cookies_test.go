@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testCookies() *Cookies {
+	return NewCookies(
+		config.ServerConfig{CookieName: "jwt-token", SSL: true, CSRF: true},
+		config.JWTConfig{AccessTTL: 15 * time.Minute, RefreshTTL: time.Hour},
+	)
+}
+
+func TestAuthMiddleware_HeaderAndCookie(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("ValidateAccessToken", mock.Anything, "good").Return(&usecase.AccessClaims{
+		Subject:   "user-1",
+		ID:        "jti-1",
+		SessionID: "sid-1",
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	}, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AuthMiddleware(mockStudentUsecase, testCookies()))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, CurrentPrincipal(c).UserID) }
+	router.GET("/me", ok)
+	router.POST("/me", ok)
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		cookies       map[string]string
+		csrfHeader    string
+		expectedCode  int
+	}{
+		{name: "bearer header", method: http.MethodGet, authorization: "Bearer good", expectedCode: http.StatusOK},
+		{name: "header without a space", method: http.MethodGet, authorization: "good", expectedCode: http.StatusUnauthorized},
+		{name: "other scheme", method: http.MethodGet, authorization: "Basic good", expectedCode: http.StatusUnauthorized},
+		{name: "empty token", method: http.MethodGet, authorization: "Bearer ", expectedCode: http.StatusUnauthorized},
+		{name: "nothing", method: http.MethodGet, expectedCode: http.StatusUnauthorized},
+		{name: "cookie on a safe method", method: http.MethodGet, cookies: map[string]string{"jwt-token": "good"}, expectedCode: http.StatusOK},
+		{name: "cookie without CSRF header", method: http.MethodPost, cookies: map[string]string{"jwt-token": "good", "jwt-token_csrf": "c1"}, expectedCode: http.StatusForbidden},
+		{name: "cookie with wrong CSRF header", method: http.MethodPost, cookies: map[string]string{"jwt-token": "good", "jwt-token_csrf": "c1"}, csrfHeader: "c2", expectedCode: http.StatusForbidden},
+		{name: "cookie with CSRF header", method: http.MethodPost, cookies: map[string]string{"jwt-token": "good", "jwt-token_csrf": "c1"}, csrfHeader: "c1", expectedCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(CSRFHeader, tt.csrfHeader)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestStudentHandler_SignInCookieMode(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("SignIn", mock.Anything, mock.Anything).Return(&model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"}, nil)
+	h := NewStudentHandler(mockStudentUsecase, nil, logrus.New(), testCookies())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/sign-in", strings.NewReader(`{"email":"test@test.com","password":"password"}`))
+	c.Request.Header.Set(AuthModeHeader, AuthModeCookie)
+
+	h.SignIn(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "auth_token")
+	assert.NotContains(t, w.Body.String(), "refresh_token")
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Contains(t, cookies, "jwt-token")
+	assert.Equal(t, "auth_token", cookies["jwt-token"].Value)
+	assert.True(t, cookies["jwt-token"].HttpOnly)
+	assert.True(t, cookies["jwt-token"].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies["jwt-token"].SameSite)
+	require.Contains(t, cookies, "jwt-token_refresh")
+	assert.Equal(t, "/api/auth", cookies["jwt-token_refresh"].Path)
+	require.Contains(t, cookies, "jwt-token_csrf")
+	assert.False(t, cookies["jwt-token_csrf"].HttpOnly, "the frontend reads the CSRF cookie")
+	assert.Contains(t, w.Body.String(), cookies["jwt-token_csrf"].Value)
+}
+
+func TestNewCookies_SecureInProduction(t *testing.T) {
+	tests := []struct {
+		name   string
+		server config.ServerConfig
+		secure bool
+	}{
+		{name: "production without SSL", server: config.ServerConfig{CookieName: "jwt-token", Mode: config.ModeProduction}, secure: true},
+		{name: "development with SSL", server: config.ServerConfig{CookieName: "jwt-token", Mode: config.ModeDevelopment, SSL: true}, secure: true},
+		{name: "development without SSL", server: config.ServerConfig{CookieName: "jwt-token", Mode: config.ModeDevelopment}, secure: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewCookies(tt.server, config.JWTConfig{AccessTTL: time.Minute, RefreshTTL: time.Hour})
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/sign-in", nil)
+
+			_, err := k.setTokens(c, &model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"})
+			require.NoError(t, err)
+
+			cookies := w.Result().Cookies()
+			require.Len(t, cookies, 3)
+			for _, cookie := range cookies {
+				assert.Equal(t, tt.secure, cookie.Secure, cookie.Name)
+			}
+		})
+	}
+}
+
+func TestStudentHandler_RefreshTokenFromCookie(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("Refresh", mock.Anything, "old_refresh").Return(&model.AuthToken{Token: "new_auth", RefreshToken: "new_refresh"}, nil)
+	h := NewStudentHandler(mockStudentUsecase, nil, logrus.New(), testCookies())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/auth/refresh-token", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "jwt-token_refresh", Value: "old_refresh"})
+	c.Request.AddCookie(&http.Cookie{Name: "jwt-token_csrf", Value: "c1"})
+	c.Request.Header.Set(CSRFHeader, "c1")
+
+	h.RefreshToken(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "new_refresh")
+	var refreshed string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "jwt-token_refresh" {
+			refreshed = cookie.Value
+		}
+	}
+	assert.Equal(t, "new_refresh", refreshed)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+)
+
+// EnrollStudent godoc
+// @Summary Enroll a student in a course
+// @Description Adds the course to the student's courses. Enrolling twice changes nothing. Send an Idempotency-Key to retry safely.
+// @Tags students
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Student ID"
+// @Param Idempotency-Key header string false "Key to retry the request with"
+// @Param request body model.EnrollmentRequest true "Course"
+// @Success 200 {object} model.Student
+// @Router /v2/students/{id}/courses [post]
+func (h *StudentHandler) EnrollStudent(c *gin.Context) {
+	var request model.EnrollmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	student, err := h.studentUsecase.EnrollStudent(c.Request.Context(), c.Param("id"), request.CourseID)
+	if err != nil {
+		respondError(c, fmt.Errorf("enroll student: %w", err))
+		return
+	}
+	student.Password = ""
+	respondJSON(c, http.StatusOK, student)
+}
+
+// UnenrollStudent godoc
+// @Summary Remove a student from a course
+// @Description Removes the course from the student's courses.
+// @Tags students
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Student ID"
+// @Param courseId path string true "Course ID"
+// @Success 200 {object} model.Student
+// @Router /v2/students/{id}/courses/{courseId} [delete]
+func (h *StudentHandler) UnenrollStudent(c *gin.Context) {
+	student, err := h.studentUsecase.UnenrollStudent(c.Request.Context(), c.Param("id"), c.Param("courseId"))
+	if err != nil {
+		respondError(c, fmt.Errorf("unenroll student: %w", err))
+		return
+	}
+	student.Password = ""
+	respondJSON(c, http.StatusOK, student)
+}
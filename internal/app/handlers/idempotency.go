@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from the store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody bounds the request bodies read for hashing.
+	maxIdempotentBody = 1 << 20
+)
+
+var (
+	errInvalidIdempotencyKey = apperror.Validation("invalid_idempotency_key", "Idempotency-Key must be 1 to 255 printable characters")
+	errIdempotencyKeyReused  = apperror.Unprocessable("idempotency_key_reused", "Idempotency-Key was already used for a different request")
+	errIdempotencyKeyInUse   = apperror.Conflict("idempotency_key_in_use", "a request with this Idempotency-Key is still in progress")
+)
+
+// IdempotencyStore keeps the responses of requests by their key.
+// *repository.IdempotencyRepository implements it.
+type IdempotencyStore interface {
+	Reserve(key, requestHash string, lockTTL time.Duration) (*model.IdempotentResponse, error)
+	Save(key string, response *model.IdempotentResponse, ttl time.Duration) error
+	Release(key string) error
+}
+
+// Idempotency makes requests with an Idempotency-Key header safe to retry.
+// The first request with a key runs and its response is stored for the
+// window, with a hash of the method, path and body; retries get the stored
+// response back, marked with Idempotent-Replayed. A key reused for a
+// different request is refused with 422, and retries while the first
+// request still runs with 409. Keys are scoped to the caller, so routes
+// behind AuthMiddleware must mount it after. Server errors are not stored,
+// so they can be retried. If the store fails, requests run without the
+// guarantee.
+func Idempotency(store IdempotencyStore, cfg config.IdempotencyConfig, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || cfg.Window <= 0 {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength || !printable(key) {
+			respondError(c, errInvalidIdempotencyKey)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody+1))
+		if err == nil && len(body) > maxIdempotentBody {
+			err = errors.New("request body too large")
+		}
+		if err != nil {
+			respondError(c, invalidRequest(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := idempotencyScope(CurrentPrincipal(c)) + ":" + key
+		requestHash := hashRequest(c.Request.Method, c.Request.URL.Path, body)
+		entry := logger.WithFields(logrus.Fields{
+			"requestID":      RequestIDOf(c),
+			"idempotencyKey": key,
+		})
+		stored, err := store.Reserve(storeKey, requestHash, cfg.LockTTL)
+		if err != nil {
+			entry.WithError(err).Error("Failed to reserve idempotency key")
+			c.Next()
+			return
+		}
+		if stored != nil {
+			replay(c, stored, requestHash)
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := store.Release(storeKey); err != nil {
+				entry.WithError(err).Error("Failed to release idempotency key")
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		err = store.Save(storeKey, &model.IdempotentResponse{
+			RequestHash: requestHash,
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}, cfg.Window)
+		if err != nil {
+			entry.WithError(err).Error("Failed to store idempotent response")
+			return
+		}
+		saved = true
+	}
+}
+
+func replay(c *gin.Context, stored *model.IdempotentResponse, requestHash string) {
+	switch {
+	case stored.RequestHash != requestHash:
+		respondError(c, errIdempotencyKeyReused)
+	case stored.Status == 0:
+		respondError(c, errIdempotencyKeyInUse)
+	default:
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+		c.Abort()
+	}
+}
+
+// idempotencyScope keeps the keys of callers apart. Requests without
+// credentials, such as sign-ups, share one scope.
+func idempotencyScope(principal *Principal) string {
+	switch {
+	case principal.IsAPIKey():
+		return "api_key:" + principal.APIKeyID
+	case principal.UserID != "":
+		return "user:" + principal.UserID
+	default:
+		return "anonymous"
+	}
+}
+
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyRecorder keeps a copy of the response body.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newIdempotencyRouter(t *testing.T, h *StudentHandler, principal *Principal) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { cache.Close() })
+	idempotency := Idempotency(repository.NewIdempotencyRepository(cache, logrus.New()), config.IdempotencyConfig{Window: time.Hour, LockTTL: time.Minute}, logrus.New())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { SetPrincipal(c, principal) })
+	router.POST("/api/v2/students/:id/courses", APIVersion(2), idempotency, h.EnrollStudent)
+	router.POST("/api/students/", idempotency, h.CreateStudent)
+	return router
+}
+
+func postWithKey(router *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_Enrollment(t *testing.T) {
+	studentID := primitive.NewObjectID()
+	path := "/api/v2/students/" + studentID.Hex() + "/courses"
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("EnrollStudent", mock.Anything, studentID.Hex(), "c1").Return(&model.Student{ID: studentID, Courses: []string{"c1"}}, nil).Once()
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+	router := newIdempotencyRouter(t, h, &Principal{AuthMethod: AuthMethodAPIKey, APIKeyID: "k1"})
+
+	first := postWithKey(router, path, "key-1", `{"courseId":"c1"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := postWithKey(router, path, "key-1", `{"courseId":"c1"}`)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+
+	reused := postWithKey(router, path, "key-1", `{"courseId":"c2"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Contains(t, reused.Body.String(), `"code":"idempotency_key_reused"`)
+
+	mockStudentUsecase.AssertNumberOfCalls(t, "EnrollStudent", 1)
+}
+
+func TestIdempotency_CreateStudent(t *testing.T) {
+	body := `{"email":"dulat@example.com","password":"password"}`
+
+	t.Run("Retry gets the created student instead of email_taken", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		mockStudentUsecase.On("CheckEmailExistence", mock.Anything, "dulat@example.com").Return(false, nil).Once()
+		mockStudentUsecase.On("CreateStudent", mock.Anything, mock.Anything).Return(&model.Student{Email: "dulat@example.com"}, nil).Once()
+		h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+		router := newIdempotencyRouter(t, h, &Principal{})
+
+		first := postWithKey(router, "/api/students/", "key-1", body)
+		retry := postWithKey(router, "/api/students/", "key-1", body)
+
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		mockStudentUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Server errors are not stored", func(t *testing.T) {
+		mockStudentUsecase := &mocks.MockStudentUsecase{}
+		mockStudentUsecase.On("CheckEmailExistence", mock.Anything, "dulat@example.com").Return(true, nil).Once()
+		mockStudentUsecase.On("CheckEmailExistence", mock.Anything, "dulat@example.com").Return(false, assert.AnError).Once()
+		mockStudentUsecase.On("CheckEmailExistence", mock.Anything, "dulat@example.com").Return(false, nil)
+		mockStudentUsecase.On("CreateStudent", mock.Anything, mock.Anything).Return(&model.Student{Email: "dulat@example.com"}, nil)
+		h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+		router := newIdempotencyRouter(t, h, &Principal{})
+
+		assert.Equal(t, http.StatusConflict, postWithKey(router, "/api/students/", "key-4xx", body).Code)
+		assert.Equal(t, http.StatusConflict, postWithKey(router, "/api/students/", "key-4xx", body).Code, "client errors are replayed")
+		assert.Equal(t, http.StatusInternalServerError, postWithKey(router, "/api/students/", "key-5xx", body).Code)
+		assert.Equal(t, http.StatusCreated, postWithKey(router, "/api/students/", "key-5xx", body).Code, "server errors can be retried")
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		h := &StudentHandler{studentUsecase: &mocks.MockStudentUsecase{}, logger: logrus.New()}
+		router := newIdempotencyRouter(t, h, &Principal{})
+
+		w := postWithKey(router, "/api/students/", strings.Repeat("k", 256), body)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"invalid_idempotency_key"`)
+	})
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer cache.Close()
+	store := repository.NewIdempotencyRepository(cache, logrus.New())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/students/", Idempotency(store, config.IdempotencyConfig{Window: time.Hour, LockTTL: time.Minute}, logrus.New()), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	_, err := store.Reserve("anonymous:key-1", hashRequest(http.MethodPost, "/api/students/", []byte(`{}`)), time.Minute)
+	assert.NoError(t, err)
+
+	w := postWithKey(router, "/api/students/", "key-1", `{}`)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"idempotency_key_in_use"`)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+)
+
+// IntrospectToken godoc
+// @Summary Introspect a token
+// @Description RFC 7662 token introspection for other services. Requires an API key with the tokens:introspect scope. Accepts access and refresh tokens; token_type_hint (access_token or refresh_token) only decides which is tried first. Revoked tokens come back inactive with revoked set.
+// @Tags Authentication
+// @Accept x-www-form-urlencoded
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} model.TokenIntrospection
+// @Router /auth/introspect [post]
+func (h *StudentHandler) IntrospectToken(c *gin.Context) {
+	var request model.IntrospectionRequest
+	if err := c.ShouldBind(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	introspection, err := h.studentUsecase.IntrospectToken(c.Request.Context(), request.Token, request.TokenTypeHint)
+	if err != nil {
+		respondError(c, fmt.Errorf("introspect token: %w", err))
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"apiKeyID": CurrentPrincipal(c).APIKeyID,
+		"active":   introspection.Active,
+		"revoked":  introspection.Revoked,
+	}).Debug("Token introspected")
+	c.Header("Cache-Control", "no-store")
+	// The response format is RFC 7662's, without the v2 envelope.
+	c.JSON(http.StatusOK, introspection)
+}
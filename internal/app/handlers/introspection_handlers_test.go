@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStudentHandler_IntrospectToken(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("AuthenticateAPIKey", mock.Anything, "sk_gateway").Return(&model.APIKey{ID: primitive.NewObjectID(), Scopes: []string{PermTokensIntrospect}}, nil)
+	mockStudentUsecase.On("AuthenticateAPIKey", mock.Anything, "sk_reader").Return(&model.APIKey{ID: primitive.NewObjectID(), Scopes: []string{PermStudentsRead}}, nil)
+	mockStudentUsecase.On("ValidateAccessToken", mock.Anything, "admin-token").Return(&usecase.AccessClaims{
+		Subject:   "admin-1",
+		Roles:     []string{model.RoleAdmin},
+		ID:        "jti-1",
+		SessionID: "sid-1",
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	}, nil)
+	mockStudentUsecase.On("IntrospectToken", mock.Anything, "some-token", "refresh_token").Return(&model.TokenIntrospection{Active: true, TokenType: "refresh_token", Subject: "student-1"}, nil)
+
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+	authorizer := NewAuthorizer(mockStudentUsecase, logrus.New())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/introspect", AuthMiddleware(mockStudentUsecase, nil), authorizer.RequireAPIKey(), authorizer.RequirePermission(PermTokensIntrospect), h.IntrospectToken)
+
+	tests := []struct {
+		name         string
+		apiKey       string
+		bearer       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "API key with scope",
+			apiKey:       "sk_gateway",
+			body:         "token=some-token&token_type_hint=refresh_token",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"active":true,"token_type":"refresh_token","sub":"student-1"}`,
+		},
+		{name: "API key without scope", apiKey: "sk_reader", body: "token=some-token", expectedCode: http.StatusForbidden},
+		{name: "signed-in admin", bearer: "admin-token", body: "token=some-token", expectedCode: http.StatusForbidden},
+		{name: "missing token", apiKey: "sk_gateway", body: "", expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/introspect", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+				assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
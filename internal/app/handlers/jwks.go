@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/keys"
+)
+
+// JWKS godoc
+// @Summary Public signing keys
+// @Description Publishes the public keys that verify access tokens, selected by their kid header
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} keys.JWKS
+// @Router /.well-known/jwks.json [get]
+func JWKS(keySet *keys.KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(keys.JWKSMaxAge.Seconds())))
+		c.JSON(http.StatusOK, keySet.JWKS())
+	}
+}
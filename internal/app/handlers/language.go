@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/i18n"
+)
+
+const languageKey = "language"
+
+// Language negotiates the language of the response messages from the
+// Accept-Language header. Error codes do not depend on it.
+func Language() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(languageKey, lang)
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// languageOf returns the language Language chose for the request, or
+// negotiates it on routes without the middleware.
+func languageOf(c *gin.Context) string {
+	if lang := c.GetString(languageKey); lang != "" {
+		return lang
+	}
+	return i18n.Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// respondMessage answers with the message of key in the language of the
+// request.
+func respondMessage(c *gin.Context, status int, key string) {
+	respondJSON(c, status, gin.H{"message": i18n.Text(languageOf(c), key)})
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newLanguageTestRouter(h *StudentHandler) *gin.Engine {
+	router := gin.New()
+	router.Use(Language())
+	router.GET("/students/:id", h.GetStudentByID)
+	router.DELETE("/students/:id", h.DeleteStudent)
+	router.POST("/me/email", h.ChangeMyEmail)
+	return router
+}
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		name                    string
+		method, path, body      string
+		acceptLanguage          string
+		mockFn                  func(m *mocks.MockStudentUsecase)
+		expectedCode            int
+		expectedContentLanguage string
+		expectedBody            string
+	}{
+		{
+			name:   "Error in Russian keeps its code",
+			method: "GET", path: "/students/missing",
+			acceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentByID", mock.Anything, "missing").Return(nil, usecase.ErrStudentNotFound)
+			},
+			expectedCode:            http.StatusNotFound,
+			expectedContentLanguage: "ru",
+			expectedBody:            `{"type":"about:blank","title":"Not Found","status":404,"detail":"студент не найден","instance":"/students/missing","code":"student_not_found"}`,
+		},
+		{
+			name:   "Success message in Kazakh",
+			method: "DELETE", path: "/students/s1",
+			acceptLanguage: "kk",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("DeleteStudent", mock.Anything, "s1").Return(nil)
+			},
+			expectedCode:            http.StatusOK,
+			expectedContentLanguage: "kk",
+			expectedBody:            `{"message":"Студент жойылды"}`,
+		},
+		{
+			name:   "Unsupported language falls back to English",
+			method: "DELETE", path: "/students/s1",
+			acceptLanguage: "fr-FR",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("DeleteStudent", mock.Anything, "s1").Return(nil)
+			},
+			expectedCode:            http.StatusOK,
+			expectedContentLanguage: "en",
+			expectedBody:            `{"message":"Student deleted"}`,
+		},
+		{
+			name:   "Validation errors name the field",
+			method: "POST", path: "/me/email", body: `{"email":"not an email"}`,
+			acceptLanguage:          "ru",
+			mockFn:                  func(m *mocks.MockStudentUsecase) {},
+			expectedCode:            http.StatusBadRequest,
+			expectedContentLanguage: "ru",
+			expectedBody:            `{"type":"about:blank","title":"Bad Request","status":400,"detail":"некорректное тело запроса","instance":"/me/email","code":"invalid_request","invalid_params":[{"name":"email","reason":"должен быть корректным адресом электронной почты"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			tt.mockFn(mockStudentUsecase)
+			h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			newLanguageTestRouter(h).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedContentLanguage, w.Header().Get("Content-Language"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockStudentUsecase.AssertExpectations(t)
+		})
+	}
+}
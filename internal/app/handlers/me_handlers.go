@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+)
+
+var errWrongPassword = apperror.Forbidden("wrong_password", "current password is incorrect")
+
+// GetMe godoc
+// @Summary Get my profile
+// @Description Returns the record of the authenticated student.
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.Student
+// @Router /me [get]
+func (h *StudentHandler) GetMe(c *gin.Context) {
+	student, err := h.studentUsecase.GetStudentByID(c.Request.Context(), CurrentPrincipal(c).UserID)
+	if err != nil {
+		respondError(c, fmt.Errorf("get profile: %w", err))
+		return
+	}
+	student.Password = ""
+	respondJSON(c, http.StatusOK, student)
+}
+
+// UpdateMe godoc
+// @Summary Update my profile
+// @Description Changes the given fields of the authenticated student's profile; omitted fields stay as they are. The email and password have routes of their own.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.ProfileUpdate true "Fields to change"
+// @Success 200 {object} model.Student
+// @Router /me [patch]
+func (h *StudentHandler) UpdateMe(c *gin.Context) {
+	var update model.ProfileUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	student, err := h.studentUsecase.UpdateProfile(c.Request.Context(), CurrentPrincipal(c).UserID, &update)
+	if err != nil {
+		respondError(c, fmt.Errorf("update profile: %w", err))
+		return
+	}
+	student.Password = ""
+	respondJSON(c, http.StatusOK, student)
+}
+
+// DeleteMe godoc
+// @Summary Delete my account
+// @Description Deletes the authenticated student's account and ends all of its sessions.
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {string} string "message: Account deleted"
+// @Router /me [delete]
+func (h *StudentHandler) DeleteMe(c *gin.Context) {
+	if err := h.studentUsecase.DeleteAccount(c.Request.Context(), CurrentPrincipal(c).UserID); err != nil {
+		respondError(c, fmt.Errorf("delete account: %w", err))
+		return
+	}
+	h.cookies.clear(c)
+	respondMessage(c, http.StatusOK, "account_deleted")
+}
+
+// ChangePassword godoc
+// @Summary Change my password
+// @Description Requires the current password. Other sessions are signed out; this one stays.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.PasswordChangeRequest true "Current and new password"
+// @Success 200 {string} string "message: Password changed"
+// @Router /me/password [post]
+func (h *StudentHandler) ChangePassword(c *gin.Context) {
+	var request model.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+	principal := CurrentPrincipal(c)
+	request.UserID = principal.UserID
+	request.SessionID = principal.SessionID
+	request.ClientIP = c.ClientIP()
+
+	err := h.studentUsecase.ChangePassword(c.Request.Context(), &request)
+	switch {
+	case err == nil:
+		respondMessage(c, http.StatusOK, "password_changed")
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		// A 401 would read as the session having ended.
+		respondError(c, errWrongPassword.Wrap(err))
+	default:
+		respondError(c, fmt.Errorf("change password: %w", err))
+	}
+}
+
+// ChangeMyEmail godoc
+// @Summary Change my email address
+// @Description Mails a verification link to the new address, which must not belong to another account. The stored email changes only after it is verified.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.EmailChangeRequest true "New email"
+// @Success 202 {string} string "message: Verification email sent to the new address"
+// @Router /me/email [post]
+func (h *StudentHandler) ChangeMyEmail(c *gin.Context) {
+	var request model.EmailChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	err := h.studentUsecase.RequestEmailChange(c.Request.Context(), CurrentPrincipal(c).UserID, request.Email)
+	h.respondEmailChange(c, err)
+}
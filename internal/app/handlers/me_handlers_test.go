@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/password"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMeContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	SetPrincipal(c, &Principal{AuthMethod: AuthMethodJWT, UserID: "user-1", SessionID: "sid-1"})
+	return c, w
+}
+
+func TestStudentHandler_GetMeHidesPassword(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("GetStudentByID", mock.Anything, "user-1").Return(&model.Student{FirstName: "Dulat", Email: "d@example.com", Password: "$argon2id$hash", Role: model.RoleStudent}, nil)
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	c, w := newMeContext("GET", "/me", "")
+	h.GetMe(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.Contains(t, w.Body.String(), `"firstName":"Dulat"`)
+}
+
+func TestStudentHandler_UpdateMe(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("UpdateProfile", mock.Anything, "user-1", mock.MatchedBy(func(update *model.ProfileUpdate) bool {
+		return update.FirstName != nil && *update.FirstName == "Aru" && update.LastName == nil && update.Age == nil
+	})).Return(&model.Student{FirstName: "Aru"}, nil)
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	c, w := newMeContext("PATCH", "/me", `{"firstName":"Aru","role":"admin"}`)
+	h.UpdateMe(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockStudentUsecase.AssertExpectations(t)
+}
+
+func TestStudentHandler_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "Changed", expectedCode: http.StatusOK, expectedBody: `{"message":"Password changed"}`},
+		{name: "Wrong current password", err: usecase.ErrInvalidCredentials, expectedCode: http.StatusForbidden, expectedBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"current password is incorrect","instance":"/me/password","code":"wrong_password"}`},
+		{name: "Weak new password", err: &usecase.WeakPasswordError{Err: password.ErrTooShort}, expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			mockStudentUsecase.On("ChangePassword", mock.Anything, mock.MatchedBy(func(request *model.PasswordChangeRequest) bool {
+				return request.UserID == "user-1" && request.SessionID == "sid-1" && request.CurrentPassword == "old pass" && request.NewPassword == "new passphrase"
+			})).Return(tt.err)
+			h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+			c, w := newMeContext("POST", "/me/password", `{"current_password":"old pass","new_password":"new passphrase"}`)
+			h.ChangePassword(c)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockStudentUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStudentHandler_ChangeMyEmail(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("RequestEmailChange", mock.Anything, "user-1", "taken@example.com").Return(usecase.ErrEmailTaken)
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	c, w := newMeContext("POST", "/me/email", `{"email":"taken@example.com"}`)
+	h.ChangeMyEmail(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Conflict","status":409,"detail":"email already exists","instance":"/me/email","code":"email_taken"}`, w.Body.String())
+}
@@ -1,53 +1,74 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
 	"strings"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/usecase"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+const (
+	// APIKeyHeader carries the API key of service-to-service calls.
+	APIKeyHeader = "X-API-Key"
+
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// AuthMiddleware authenticates the caller by an API key in the X-API-Key
+// header, by the access token in the Authorization header or, in cookie
+// mode, by the access token cookie. Either way the permissions end up in the
+// gin context, so the Authorizer checks treat them alike. API keys have no
+// user and no roles. Unsafe requests authenticated by cookie must pass the
+// CSRF check.
+func AuthMiddleware(studentUsecase usecase.StudentUsecase, cookies *Cookies) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			key, err := studentUsecase.AuthenticateAPIKey(c.Request.Context(), apiKey)
+			if err != nil {
+				respondError(c, fmt.Errorf("authenticate API key: %w", err))
+				return
+			}
+			SetPrincipal(c, APIKeyPrincipal(key))
+			c.Next()
 			return
 		}
-		tokens := strings.Split(tokenString, " ")
-		fmt.Printf("tokens: %v\n", tokens)
-		token, err := jwt.Parse(tokens[1], func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
 
-			return []byte("dfhdfjhgdjkff"), nil
-		})
-		if err != nil {
-			log.Println("1")
-			log.Println(err.Error())
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		var tokenString string
+		if header := c.GetHeader("Authorization"); header != "" {
+			scheme, token, ok := strings.Cut(header, " ")
+			token = strings.TrimSpace(token)
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				respondError(c, errMalformedAuth)
+				return
+			}
+			tokenString = token
+		} else if token := cookies.accessToken(c); token != "" {
+			if !cookies.checkCSRF(c) {
+				respondError(c, errCSRF)
+				return
+			}
+			tokenString = token
+		} else {
+			respondError(c, errMissingAuth)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			userID, ok := claims["userID"].(string)
-			if !ok {
-				log.Println("2")
-				log.Println(err.Error())
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-				return
+		claims, err := studentUsecase.ValidateAccessToken(c.Request.Context(), tokenString)
+		if err != nil {
+			// Revoked and invalid tokens alike are unauthorized; anything
+			// else is a failure of the token store.
+			if !errors.Is(err, apperror.ErrUnauthorized) {
+				err = fmt.Errorf("validate access token: %w", err)
 			}
-			c.Set("userID", userID)
-			c.Next()
+			respondError(c, err)
 			return
 		}
-		log.Println("3")
-		log.Println(err.Error())
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
+
+		SetPrincipal(c, TokenPrincipal(claims))
+		c.Next()
 	}
 }
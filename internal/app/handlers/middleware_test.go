@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/keys"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("AuthenticateAPIKey", mock.Anything, "sk_courses").Return(&model.APIKey{ID: primitive.NewObjectID(), Scopes: []string{PermStudentsRead, PermRostersRead}}, nil)
+	mockStudentUsecase.On("AuthenticateAPIKey", mock.Anything, "sk_revoked").Return(nil, usecase.ErrInvalidAPIKey)
+	authorizer := NewAuthorizer(mockStudentUsecase, logrus.New())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AuthMiddleware(mockStudentUsecase, nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/students/:id", authorizer.RequireSelfOrPermission("id", PermStudentsRead), ok)
+	router.PUT("/students/:id", authorizer.RequireSelfOrPermission("id", PermStudentsWrite), ok)
+	router.GET("/courses/:id/students", authorizer.RequireRosterAccess("id"), ok)
+	router.POST("/auth/logout", authorizer.RequireUser(), ok)
+	router.PUT("/admin/students/:id/role", authorizer.RequireRole(model.RoleAdmin), ok)
+
+	tests := []struct {
+		name         string
+		key          string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{name: "reads a student", key: "sk_courses", method: http.MethodGet, path: "/students/student-1", expectedCode: http.StatusOK},
+		{name: "reads a roster", key: "sk_courses", method: http.MethodGet, path: "/courses/math/students", expectedCode: http.StatusOK},
+		{name: "writes without the scope", key: "sk_courses", method: http.MethodPut, path: "/students/student-1", expectedCode: http.StatusForbidden},
+		{name: "acts as a user", key: "sk_courses", method: http.MethodPost, path: "/auth/logout", expectedCode: http.StatusForbidden},
+		{name: "uses an admin route", key: "sk_courses", method: http.MethodPut, path: "/admin/students/student-1/role", expectedCode: http.StatusForbidden},
+		{name: "revoked key", key: "sk_revoked", method: http.MethodGet, path: "/students/student-1", expectedCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestStudentHandler_CreateAPIKeyRejectsUnknownScope(t *testing.T) {
+	h := &StudentHandler{studentUsecase: &mocks.MockStudentUsecase{}, logger: logrus.New()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"batch","scopes":["roles:manage"]}`))
+
+	h.CreateAPIKey(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid scope roles:manage","instance":"/admin/api-keys","code":"invalid_scope"}`, w.Body.String())
+}
+
+// newTokenUsecase is a usecase that validates access tokens with a real key
+// set; its stores are never reached for tokens that fail to parse.
+func newTokenUsecase(t *testing.T) usecase.StudentUsecase {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { cache.Close() })
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	_, err = keys.Generate(dir, keys.AlgEdDSA)
+	require.NoError(t, err)
+	keySet, err := keys.Load(dir)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	studentRepo, err := repository.NewStudentRepository(client, "studentsdb", "students", cache, logger)
+	require.NoError(t, err)
+	cfg := &config.Config{
+		JWT:      config.JWTConfig{Issuer: "students-service", Audience: "students-api", AccessTTL: time.Minute, RefreshTTL: time.Hour},
+		Password: config.PasswordConfig{BcryptCost: bcrypt.MinCost},
+	}
+	studentUsecase, err := usecase.NewStudentUsecase(*studentRepo,
+		*repository.NewTokenRepository(cache, logger),
+		*repository.NewLoginAttemptRepository(cache, logger),
+		*repository.NewOneTimeTokenRepository(cache, logger),
+		*repository.NewTwoFactorRepository(client, "studentsdb", logger),
+		*repository.NewIdentityRepository(client, "studentsdb", logger),
+		*repository.NewAPIKeyRepository(client, "studentsdb", logger),
+		nil, logger, cfg, keySet, nil)
+	require.NoError(t, err)
+	return studentUsecase
+}
+
+func TestAuthMiddleware_InvalidBearerToken(t *testing.T) {
+	dir := t.TempDir()
+	_, err := keys.Generate(dir, keys.AlgEdDSA)
+	require.NoError(t, err)
+	otherKeys, err := keys.Load(dir)
+	require.NoError(t, err)
+	now := time.Now()
+	unknownKid, err := otherKeys.Sign(&usecase.AccessClaims{
+		Subject:   "student-1",
+		ID:        "jti-1",
+		SessionID: "session-1",
+		Issuer:    "students-service",
+		Audience:  usecase.Audience{"students-api"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AuthMiddleware(newTokenUsecase(t), nil))
+	router.GET("/students/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for name, token := range map[string]string{
+		"malformed":   "not-a-jwt",
+		"unknown kid": unknownKid,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/students/student-1", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"invalid_token"`)
+		})
+	}
+}
@@ -2,8 +2,10 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -18,12 +20,37 @@ func (m *MockStudentUsecase) CreateStudent(ctx context.Context, student *model.S
 
 func (m *MockStudentUsecase) GetStudentByID(ctx context.Context, id string) (*model.Student, error) {
 	args := m.Called(ctx, id)
-	return args.Get(0).(*model.Student), args.Error(1)
+	student, _ := args.Get(0).(*model.Student)
+	return student, args.Error(1)
 }
 
-func (m *MockStudentUsecase) GetStudentByCoursesID(ctx context.Context, id string) (*model.Student, error) {
+func (m *MockStudentUsecase) GetStudentsByCourseID(ctx context.Context, id string) ([]*model.Student, error) {
 	args := m.Called(ctx, id)
-	return args.Get(0).(*model.Student), args.Error(1)
+	return args.Get(0).([]*model.Student), args.Error(1)
+}
+
+func (m *MockStudentUsecase) GetStudentsByIDs(ctx context.Context, ids []string) ([]*model.Student, error) {
+	args := m.Called(ctx, ids)
+	students, _ := args.Get(0).([]*model.Student)
+	return students, args.Error(1)
+}
+
+func (m *MockStudentUsecase) GetStudentsByCourseIDs(ctx context.Context, courseIDs []string) (map[string][]*model.Student, error) {
+	args := m.Called(ctx, courseIDs)
+	rosters, _ := args.Get(0).(map[string][]*model.Student)
+	return rosters, args.Error(1)
+}
+
+func (m *MockStudentUsecase) EnrollStudent(ctx context.Context, studentID, courseID string) (*model.Student, error) {
+	args := m.Called(ctx, studentID, courseID)
+	student, _ := args.Get(0).(*model.Student)
+	return student, args.Error(1)
+}
+
+func (m *MockStudentUsecase) UnenrollStudent(ctx context.Context, studentID, courseID string) (*model.Student, error) {
+	args := m.Called(ctx, studentID, courseID)
+	student, _ := args.Get(0).(*model.Student)
+	return student, args.Error(1)
 }
 
 func (m *MockStudentUsecase) UpdateStudent(ctx context.Context, student_id string, student *model.Student) (*model.Student, error) {
@@ -36,34 +63,193 @@ func (m *MockStudentUsecase) DeleteStudent(ctx context.Context, id string) error
 	return args.Error(0)
 }
 
+func (m *MockStudentUsecase) SetRole(ctx context.Context, id string, role string) (*model.Student, error) {
+	args := m.Called(ctx, id, role)
+	student, _ := args.Get(0).(*model.Student)
+	return student, args.Error(1)
+}
+
+func (m *MockStudentUsecase) UnlockAccount(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) ResetPassword(ctx context.Context, resetToken string, newPassword string) error {
+	args := m.Called(ctx, resetToken, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) VerifyEmail(ctx context.Context, verificationToken string) error {
+	args := m.Called(ctx, verificationToken)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) ResendVerification(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) RequestEmailChange(ctx context.Context, id string, newEmail string) error {
+	args := m.Called(ctx, id, newEmail)
+	return args.Error(0)
+}
+
 func (m *MockStudentUsecase) SignIn(ctx context.Context, signInData *model.SignInData) (*model.AuthToken, error) {
-	args := m.Called(signInData)
-	return args.Get(0).(*model.AuthToken), args.Error(1)
+	args := m.Called(ctx, signInData)
+	authToken, _ := args.Get(0).(*model.AuthToken)
+	return authToken, args.Error(1)
 }
 
-func (m *MockStudentUsecase) SaveRefreshToken(userID string, refreshToken string) error {
-	args := m.Called(userID, refreshToken)
+func (m *MockStudentUsecase) CompleteTwoFactorSignIn(ctx context.Context, request *model.TwoFactorSignInRequest) (*model.AuthToken, error) {
+	args := m.Called(ctx, request)
+	authToken, _ := args.Get(0).(*model.AuthToken)
+	return authToken, args.Error(1)
+}
+
+func (m *MockStudentUsecase) BeginChallengeEnrollment(ctx context.Context, challengeToken string) (*model.TwoFactorEnrollment, error) {
+	args := m.Called(ctx, challengeToken)
+	enrollment, _ := args.Get(0).(*model.TwoFactorEnrollment)
+	return enrollment, args.Error(1)
+}
+
+func (m *MockStudentUsecase) BeginTwoFactorEnrollment(ctx context.Context, userID string) (*model.TwoFactorEnrollment, error) {
+	args := m.Called(ctx, userID)
+	enrollment, _ := args.Get(0).(*model.TwoFactorEnrollment)
+	return enrollment, args.Error(1)
+}
+
+func (m *MockStudentUsecase) EnableTwoFactor(ctx context.Context, userID string, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	recoveryCodes, _ := args.Get(0).([]string)
+	return recoveryCodes, args.Error(1)
+}
+
+func (m *MockStudentUsecase) DisableTwoFactor(ctx context.Context, userID string, code string) error {
+	args := m.Called(ctx, userID, code)
 	return args.Error(0)
 }
 
-func (m *MockStudentUsecase) GenerateToken(studentID string) (string, error) {
-	args := m.Called(studentID)
-	return args.String(0), args.Error(1)
+func (m *MockStudentUsecase) RegenerateRecoveryCodes(ctx context.Context, userID string, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	recoveryCodes, _ := args.Get(0).([]string)
+	return recoveryCodes, args.Error(1)
 }
 
-func (m *MockStudentUsecase) ValidateRefreshToken(refreshToken string) (string, error) {
-	args := m.Called(refreshToken)
-	return args.String(0), args.Error(1)
+func (m *MockStudentUsecase) TwoFactorRequiredRoles(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	roles, _ := args.Get(0).([]string)
+	return roles, args.Error(1)
 }
 
-func (m *MockStudentUsecase) DeleteRefreshToken(userID string) error {
-	args := m.Called(userID)
+func (m *MockStudentUsecase) SetTwoFactorRequiredRoles(ctx context.Context, roles []string) ([]string, error) {
+	args := m.Called(ctx, roles)
+	updated, _ := args.Get(0).([]string)
+	return updated, args.Error(1)
+}
+
+func (m *MockStudentUsecase) OIDCLogin(ctx context.Context) (*model.OIDCLogin, error) {
+	args := m.Called(ctx)
+	login, _ := args.Get(0).(*model.OIDCLogin)
+	return login, args.Error(1)
+}
+
+func (m *MockStudentUsecase) OIDCCallback(ctx context.Context, request *model.OIDCCallbackRequest) (*model.AuthToken, error) {
+	args := m.Called(ctx, request)
+	authToken, _ := args.Get(0).(*model.AuthToken)
+	return authToken, args.Error(1)
+}
+
+func (m *MockStudentUsecase) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time, createdBy string) (*model.APIKey, error) {
+	args := m.Called(ctx, name, scopes, expiresAt, createdBy)
+	key, _ := args.Get(0).(*model.APIKey)
+	return key, args.Error(1)
+}
+
+func (m *MockStudentUsecase) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	args := m.Called(ctx)
+	keys, _ := args.Get(0).([]*model.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *MockStudentUsecase) RevokeAPIKey(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockStudentUsecase) AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	args := m.Called(ctx, key)
+	apiKey, _ := args.Get(0).(*model.APIKey)
+	return apiKey, args.Error(1)
+}
+
+func (m *MockStudentUsecase) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*model.TokenIntrospection, error) {
+	args := m.Called(ctx, token, tokenTypeHint)
+	introspection, _ := args.Get(0).(*model.TokenIntrospection)
+	return introspection, args.Error(1)
+}
+
+func (m *MockStudentUsecase) UpdateProfile(ctx context.Context, userID string, update *model.ProfileUpdate) (*model.Student, error) {
+	args := m.Called(ctx, userID, update)
+	student, _ := args.Get(0).(*model.Student)
+	return student, args.Error(1)
+}
+
+func (m *MockStudentUsecase) ChangePassword(ctx context.Context, request *model.PasswordChangeRequest) error {
+	args := m.Called(ctx, request)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) DeleteAccount(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) Refresh(ctx context.Context, refreshToken string) (*model.AuthToken, error) {
+	args := m.Called(ctx, refreshToken)
+	authToken, _ := args.Get(0).(*model.AuthToken)
+	return authToken, args.Error(1)
+}
+
+func (m *MockStudentUsecase) LogoutSession(ctx context.Context, userID, sessionID, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, sessionID, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	args := m.Called(ctx, userID)
+	sessions, _ := args.Get(0).([]*model.Session)
+	return sessions, args.Error(1)
+}
+
+func (m *MockStudentUsecase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) LogoutAll(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockStudentUsecase) GenerateToken(student *model.Student, sessionID string) (string, error) {
+	args := m.Called(student, sessionID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStudentUsecase) ValidateAccessToken(ctx context.Context, accessToken string) (*usecase.AccessClaims, error) {
+	args := m.Called(ctx, accessToken)
+	claims, _ := args.Get(0).(*usecase.AccessClaims)
+	return claims, args.Error(1)
+}
+
 func (m *MockStudentUsecase) GetByEmail(ctx context.Context, email string) (*model.Student, error) {
-	args := m.Called(email)
-	return args.Get(0).(*model.Student), args.Error(0)
+	args := m.Called(ctx, email)
+	return args.Get(0).(*model.Student), args.Error(1)
 }
 
 func (m *MockStudentUsecase) CheckEmailExistence(ctx context.Context, email string) (bool, error) {
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/nurmeden/students-service/internal/oidc"
+)
+
+var (
+	errIdentityProviderUnavailable = apperror.Unavailable("identity_provider_unavailable", "identity provider is unavailable")
+	errSignInRefused               = apperror.Unauthorized("sign_in_refused", "sign-in was refused by the identity provider")
+	errCodeAndStateRequired        = apperror.Validation("invalid_oidc_callback", "code and state are required")
+	errInvalidIDToken              = apperror.Unauthorized("invalid_id_token", "invalid identity token")
+)
+
+// OIDCLogin godoc
+// @Summary Sign in with the university identity provider
+// @Description Redirects the browser to the OpenID Connect provider. The provider sends it back to /auth/oidc/callback, which only accepts it in the browser that started the sign-on.
+// @Tags Authentication
+// @Success 302
+// @Router /auth/oidc/login [get]
+func (h *StudentHandler) OIDCLogin(c *gin.Context) {
+	login, err := h.studentUsecase.OIDCLogin(c.Request.Context())
+	if err != nil {
+		if !errors.Is(err, usecase.ErrOIDCDisabled) {
+			err = errIdentityProviderUnavailable.Wrap(err)
+		}
+		respondError(c, err)
+		return
+	}
+	h.cookies.setOIDCState(c, login.State, login.ExpiresIn)
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+// OIDCCallback godoc
+// @Summary Finish a sign-in with the university identity provider
+// @Description Redeems the authorization code, links or creates the student by verified email and returns the service's tokens, or a two-factor challenge as sign-in does.
+// @Tags Authentication
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} TokenResponse
+// @Router /auth/oidc/callback [get]
+func (h *StudentHandler) OIDCCallback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		h.logger.WithContext(c.Request.Context()).WithField("error", providerErr).Warn("Identity provider refused the sign-in")
+		respondError(c, errSignInRefused)
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		respondError(c, errCodeAndStateRequired)
+		return
+	}
+	if !h.cookies.checkOIDCState(c, state) {
+		respondError(c, usecase.ErrInvalidOIDCState)
+		return
+	}
+
+	// The callback is a browser navigation, which cannot ask for cookies
+	// with a header.
+	c.Set(cookieModeKey, true)
+	authResult, err := h.studentUsecase.OIDCCallback(c.Request.Context(), &model.OIDCCallbackRequest{
+		Code:      code,
+		State:     state,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if errors.Is(err, oidc.ErrInvalidIDToken) {
+		err = errInvalidIDToken.Wrap(err)
+	}
+	h.respondSignIn(c, authResult, err)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStudentHandler_OIDCLogin(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("OIDCLogin", mock.Anything).Return(&model.OIDCLogin{
+		URL:       "https://sso.example.edu/authorize?state=abc",
+		State:     "abc",
+		ExpiresIn: 10 * time.Minute,
+	}, nil)
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/oidc/login", nil)
+
+	h.OIDCLogin(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://sso.example.edu/authorize?state=abc", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, oidcStateCookie, cookies[0].Name)
+		assert.Equal(t, hashOIDCState("abc"), cookies[0].Value)
+		assert.Equal(t, 600, cookies[0].MaxAge)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	}
+}
+
+func TestStudentHandler_OIDCCallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		stateCookie  string
+		result       *model.AuthToken
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Signed in",
+			query:        "?code=c&state=s",
+			stateCookie:  hashOIDCState("s"),
+			result:       &model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"token":"auth_token","refresh_token":"refresh_token"}`,
+		},
+		{
+			name:         "Unverified email",
+			query:        "?code=c&state=s",
+			stateCookie:  hashOIDCState("s"),
+			err:          usecase.ErrOIDCEmailNotVerified,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"the identity provider did not verify the email","instance":"/oidc/callback","code":"oidc_email_not_verified"}`,
+		},
+		{
+			name:         "Replayed state",
+			query:        "?code=c&state=s",
+			stateCookie:  hashOIDCState("s"),
+			err:          usecase.ErrInvalidOIDCState,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid or expired single sign-on state, start again","instance":"/oidc/callback","code":"invalid_oidc_state"}`,
+		},
+		{
+			name:         "Started in another browser",
+			query:        "?code=c&state=s",
+			stateCookie:  hashOIDCState("other"),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid or expired single sign-on state, start again","instance":"/oidc/callback","code":"invalid_oidc_state"}`,
+		},
+		{
+			name:         "Without the state cookie",
+			query:        "?code=c&state=s",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid or expired single sign-on state, start again","instance":"/oidc/callback","code":"invalid_oidc_state"}`,
+		},
+		{
+			name:         "Refused by provider",
+			query:        "?error=access_denied&state=s",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"sign-in was refused by the identity provider","instance":"/oidc/callback","code":"sign_in_refused"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			mockStudentUsecase.On("OIDCCallback", mock.Anything, mock.MatchedBy(func(request *model.OIDCCallbackRequest) bool {
+				return request.Code == "c" && request.State == "s"
+			})).Return(tt.result, tt.err)
+			h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/oidc/callback"+tt.query, nil)
+			if tt.stateCookie != "" {
+				c.Request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.stateCookie})
+			}
+
+			h.OIDCCallback(c)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			if tt.stateCookie != hashOIDCState("s") {
+				mockStudentUsecase.AssertNotCalled(t, "OIDCCallback", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
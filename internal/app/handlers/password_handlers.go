@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+)
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Mails a single-use reset link if the email is registered. The response is the same either way.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body model.ForgotPasswordRequest true "Account email"
+// @Success 202 {string} string "message: If the email is registered, a reset link has been sent"
+// @Router /auth/password/forgot [post]
+func (h *StudentHandler) ForgotPassword(c *gin.Context) {
+	var request model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	if err := h.studentUsecase.ForgotPassword(c.Request.Context(), request.Email); err != nil {
+		respondError(c, fmt.Errorf("start password reset: %w", err))
+		return
+	}
+
+	respondMessage(c, http.StatusAccepted, "reset_link_sent")
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Sets a new password using a reset token and logs the student out of every session
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body model.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {string} string "message: Password has been reset"
+// @Router /auth/password/reset [post]
+func (h *StudentHandler) ResetPassword(c *gin.Context) {
+	var request model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	err := h.studentUsecase.ResetPassword(c.Request.Context(), request.Token, request.Password)
+	if err != nil {
+		respondError(c, fmt.Errorf("reset password: %w", err))
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "password_reset")
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+)
+
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request, put into the gin
+// context by AuthMiddleware. API keys have no user, no roles and no
+// session; their scopes are the permissions.
+type Principal struct {
+	AuthMethod    string
+	UserID        string
+	APIKeyID      string
+	Roles         []string
+	Permissions   []string
+	Tenant        string
+	SessionID     string
+	TokenID       string
+	ExpiresAt     time.Time
+	EmailVerified bool
+}
+
+func (p *Principal) IsAPIKey() bool {
+	return p.AuthMethod == AuthMethodAPIKey
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return hasValue(p.Roles, role)
+}
+
+func (p *Principal) HasPermission(permission string) bool {
+	return hasValue(p.Permissions, permission)
+}
+
+// APIKeyPrincipal is the caller authenticated by an API key.
+func APIKeyPrincipal(key *model.APIKey) *Principal {
+	return &Principal{
+		AuthMethod:    AuthMethodAPIKey,
+		APIKeyID:      key.ID.Hex(),
+		Roles:         []string{},
+		Permissions:   key.Scopes,
+		EmailVerified: true,
+	}
+}
+
+// TokenPrincipal is the caller authenticated by a valid access token.
+// Tokens without roles predate them and belong to students.
+func TokenPrincipal(claims *usecase.AccessClaims) *Principal {
+	roles := claims.Roles
+	if len(roles) == 0 {
+		roles = []string{model.RoleStudent}
+	}
+	return &Principal{
+		AuthMethod:    AuthMethodJWT,
+		UserID:        claims.Subject,
+		Roles:         roles,
+		Permissions:   PermissionsFor(roles),
+		Tenant:        claims.Tenant,
+		SessionID:     claims.SessionID,
+		TokenID:       claims.ID,
+		ExpiresAt:     claims.ExpiresAtTime(),
+		EmailVerified: claims.EmailVerified,
+	}
+}
+
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+}
+
+// CurrentPrincipal returns the caller of the request. Outside of routes
+// behind AuthMiddleware it is the zero Principal, which holds no roles or
+// permissions.
+func CurrentPrincipal(c *gin.Context) *Principal {
+	if principal, ok := c.Value(principalKey).(*Principal); ok {
+		return principal
+	}
+	return &Principal{}
+}
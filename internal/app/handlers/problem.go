@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/i18n"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Errors of the handler layer itself.
+var (
+	errInvalidRequest   = apperror.Validation("invalid_request", "invalid request body")
+	errMalformedAuth    = apperror.Unauthorized("malformed_authorization", "malformed Authorization header")
+	errMissingAuth      = apperror.Unauthorized("missing_authorization", "authorization header is missing")
+	errCSRF             = apperror.Forbidden("csrf_failed", "invalid CSRF token")
+	errForbidden        = apperror.Forbidden("forbidden", "forbidden")
+	errEmailNotVerified = apperror.Forbidden("email_not_verified", "email address is not verified")
+)
+
+// Problem is an RFC 7807 problem details object. Code is the stable error
+// code of the domain error and RequestID the ID of the failed request.
+// Detail and the reasons of InvalidParams are in the language of the
+// request.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	RequestID     string         `json:"request_id,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// InvalidParam is a request field that failed validation.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func init() {
+	// Name invalid params after their JSON fields, as clients send them.
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// StatusOf maps the kind of a domain error to its HTTP status.
+func StatusOf(err *apperror.Error) int {
+	switch err.Kind {
+	case apperror.ErrValidation:
+		return http.StatusBadRequest
+	case apperror.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case apperror.ErrForbidden:
+		return http.StatusForbidden
+	case apperror.ErrNotFound:
+		return http.StatusNotFound
+	case apperror.ErrConflict:
+		return http.StatusConflict
+	case apperror.ErrUnprocessable:
+		return http.StatusUnprocessableEntity
+	case apperror.ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	case apperror.ErrUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError aborts the request with the problem for err and records err
+// on the context, where ErrorHandler logs it. Errors that are no domain
+// error answer 500 without their text.
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	writeProblem(c, err)
+}
+
+// invalidRequest is the error of a request body or query that failed to
+// bind.
+func invalidRequest(err error) error {
+	return errInvalidRequest.Wrap(err)
+}
+
+func writeProblem(c *gin.Context, err error) {
+	appErr := apperror.From(err)
+	status := StatusOf(appErr)
+	if retryAfter, ok := retryAfterOf(err); ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	lang := languageOf(c)
+	detail, ok := i18n.Message(lang, appErr.Code, appErr.Args...)
+	if !ok {
+		detail = appErr.Message
+	}
+	problem := Problem{
+		Type:          "about:blank",
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		Instance:      c.Request.URL.Path,
+		Code:          appErr.Code,
+		RequestID:     RequestIDOf(c),
+		InvalidParams: invalidParams(lang, err),
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, problem)
+}
+
+// invalidParams lists the fields of a request that failed validation.
+func invalidParams(lang string, err error) []InvalidParam {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+	params := make([]InvalidParam, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		// The catalog has the tags of the model; the params of those are
+		// their only verb.
+		var args []interface{}
+		if fieldErr.Param() != "" {
+			args = append(args, fieldErr.Param())
+		}
+		reason, ok := i18n.Message(lang, "validation_"+fieldErr.Tag(), args...)
+		if !ok {
+			reason = i18n.Text(lang, "validation_invalid")
+		}
+		params = append(params, InvalidParam{Name: fieldErr.Field(), Reason: reason})
+	}
+	return params
+}
+
+func retryAfterOf(err error) (time.Duration, bool) {
+	var lockoutErr *usecase.LockoutError
+	if errors.As(err, &lockoutErr) {
+		return lockoutErr.RetryAfter, true
+	}
+	var rateLimitErr *usecase.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// ErrorHandler answers the errors handlers record with c.Error, and panics,
+// with problem details unless a response was already written, and logs the
+// server errors among them with the request ID.
+func ErrorHandler(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err := fmt.Errorf("panic: %v", recovered)
+				logError(logger, c, err)
+				if !c.Writer.Written() {
+					writeProblem(c, err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		if len(c.Errors) == 0 {
+			return
+		}
+		err := c.Errors.Last().Err
+		if !c.Writer.Written() {
+			writeProblem(c, err)
+		}
+		logError(logger, c, err)
+	}
+}
+
+func logError(logger *logrus.Logger, c *gin.Context, err error) {
+	appErr := apperror.From(err)
+	entry := logger.WithError(err).WithFields(logrus.Fields{
+		"requestID": RequestIDOf(c),
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.Path,
+		"code":      appErr.Code,
+	})
+	// Requests the client gave up on are no failures of the server.
+	if StatusOf(appErr) >= http.StatusInternalServerError && !errors.Is(err, context.Canceled) {
+		entry.Error("Request failed")
+		return
+	}
+	entry.Debug("Request failed")
+}
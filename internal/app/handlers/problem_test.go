@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/nurmeden/students-service/internal/requestid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newErrorTestRouter(handler gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(RequestID(), ErrorHandler(logrus.New()))
+	router.GET("/test", handler)
+	return router
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		handler            gin.HandlerFunc
+		expectedCode       int
+		expectedBody       string
+		expectedRetryAfter string
+	}{
+		{
+			name:         "Domain error recorded by the handler",
+			handler:      func(c *gin.Context) { _ = c.Error(usecase.ErrStudentNotFound) },
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"student not found","instance":"/test","code":"student_not_found","request_id":"req-1"}`,
+		},
+		{
+			name:         "Other errors hide their text",
+			handler:      func(c *gin.Context) { respondError(c, errors.New("connection refused by mongo:27017")) },
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","instance":"/test","code":"internal_error","request_id":"req-1"}`,
+		},
+		{
+			name:         "Deadline exceeded",
+			handler:      func(c *gin.Context) { respondError(c, fmt.Errorf("get student: %w", context.DeadlineExceeded)) },
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"the request took too long","instance":"/test","code":"deadline_exceeded","request_id":"req-1"}`,
+		},
+		{
+			name:         "Panic",
+			handler:      func(c *gin.Context) { panic("boom") },
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","instance":"/test","code":"internal_error","request_id":"req-1"}`,
+		},
+		{
+			name:               "Lockout",
+			handler:            func(c *gin.Context) { respondError(c, &usecase.LockoutError{RetryAfter: 90 * time.Second}) },
+			expectedCode:       http.StatusTooManyRequests,
+			expectedBody:       `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"too many failed sign-in attempts, try again later","instance":"/test","code":"account_locked","request_id":"req-1"}`,
+			expectedRetryAfter: "90",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+
+			newErrorTestRouter(tt.handler).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, tt.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		kept      bool
+	}{
+		{name: "Propagated", requestID: "3f2a-42", kept: true},
+		{name: "Missing", requestID: ""},
+		{name: "Not printable", requestID: "bad id\x01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen, inContext string
+			router := newErrorTestRouter(func(c *gin.Context) {
+				seen = RequestIDOf(c)
+				inContext = requestid.FromContext(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(RequestIDHeader, tt.requestID)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+			assert.Equal(t, seen, inContext)
+			if tt.kept {
+				assert.Equal(t, tt.requestID, seen)
+			} else {
+				assert.Len(t, seen, 32)
+			}
+		})
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	t.Run("Cancels the request context", func(t *testing.T) {
+		router := gin.New()
+		router.Use(RequestID(), ErrorHandler(logrus.New()))
+		router.GET("/test", Deadline(10*time.Millisecond), func(c *gin.Context) {
+			<-c.Request.Context().Done()
+			respondError(c, c.Request.Context().Err())
+		})
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"deadline_exceeded"`)
+	})
+
+	t.Run("Zero sets no deadline", func(t *testing.T) {
+		var hasDeadline bool
+		router := gin.New()
+		router.GET("/test", Deadline(0), func(c *gin.Context) {
+			_, hasDeadline = c.Request.Context().Deadline()
+			c.Status(http.StatusNoContent)
+		})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+		assert.False(t, hasDeadline)
+	})
+}
+
+func TestStudentHandler_GetStudentByIDNotFound(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("GetStudentByID", mock.Anything, "missing").Return(nil, usecase.ErrStudentNotFound)
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/students/missing", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+
+	h.GetStudentByID(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"student not found","instance":"/students/missing","code":"student_not_found"}`, w.Body.String())
+	mockStudentUsecase.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/nurmeden/students-service/internal/ratelimit"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimiter limits the requests of route groups by the rules of
+// config.RateLimitConfig.
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	cfg     config.RateLimitConfig
+	logger  *logrus.Logger
+}
+
+func NewRateLimiter(limiter ratelimit.Limiter, cfg config.RateLimitConfig, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		limiter: limiter,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Limit counts the requests of group and refuses those over its limit with
+// 429 and Retry-After. Every counted response carries the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers of the
+// IETF draft. Groups counted by caller must be mounted after
+// AuthMiddleware. Requests pass if the limiter fails.
+func (l *RateLimiter) Limit(group string) gin.HandlerFunc {
+	rule, ok := l.cfg.Groups[group]
+	if !l.cfg.Enabled || !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		principal := CurrentPrincipal(c)
+		limit := ratelimit.Limit{Requests: requestsFor(rule, principal), Window: rule.Window}
+		key := rateLimitKey(c, principal, rule.By)
+		result, err := l.limiter.Allow(c.Request.Context(), group+":"+key, limit)
+		if err != nil {
+			l.logger.WithError(err).WithField("requestID", RequestIDOf(c)).Error("Failed to check rate limit")
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, seconds(limit.Window)))
+		if !result.Allowed {
+			l.logger.WithFields(logrus.Fields{
+				"requestID": RequestIDOf(c),
+				"group":     group,
+				"key":       key,
+				"path":      c.Request.URL.Path,
+			}).Warn("Rate limit exceeded")
+			respondError(c, &usecase.RateLimitError{RetryAfter: result.RetryAfter})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestsFor is the limit of the caller: the highest override of their
+// roles, or the default.
+func requestsFor(rule config.RateLimitRule, principal *Principal) int {
+	requests, overridden := rule.Requests, false
+	for _, role := range principal.Roles {
+		if roleRequests, ok := rule.Roles[role]; ok && (!overridden || roleRequests > requests) {
+			requests, overridden = roleRequests, true
+		}
+	}
+	return requests
+}
+
+func rateLimitKey(c *gin.Context, principal *Principal, by string) string {
+	if by != config.RateLimitByIP {
+		switch {
+		case principal.IsAPIKey():
+			return "api_key:" + principal.APIKeyID
+		case principal.UserID != "":
+			return "user:" + principal.UserID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
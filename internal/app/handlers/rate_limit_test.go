@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/ratelimit"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitRouter(limiter ratelimit.Limiter, group string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	rateLimiter := NewRateLimiter(limiter, config.RateLimitConfig{
+		Enabled: true,
+		Groups: map[string]config.RateLimitRule{
+			"auth": {Requests: 2, Window: time.Minute, By: config.RateLimitByIP},
+			"api":  {Requests: 2, Window: time.Minute, Roles: map[string]int{model.RoleAdmin: 4, model.RoleInstructor: 3}},
+		},
+	}, logrus.New())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			SetPrincipal(c, &Principal{AuthMethod: AuthMethodJWT, UserID: userID, Roles: c.Request.Header.Values("X-Test-Role")})
+		}
+	})
+	router.GET("/test", rateLimiter.Limit(group), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func get(router *gin.Engine, ip, userID string, roles ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = ip + ":1234"
+	if userID != "" {
+		req.Header.Set("X-Test-User", userID)
+	}
+	for _, role := range roles {
+		req.Header.Add("X-Test-Role", role)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	t.Run("Headers and refusal", func(t *testing.T) {
+		router := newRateLimitRouter(ratelimit.NewLocal(), "auth")
+
+		w := get(router, "10.0.0.1", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+		reset, err := strconv.Atoi(w.Header().Get("RateLimit-Reset"))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, reset, 60)
+
+		get(router, "10.0.0.1", "")
+		w = get(router, "10.0.0.1", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+
+		assert.Equal(t, http.StatusNoContent, get(router, "10.0.0.2", "").Code, "IPs are counted apart")
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "10.0.0.1", "s1").Code, "IP groups ignore the user")
+	})
+
+	t.Run("Per caller with role overrides", func(t *testing.T) {
+		router := newRateLimitRouter(ratelimit.NewLocal(), "api")
+
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusNoContent, get(router, "10.0.0.1", "s1").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "10.0.0.1", "s1").Code)
+		assert.Equal(t, http.StatusNoContent, get(router, "10.0.0.1", "s2").Code, "users behind one IP are counted apart")
+
+		w := get(router, "10.0.0.1", "a1", model.RoleInstructor, model.RoleAdmin)
+		assert.Equal(t, "4", w.Header().Get("RateLimit-Limit"), "the highest role override applies")
+	})
+
+	t.Run("Unconfigured group", func(t *testing.T) {
+		router := newRateLimitRouter(ratelimit.NewLocal(), "graphql")
+
+		w := get(router, "10.0.0.1", "")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Limiter failure lets requests pass", func(t *testing.T) {
+		router := newRateLimitRouter(failingLimiter{}, "auth")
+
+		assert.Equal(t, http.StatusNoContent, get(router, "10.0.0.1", "").Code)
+	})
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
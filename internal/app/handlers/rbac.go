@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	PermStudentsRead   = "students:read"
+	PermStudentsWrite  = "students:write"
+	PermStudentsDelete = "students:delete"
+	PermRostersRead    = "rosters:read"
+	PermRolesManage    = "roles:manage"
+	PermAccountsUnlock = "accounts:unlock"
+	PermSecurityManage = "security:manage"
+	// PermTokensIntrospect is an API key scope only; no role holds it.
+	PermTokensIntrospect = "tokens:introspect"
+)
+
+// rolePermissions is the permission matrix. Access to a student's own record
+// is granted by the self rules of the routes, not by a permission, so a
+// plain student holds none.
+var rolePermissions = map[string][]string{
+	model.RoleAdmin: {
+		PermStudentsRead,
+		PermStudentsWrite,
+		PermStudentsDelete,
+		PermRostersRead,
+		PermRolesManage,
+		PermAccountsUnlock,
+		PermSecurityManage,
+	},
+	model.RoleInstructor: {},
+	model.RoleStudent:    {},
+}
+
+// apiKeyScopes are the permissions an API key may be granted. Managing
+// accounts stays with signed-in admins.
+var apiKeyScopes = []string{
+	PermStudentsRead,
+	PermStudentsWrite,
+	PermRostersRead,
+	PermTokensIntrospect,
+}
+
+func IsAPIKeyScope(scope string) bool {
+	return hasValue(apiKeyScopes, scope)
+}
+
+// PermissionsFor returns the union of the permissions of the roles.
+func PermissionsFor(roles []string) []string {
+	seen := make(map[string]struct{})
+	permissions := []string{}
+	for _, role := range roles {
+		for _, permission := range rolePermissions[role] {
+			if _, ok := seen[permission]; ok {
+				continue
+			}
+			seen[permission] = struct{}{}
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+// Authorizer holds the route-level authorization middlewares. They must run
+// after AuthMiddleware, which puts the roles and permissions of the caller
+// into the gin context.
+type Authorizer struct {
+	studentUsecase usecase.StudentUsecase
+	logger         *logrus.Logger
+}
+
+func NewAuthorizer(studentUsecase usecase.StudentUsecase, logger *logrus.Logger) *Authorizer {
+	return &Authorizer{
+		studentUsecase: studentUsecase,
+		logger:         logger,
+	}
+}
+
+func (a *Authorizer) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, role := range roles {
+			if CurrentPrincipal(c).HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+		a.deny(c, "missing role")
+	}
+}
+
+func (a *Authorizer) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CurrentPrincipal(c).HasPermission(permission) {
+			a.deny(c, "missing permission "+permission)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrPermission lets students act on their own record, named by
+// the path parameter, and everyone else only with the permission.
+func (a *Authorizer) RequireSelfOrPermission(param, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := CurrentPrincipal(c)
+		if c.Param(param) == principal.UserID || principal.HasPermission(permission) {
+			c.Next()
+			return
+		}
+		a.deny(c, "not the owner and missing permission "+permission)
+	}
+}
+
+// RequireRosterAccess allows reading the roster of the course named by the
+// path parameter to holders of rosters:read and to instructors teaching it.
+func (a *Authorizer) RequireRosterAccess(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, err := CanReadRoster(c.Request.Context(), a.studentUsecase, CurrentPrincipal(c), c.Param(param))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if !allowed {
+			a.deny(c, "not an instructor of the course and missing permission "+PermRostersRead)
+			return
+		}
+		c.Next()
+	}
+}
+
+// CanReadRoster tells whether the principal holds rosters:read or is an
+// instructor teaching the course.
+func CanReadRoster(ctx context.Context, studentUsecase usecase.StudentUsecase, principal *Principal, courseID string) (bool, error) {
+	if principal.HasPermission(PermRostersRead) {
+		return true, nil
+	}
+	if !principal.HasRole(model.RoleInstructor) {
+		return false, nil
+	}
+	instructor, err := studentUsecase.GetStudentByID(ctx, principal.UserID)
+	if errors.Is(err, usecase.ErrStudentNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("load instructor: %w", err)
+	}
+	return hasValue(instructor.Courses, courseID), nil
+}
+
+// RequireUser refuses API keys on routes that act on the signed-in
+// student's own account, such as logout.
+func (a *Authorizer) RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentPrincipal(c).IsAPIKey() {
+			a.deny(c, "route requires a signed-in user")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAPIKey restricts service-to-service routes to API keys.
+func (a *Authorizer) RequireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CurrentPrincipal(c).IsAPIKey() {
+			a.deny(c, "route requires an API key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail restricts accounts whose email is not verified yet to
+// the routes without this middleware.
+func (a *Authorizer) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CurrentPrincipal(c).EmailVerified {
+			a.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"userID": CurrentPrincipal(c).UserID,
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+			}).Info("Request from unverified account refused")
+			respondError(c, errEmailNotVerified)
+			return
+		}
+		c.Next()
+	}
+}
+
+func (a *Authorizer) deny(c *gin.Context, reason string) {
+	principal := CurrentPrincipal(c)
+	a.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID":   principal.UserID,
+		"apiKeyID": principal.APIKeyID,
+		"roles":    principal.Roles,
+		"method":   c.Request.Method,
+		"path":     c.Request.URL.Path,
+		"ip":       c.ClientIP(),
+		"reason":   reason,
+	}).Warn("Unauthorized access attempt")
+	respondError(c, errForbidden)
+}
+
+func hasValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
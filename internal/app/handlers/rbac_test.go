@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRBACRouter(authorizer *Authorizer, userID string, roles []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{AuthMethod: AuthMethodJWT, UserID: userID, Roles: roles, Permissions: PermissionsFor(roles)})
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.PUT("/students/:id", authorizer.RequireSelfOrPermission("id", PermStudentsWrite), ok)
+	router.GET("/courses/:id/students", authorizer.RequireRosterAccess("id"), ok)
+	router.PUT("/admin/students/:id/role", authorizer.RequireRole(model.RoleAdmin), authorizer.RequirePermission(PermRolesManage), ok)
+	return router
+}
+
+func TestAuthorizer(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("GetStudentByID", mock.Anything, "instructor-1").Return(&model.Student{Courses: []string{"math"}, Role: model.RoleInstructor}, nil)
+	authorizer := NewAuthorizer(mockStudentUsecase, logrus.New())
+
+	tests := []struct {
+		name         string
+		userID       string
+		roles        []string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{name: "student updates own record", userID: "student-1", roles: []string{model.RoleStudent}, method: http.MethodPut, path: "/students/student-1", expectedCode: http.StatusOK},
+		{name: "student updates another record", userID: "student-1", roles: []string{model.RoleStudent}, method: http.MethodPut, path: "/students/student-2", expectedCode: http.StatusForbidden},
+		{name: "admin updates any record", userID: "admin-1", roles: []string{model.RoleAdmin}, method: http.MethodPut, path: "/students/student-2", expectedCode: http.StatusOK},
+		{name: "instructor reads own course roster", userID: "instructor-1", roles: []string{model.RoleInstructor}, method: http.MethodGet, path: "/courses/math/students", expectedCode: http.StatusOK},
+		{name: "instructor reads foreign course roster", userID: "instructor-1", roles: []string{model.RoleInstructor}, method: http.MethodGet, path: "/courses/history/students", expectedCode: http.StatusForbidden},
+		{name: "student reads roster", userID: "student-1", roles: []string{model.RoleStudent}, method: http.MethodGet, path: "/courses/math/students", expectedCode: http.StatusForbidden},
+		{name: "instructor changes roles", userID: "instructor-1", roles: []string{model.RoleInstructor}, method: http.MethodPut, path: "/admin/students/student-1/role", expectedCode: http.StatusForbidden},
+		{name: "admin changes roles", userID: "admin-1", roles: []string{model.RoleAdmin}, method: http.MethodPut, path: "/admin/students/student-1/role", expectedCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRBACRouter(authorizer, tt.userID, tt.roles)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
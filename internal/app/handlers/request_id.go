@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/requestid"
+)
+
+const (
+	RequestIDHeader = requestid.Header
+
+	requestIDKey = "requestID"
+
+	// maxRequestIDLength bounds request IDs taken from callers, which end up
+	// in logs and responses.
+	maxRequestIDLength = 128
+)
+
+// RequestID takes the request ID from the X-Request-ID header, or makes one
+// up if it is missing or unusable, and echoes it in the response. The ID is
+// also put into the request context for the log lines and outbound calls of
+// the usecases.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := EnsureRequestID(c.GetHeader(RequestIDHeader))
+		c.Set(requestIDKey, requestID)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// Deadline cancels the request context after timeout, so the queries of a
+// request stop once its answer would be late. Zero sets no deadline; the
+// context still ends when the client goes away.
+func Deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// AccessLog logs every request like the default logger of gin, followed by
+// its request ID.
+func AccessLog() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		requestID, _ := param.Keys[requestIDKey].(string)
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			param.Path,
+			requestID,
+			param.ErrorMessage,
+		)
+	})
+}
+
+// EnsureRequestID returns the request ID a caller sent, or a new one if it
+// is missing or unusable.
+func EnsureRequestID(requestID string) string {
+	if !validRequestID(requestID) {
+		return newRequestID()
+	}
+	return requestID
+}
+
+// RequestIDOf returns the ID RequestID gave the request, or "" outside of
+// it.
+func RequestIDOf(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// validRequestID accepts printable ASCII without spaces, so caller IDs cannot
+// break log lines or headers.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
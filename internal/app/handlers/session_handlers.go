@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions godoc
+// @Summary List my sessions
+// @Description Lists the signed-in sessions of the authenticated student with the user agent and IP of each sign-in, newest first. The session of this request is marked current.
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Session
+// @Router /me/sessions [get]
+func (h *StudentHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.studentUsecase.ListSessions(c.Request.Context(), CurrentPrincipal(c).UserID)
+	if err != nil {
+		respondError(c, fmt.Errorf("list sessions: %w", err))
+		return
+	}
+
+	current := CurrentPrincipal(c).SessionID
+	for _, session := range sessions {
+		session.Current = session.ID == current
+	}
+	respondList(c, sessions, len(sessions))
+}
+
+// RevokeSession godoc
+// @Summary Revoke one of my sessions
+// @Description Signs the session out: its refresh token stops working and its access tokens are rejected.
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {string} string "message: Session revoked"
+// @Router /me/sessions/{id} [delete]
+func (h *StudentHandler) RevokeSession(c *gin.Context) {
+	if err := h.studentUsecase.RevokeSession(c.Request.Context(), CurrentPrincipal(c).UserID, c.Param("id")); err != nil {
+		respondError(c, fmt.Errorf("revoke session: %w", err))
+		return
+	}
+	respondMessage(c, http.StatusOK, "session_revoked")
+}
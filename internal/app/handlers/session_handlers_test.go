@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStudentHandler_ListSessionsMarksCurrent(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("ListSessions", mock.Anything, "user-1").Return([]*model.Session{{ID: "a"}, {ID: "b"}}, nil)
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/me/sessions", nil)
+	SetPrincipal(c, &Principal{AuthMethod: AuthMethodJWT, UserID: "user-1", SessionID: "b"})
+
+	h.ListSessions(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[
+		{"id":"a","userAgent":"","ip":"","createdAt":"0001-01-01T00:00:00Z","lastRefreshAt":"0001-01-01T00:00:00Z","current":false},
+		{"id":"b","userAgent":"","ip":"","createdAt":"0001-01-01T00:00:00Z","lastRefreshAt":"0001-01-01T00:00:00Z","current":true}
+	]}`, w.Body.String())
+}
+
+func TestStudentHandler_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Revoked",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"message":"Session revoked"}`,
+		},
+		{
+			name:         "Not the caller's session",
+			err:          usecase.ErrSessionNotFound,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"session not found","instance":"/me/sessions/s1","code":"session_not_found"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			mockStudentUsecase.On("RevokeSession", mock.Anything, "user-1", "s1").Return(tt.err)
+			h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("DELETE", "/me/sessions/s1", nil)
+			c.Params = gin.Params{{Key: "id", Value: "s1"}}
+			SetPrincipal(c, &Principal{AuthMethod: AuthMethodJWT, UserID: "user-1"})
+
+			h.RevokeSession(c)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockStudentUsecase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+)
+
+// CompleteTwoFactorSignIn godoc
+// @Summary Complete a two-factor sign-in
+// @Description Redeems the challenge_token from sign-in with a TOTP code or a recovery code. If the challenge required enrollment, the code confirms the new secret and recovery_codes are returned once.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorSignInRequest true "Challenge and code"
+// @Success 200 {object} TokenResponse
+// @Router /auth/sign-in/2fa [post]
+func (h *StudentHandler) CompleteTwoFactorSignIn(c *gin.Context) {
+	var request model.TwoFactorSignInRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	request.ClientIP = c.ClientIP()
+	request.UserAgent = c.Request.UserAgent()
+	authResult, err := h.studentUsecase.CompleteTwoFactorSignIn(c.Request.Context(), &request)
+	if err != nil {
+		respondError(c, fmt.Errorf("complete two-factor sign-in: %w", err))
+		return
+	}
+
+	var extra gin.H
+	if len(authResult.RecoveryCodes) > 0 {
+		extra = gin.H{"recovery_codes": authResult.RecoveryCodes}
+	}
+	h.respondTokens(c, authResult, extra)
+}
+
+// BeginChallengeEnrollment godoc
+// @Summary Enroll in two-factor authentication during sign-in
+// @Description For accounts whose role requires two-factor authentication but that have not enrolled yet. Returns the secret and otpauth URI for the authenticator app.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorChallengeRequest true "Challenge"
+// @Success 200 {object} model.TwoFactorEnrollment
+// @Router /auth/sign-in/2fa/enroll [post]
+func (h *StudentHandler) BeginChallengeEnrollment(c *gin.Context) {
+	var request model.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	enrollment, err := h.studentUsecase.BeginChallengeEnrollment(c.Request.Context(), request.ChallengeToken)
+	if err != nil {
+		respondError(c, fmt.Errorf("start two-factor enrollment: %w", err))
+		return
+	}
+	respondJSON(c, http.StatusOK, enrollment)
+}
+
+// BeginTwoFactorEnrollment godoc
+// @Summary Start two-factor enrollment
+// @Description Generates a TOTP secret for the caller. It takes effect once confirmed at /auth/2fa/enable.
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.TwoFactorEnrollment
+// @Router /auth/2fa/enroll [post]
+func (h *StudentHandler) BeginTwoFactorEnrollment(c *gin.Context) {
+	enrollment, err := h.studentUsecase.BeginTwoFactorEnrollment(c.Request.Context(), CurrentPrincipal(c).UserID)
+	if err != nil {
+		respondError(c, fmt.Errorf("start two-factor enrollment: %w", err))
+		return
+	}
+	respondJSON(c, http.StatusOK, enrollment)
+}
+
+// EnableTwoFactor godoc
+// @Summary Confirm two-factor enrollment
+// @Description Checks a code from the authenticator app against the pending secret and enables two-factor authentication. The recovery codes are only shown here.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {string} string "recovery_codes"
+// @Router /auth/2fa/enable [post]
+func (h *StudentHandler) EnableTwoFactor(c *gin.Context) {
+	var request model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	recoveryCodes, err := h.studentUsecase.EnableTwoFactor(c.Request.Context(), CurrentPrincipal(c).UserID, request.Code)
+	if err != nil {
+		respondError(c, fmt.Errorf("enable two-factor authentication: %w", err))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Requires a current TOTP or recovery code. Not allowed when the caller's role requires two-factor authentication.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {string} string "message: Two-factor authentication disabled"
+// @Router /auth/2fa/disable [post]
+func (h *StudentHandler) DisableTwoFactor(c *gin.Context) {
+	var request model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	if err := h.studentUsecase.DisableTwoFactor(c.Request.Context(), CurrentPrincipal(c).UserID, request.Code); err != nil {
+		respondError(c, fmt.Errorf("disable two-factor authentication: %w", err))
+		return
+	}
+	respondMessage(c, http.StatusOK, "two_factor_disabled")
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace the recovery codes
+// @Description Requires a current TOTP or recovery code. All previous recovery codes stop working.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {string} string "recovery_codes"
+// @Router /auth/2fa/recovery-codes [post]
+func (h *StudentHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var request model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	recoveryCodes, err := h.studentUsecase.RegenerateRecoveryCodes(c.Request.Context(), CurrentPrincipal(c).UserID, request.Code)
+	if err != nil {
+		respondError(c, fmt.Errorf("replace recovery codes: %w", err))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// GetTwoFactorRoles godoc
+// @Summary List the roles that require two-factor authentication
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.TwoFactorRolesRequest
+// @Router /admin/two-factor/roles [get]
+func (h *StudentHandler) GetTwoFactorRoles(c *gin.Context) {
+	roles, err := h.studentUsecase.TwoFactorRequiredRoles(c.Request.Context())
+	if err != nil {
+		respondError(c, fmt.Errorf("get two-factor roles: %w", err))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"roles": roles})
+}
+
+// SetTwoFactorRoles godoc
+// @Summary Require two-factor authentication for roles
+// @Description Admin only. Students of these roles who have not enrolled are asked to enroll at their next sign-in.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.TwoFactorRolesRequest true "Roles"
+// @Success 200 {object} model.TwoFactorRolesRequest
+// @Router /admin/two-factor/roles [put]
+func (h *StudentHandler) SetTwoFactorRoles(c *gin.Context) {
+	var request model.TwoFactorRolesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	roles, err := h.studentUsecase.SetTwoFactorRequiredRoles(c.Request.Context(), request.Roles)
+	if err != nil {
+		respondError(c, fmt.Errorf("set two-factor roles: %w", err))
+		return
+	}
+
+	h.logger.WithContext(c.Request.Context()).WithFields(logrus.Fields{"adminID": CurrentPrincipal(c).UserID, "roles": roles}).Info("Two-factor roles changed")
+	respondJSON(c, http.StatusOK, gin.H{"roles": roles})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStudentHandler_SignInTwoFactorChallenge(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	expiresAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockStudentUsecase.On("SignIn", mock.Anything, mock.Anything).Return(nil, &usecase.TwoFactorRequiredError{
+		ChallengeToken:     "challenge",
+		EnrollmentRequired: true,
+		ExpiresAt:          expiresAt,
+	})
+	h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/sign-in", strings.NewReader(`{"email":"admin@test.com","password":"password"}`))
+
+	h.SignIn(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"two_factor_required":true,"enrollment_required":true,"challenge_token":"challenge","expires_at":"2023-05-01T12:00:00Z"}`, w.Body.String())
+	mockStudentUsecase.AssertExpectations(t)
+}
+
+func TestStudentHandler_CompleteTwoFactorSignIn(t *testing.T) {
+	tests := []struct {
+		name         string
+		result       *model.AuthToken
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Valid code",
+			result:       &model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"token":"auth_token","refresh_token":"refresh_token"}`,
+		},
+		{
+			name:         "Enrollment completed",
+			result:       &model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token", RecoveryCodes: []string{"abcde-fghij"}},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"token":"auth_token","refresh_token":"refresh_token","recovery_codes":["abcde-fghij"]}`,
+		},
+		{
+			name:         "Wrong code",
+			err:          usecase.ErrInvalidTwoFactorCode,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid two-factor code","instance":"/sign-in/2fa","code":"invalid_two_factor_code"}`,
+		},
+		{
+			name:         "Expired challenge",
+			err:          usecase.ErrInvalidChallenge,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid or expired two-factor challenge","instance":"/sign-in/2fa","code":"invalid_challenge"}`,
+		},
+		{
+			name:         "Locked out",
+			err:          &usecase.LockoutError{RetryAfter: time.Minute},
+			expectedCode: http.StatusTooManyRequests,
+			expectedBody: `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"too many failed sign-in attempts, try again later","instance":"/sign-in/2fa","code":"account_locked"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			mockStudentUsecase.On("CompleteTwoFactorSignIn", mock.Anything, mock.MatchedBy(func(request *model.TwoFactorSignInRequest) bool {
+				return request.ChallengeToken == "challenge" && request.Code == "123456"
+			})).Return(tt.result, tt.err)
+			h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/sign-in/2fa", strings.NewReader(`{"challenge_token":"challenge","code":"123456"}`))
+
+			h.CompleteTwoFactorSignIn(c)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockStudentUsecase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/model"
+)
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Redeems the token from a verification email. Completes sign-up or a pending email change.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body model.VerifyEmailRequest true "Verification token"
+// @Success 200 {string} string "message: Email verified"
+// @Router /auth/verify-email [post]
+func (h *StudentHandler) VerifyEmail(c *gin.Context) {
+	var request model.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	err := h.studentUsecase.VerifyEmail(c.Request.Context(), request.Token)
+	if err != nil {
+		respondError(c, fmt.Errorf("verify email: %w", err))
+		return
+	}
+	respondMessage(c, http.StatusOK, "email_verified")
+}
+
+// ResendVerification godoc
+// @Summary Resend the verification email
+// @Description Mails a new verification link for the unverified or pending email of the caller. Rate limited.
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {string} string "message: Verification email sent"
+// @Router /auth/verify-email/resend [post]
+func (h *StudentHandler) ResendVerification(c *gin.Context) {
+	err := h.studentUsecase.ResendVerification(c.Request.Context(), CurrentPrincipal(c).UserID)
+	if err != nil {
+		respondError(c, fmt.Errorf("resend verification email: %w", err))
+		return
+	}
+	respondMessage(c, http.StatusAccepted, "verification_sent")
+}
+
+// RequestEmailChange godoc
+// @Summary Change the email address
+// @Description Mails a verification link to the new address. The stored email changes only after it is verified.
+// @Tags students
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Student ID"
+// @Param request body model.EmailChangeRequest true "New email"
+// @Success 202 {string} string "message: Verification email sent to the new address"
+// @Router /students/{id}/email [post]
+func (h *StudentHandler) RequestEmailChange(c *gin.Context) {
+	var request model.EmailChangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, invalidRequest(err))
+		return
+	}
+
+	err := h.studentUsecase.RequestEmailChange(c.Request.Context(), c.Param("id"), request.Email)
+	h.respondEmailChange(c, err)
+}
+
+func (h *StudentHandler) respondEmailChange(c *gin.Context, err error) {
+	if err != nil {
+		respondError(c, fmt.Errorf("request email change: %w", err))
+		return
+	}
+	respondMessage(c, http.StatusAccepted, "email_change_sent")
+}
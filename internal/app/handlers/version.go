@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiVersionKey = "apiVersion"
+
+// Envelope is the body of every successful v2 response. Meta is set on
+// lists. Errors are problem details in both versions.
+type Envelope struct {
+	Data interface{} `json:"data"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+type Meta struct {
+	Count int `json:"count"`
+}
+
+// APIVersion marks the routes of a group as belonging to an API version.
+// The handlers are shared; only the shape of their responses differs.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+func isV2(c *gin.Context) bool {
+	return c.GetInt(apiVersionKey) >= 2
+}
+
+// respondJSON writes body as it is on v1 routes and in the envelope on v2.
+func respondJSON(c *gin.Context, status int, body interface{}) {
+	if isV2(c) {
+		c.JSON(status, Envelope{Data: body})
+		return
+	}
+	c.JSON(status, body)
+}
+
+// respondData writes the responses v1 already wrapped in "data", which is
+// the v2 envelope without meta.
+func respondData(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, Envelope{Data: data})
+}
+
+// respondList is respondData for lists, with their count on v2.
+func respondList(c *gin.Context, data interface{}, count int) {
+	if isV2(c) {
+		c.JSON(http.StatusOK, Envelope{Data: data, Meta: &Meta{Count: count}})
+		return
+	}
+	c.JSON(http.StatusOK, Envelope{Data: data})
+}
+
+// Deprecated announces on every response of a group that it is deprecated
+// since deprecatedAt (RFC 9745) and goes away at sunset (RFC 8594), and
+// links the version replacing it. Zero times leave their header out.
+func Deprecated(deprecatedAt, sunset time.Time, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !deprecatedAt.IsZero() {
+			c.Header("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+		}
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successor != "" {
+			c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nurmeden/students-service/internal/app/handlers/mocks"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newV2TestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Set(apiVersionKey, 2)
+	return c, w
+}
+
+func TestStudentHandler_V2Envelope(t *testing.T) {
+	student := func() *model.Student {
+		return &model.Student{FirstName: "Dulat", Email: "test@test.com", Password: "$2a$hash", Role: model.RoleStudent}
+	}
+	const studentJSON = `{"ID":"000000000000000000000000","firstName":"Dulat","lastName":"","email":"test@test.com","age":"","courses":null,"role":"student","emailVerified":false}`
+
+	tests := []struct {
+		name         string
+		mockFn       func(m *mocks.MockStudentUsecase)
+		call         func(h *StudentHandler, c *gin.Context)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "Student without password hash",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentByID", mock.Anything, "s1").Return(student(), nil)
+			},
+			call:         func(h *StudentHandler, c *gin.Context) { h.GetStudentByID(c) },
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":` + studentJSON + `}`,
+		},
+		{
+			name: "Roster with count",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentsByCourseID", mock.Anything, "s1").Return([]*model.Student{student()}, nil)
+			},
+			call:         func(h *StudentHandler, c *gin.Context) { h.GetStudentsByCourseID(c) },
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[` + studentJSON + `],"meta":{"count":1}}`,
+		},
+		{
+			name: "Empty roster is an empty list",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("GetStudentsByCourseID", mock.Anything, "s1").Return([]*model.Student(nil), nil)
+			},
+			call:         func(h *StudentHandler, c *gin.Context) { h.GetStudentsByCourseID(c) },
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[],"meta":{"count":0}}`,
+		},
+		{
+			name: "Message",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("DeleteStudent", mock.Anything, "s1").Return(nil)
+			},
+			call:         func(h *StudentHandler, c *gin.Context) { h.DeleteStudent(c) },
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":{"message":"Student deleted"}}`,
+		},
+		{
+			name: "Tokens",
+			mockFn: func(m *mocks.MockStudentUsecase) {
+				m.On("SignIn", mock.Anything, mock.Anything).Return(&model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"}, nil)
+			},
+			call:         func(h *StudentHandler, c *gin.Context) { h.SignIn(c) },
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":{"token":"auth_token","refresh_token":"refresh_token"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStudentUsecase := &mocks.MockStudentUsecase{}
+			tt.mockFn(mockStudentUsecase)
+			h := &StudentHandler{studentUsecase: mockStudentUsecase, logger: logrus.New()}
+			c, w := newV2TestContext("POST", "/api/v2/test", `{"email":"test@test.com","password":"password"}`)
+			c.Params = gin.Params{{Key: "id", Value: "s1"}}
+
+			tt.call(h, c)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockStudentUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStudentHandler_V2RefreshCookiePath(t *testing.T) {
+	mockStudentUsecase := &mocks.MockStudentUsecase{}
+	mockStudentUsecase.On("SignIn", mock.Anything, mock.Anything).Return(&model.AuthToken{Token: "auth_token", RefreshToken: "refresh_token"}, nil)
+	h := NewStudentHandler(mockStudentUsecase, nil, logrus.New(), testCookies())
+	c, w := newV2TestContext("POST", "/api/v2/auth/sign-in", `{"email":"test@test.com","password":"password"}`)
+	c.Request.Header.Set(AuthModeHeader, AuthModeCookie)
+
+	h.SignIn(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "jwt-token_refresh" {
+			assert.Equal(t, "/api/v2/auth", cookie.Path)
+			return
+		}
+	}
+	t.Fatal("no refresh cookie set")
+}
+
+func TestDeprecated(t *testing.T) {
+	deprecatedAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	router := gin.New()
+	router.GET("/api/me", Deprecated(deprecatedAt, sunset, "/api/v2"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/api/other", Deprecated(time.Time{}, time.Time{}, ""), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/me", nil))
+	assert.Equal(t, "@1793491200", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/other", nil))
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
@@ -0,0 +1,400 @@
+package i18n
+
+// catalog holds every message of the API in every supported language. Keys
+// of errors are their codes; the English text matches the message of the
+// domain error. Verbs take the Args of the error in the same order.
+var catalog = map[string]map[string]string{
+	// Generic errors.
+	"internal_error": {
+		English: "internal server error",
+		Russian: "внутренняя ошибка сервера",
+		Kazakh:  "сервердің ішкі қатесі",
+	},
+	"invalid_request": {
+		English: "invalid request body",
+		Russian: "некорректное тело запроса",
+		Kazakh:  "сұраныс денесі қате",
+	},
+	"forbidden": {
+		English: "forbidden",
+		Russian: "доступ запрещён",
+		Kazakh:  "қол жеткізуге тыйым салынған",
+	},
+	"rate_limited": {
+		English: "too many requests, try again later",
+		Russian: "слишком много запросов, повторите попытку позже",
+		Kazakh:  "сұраныстар тым көп, кейінірек қайталап көріңіз",
+	},
+	"deadline_exceeded": {
+		English: "the request took too long",
+		Russian: "запрос выполнялся слишком долго",
+		Kazakh:  "сұраныс тым ұзақ орындалды",
+	},
+
+	// Validation of request fields, with the field name as invalid param.
+	"validation_required": {
+		English: "is required",
+		Russian: "обязательное поле",
+		Kazakh:  "міндетті өріс",
+	},
+	"validation_email": {
+		English: "must be a valid email address",
+		Russian: "должен быть корректным адресом электронной почты",
+		Kazakh:  "дұрыс электрондық пошта мекенжайы болуы керек",
+	},
+	"validation_min": {
+		English: "must have at least %s items",
+		Russian: "должно содержать не менее %s элементов",
+		Kazakh:  "кемінде %s элементтен тұруы керек",
+	},
+	"validation_invalid": {
+		English: "is invalid",
+		Russian: "некорректное значение",
+		Kazakh:  "мәні қате",
+	},
+
+	// Authentication.
+	"missing_authorization": {
+		English: "authorization header is missing",
+		Russian: "отсутствует заголовок Authorization",
+		Kazakh:  "Authorization тақырыбы жоқ",
+	},
+	"malformed_authorization": {
+		English: "malformed Authorization header",
+		Russian: "некорректный заголовок Authorization",
+		Kazakh:  "Authorization тақырыбы қате",
+	},
+	"invalid_token": {
+		English: "invalid access token",
+		Russian: "недействительный токен доступа",
+		Kazakh:  "қол жеткізу токені жарамсыз",
+	},
+	"token_revoked": {
+		English: "access token revoked",
+		Russian: "токен доступа отозван",
+		Kazakh:  "қол жеткізу токені кері қайтарылды",
+	},
+	"invalid_refresh_token": {
+		English: "invalid refresh token",
+		Russian: "недействительный токен обновления",
+		Kazakh:  "жаңарту токені жарамсыз",
+	},
+	"refresh_token_reused": {
+		English: "refresh token reused",
+		Russian: "токен обновления использован повторно",
+		Kazakh:  "жаңарту токені қайта пайдаланылды",
+	},
+	"refresh_token_not_found": {
+		English: "refresh token not found",
+		Russian: "токен обновления не найден",
+		Kazakh:  "жаңарту токені табылмады",
+	},
+	"invalid_api_key": {
+		English: "invalid, expired or revoked api key",
+		Russian: "API-ключ недействителен, истёк или отозван",
+		Kazakh:  "API кілті жарамсыз, мерзімі өткен немесе кері қайтарылған",
+	},
+	"csrf_failed": {
+		English: "invalid CSRF token",
+		Russian: "недействительный CSRF-токен",
+		Kazakh:  "CSRF токені жарамсыз",
+	},
+	"credentials_missing": {
+		English: "email and password are required",
+		Russian: "необходимо указать email и пароль",
+		Kazakh:  "email мен құпиясөз міндетті",
+	},
+	"invalid_credentials": {
+		English: "invalid email or password",
+		Russian: "неверный email или пароль",
+		Kazakh:  "email немесе құпиясөз қате",
+	},
+	"account_locked": {
+		English: "too many failed sign-in attempts, try again later",
+		Russian: "слишком много неудачных попыток входа, повторите попытку позже",
+		Kazakh:  "кіру әрекеттері тым көп сәтсіз болды, кейінірек қайталап көріңіз",
+	},
+	"email_not_verified": {
+		English: "email address is not verified",
+		Russian: "адрес электронной почты не подтверждён",
+		Kazakh:  "электрондық пошта мекенжайы расталмаған",
+	},
+
+	// Two-factor authentication.
+	"invalid_challenge": {
+		English: "invalid or expired two-factor challenge",
+		Russian: "недействительный или истёкший запрос двухфакторной аутентификации",
+		Kazakh:  "екі факторлы аутентификация сұрауы жарамсыз немесе мерзімі өткен",
+	},
+	"invalid_two_factor_code": {
+		English: "invalid two-factor code",
+		Russian: "неверный код двухфакторной аутентификации",
+		Kazakh:  "екі факторлы аутентификация коды қате",
+	},
+	"two_factor_enabled": {
+		English: "two-factor authentication already enabled",
+		Russian: "двухфакторная аутентификация уже включена",
+		Kazakh:  "екі факторлы аутентификация қосылып қойған",
+	},
+	"two_factor_not_enabled": {
+		English: "two-factor authentication not enabled",
+		Russian: "двухфакторная аутентификация не включена",
+		Kazakh:  "екі факторлы аутентификация қосылмаған",
+	},
+	"two_factor_required": {
+		English: "two-factor authentication is required for this role",
+		Russian: "для этой роли требуется двухфакторная аутентификация",
+		Kazakh:  "бұл рөл үшін екі факторлы аутентификация міндетті",
+	},
+	"no_two_factor_enrollment": {
+		English: "no two-factor enrollment in progress",
+		Russian: "подключение двухфакторной аутентификации не начато",
+		Kazakh:  "екі факторлы аутентификацияны қосу басталмаған",
+	},
+
+	// Single sign-on.
+	"oidc_disabled": {
+		English: "single sign-on is not enabled",
+		Russian: "единый вход не включён",
+		Kazakh:  "бірыңғай кіру қосылмаған",
+	},
+	"invalid_oidc_state": {
+		English: "invalid or expired single sign-on state, start again",
+		Russian: "недействительный или истёкший вход через провайдера, начните заново",
+		Kazakh:  "провайдер арқылы кіру жарамсыз немесе мерзімі өткен, қайта бастаңыз",
+	},
+	"invalid_oidc_callback": {
+		English: "code and state are required",
+		Russian: "необходимы параметры code и state",
+		Kazakh:  "code және state параметрлері міндетті",
+	},
+	"oidc_email_not_verified": {
+		English: "the identity provider did not verify the email",
+		Russian: "провайдер идентификации не подтвердил email",
+		Kazakh:  "сәйкестендіру провайдері email-ді растамады",
+	},
+	"invalid_id_token": {
+		English: "invalid identity token",
+		Russian: "недействительный токен идентификации",
+		Kazakh:  "сәйкестендіру токені жарамсыз",
+	},
+	"sign_in_refused": {
+		English: "sign-in was refused by the identity provider",
+		Russian: "провайдер идентификации отклонил вход",
+		Kazakh:  "сәйкестендіру провайдері кіруден бас тартты",
+	},
+	"identity_provider_unavailable": {
+		English: "identity provider is unavailable",
+		Russian: "провайдер идентификации недоступен",
+		Kazakh:  "сәйкестендіру провайдері қолжетімсіз",
+	},
+
+	// Passwords.
+	"password_too_short": {
+		English: "password is too short: at least %d characters required",
+		Russian: "пароль слишком короткий: требуется не менее %d символов",
+		Kazakh:  "құпиясөз тым қысқа: кемінде %d таңба қажет",
+	},
+	"password_too_long": {
+		English: "password is too long: at most %d characters allowed",
+		Russian: "пароль слишком длинный: допускается не более %d символов",
+		Kazakh:  "құпиясөз тым ұзын: ең көбі %d таңбаға рұқсат етіледі",
+	},
+	"password_too_long_bytes": {
+		English: "password is too long: at most %d bytes allowed",
+		Russian: "пароль слишком длинный: допускается не более %d байт",
+		Kazakh:  "құпиясөз тым ұзын: ең көбі %d байтқа рұқсат етіледі",
+	},
+	"password_too_common": {
+		English: "password is too common",
+		Russian: "пароль слишком распространённый",
+		Kazakh:  "құпиясөз тым кең таралған",
+	},
+	"wrong_password": {
+		English: "current password is incorrect",
+		Russian: "текущий пароль неверен",
+		Kazakh:  "ағымдағы құпиясөз қате",
+	},
+	"invalid_reset_token": {
+		English: "invalid or expired password reset token",
+		Russian: "недействительный или истёкший токен сброса пароля",
+		Kazakh:  "құпиясөзді қалпына келтіру токені жарамсыз немесе мерзімі өткен",
+	},
+
+	// Email verification.
+	"invalid_verification_token": {
+		English: "invalid or expired email verification token",
+		Russian: "недействительный или истёкший токен подтверждения email",
+		Kazakh:  "email растау токені жарамсыз немесе мерзімі өткен",
+	},
+	"email_already_verified": {
+		English: "email already verified",
+		Russian: "email уже подтверждён",
+		Kazakh:  "email расталып қойған",
+	},
+	"email_taken": {
+		English: "email already exists",
+		Russian: "email уже зарегистрирован",
+		Kazakh:  "бұл email тіркелген",
+	},
+	"one_time_token_not_found": {
+		English: "one-time token not found or expired",
+		Russian: "одноразовый токен не найден или истёк",
+		Kazakh:  "бір реттік токен табылмады немесе мерзімі өткен",
+	},
+
+	// Students and accounts.
+	"student_not_found": {
+		English: "student not found",
+		Russian: "студент не найден",
+		Kazakh:  "студент табылмады",
+	},
+	"invalid_course_id": {
+		English: "invalid course ID",
+		Russian: "некорректный идентификатор курса",
+		Kazakh:  "курс идентификаторы жарамсыз",
+	},
+	"too_many_ids": {
+		English: "at most %d IDs per request",
+		Russian: "не более %d идентификаторов в запросе",
+		Kazakh:  "бір сұраныста ең көбі %d идентификатор",
+	},
+	"no_students_for_course": {
+		English: "no students found for the course",
+		Russian: "на курсе не найдено студентов",
+		Kazakh:  "курста студенттер табылмады",
+	},
+	"courses_unavailable": {
+		English: "courses service is unavailable",
+		Russian: "сервис курсов недоступен",
+		Kazakh:  "курстар қызметі қолжетімсіз",
+	},
+	"invalid_role": {
+		English: "invalid role",
+		Russian: "недопустимая роль",
+		Kazakh:  "рөл жарамсыз",
+	},
+	"session_not_found": {
+		English: "session not found",
+		Russian: "сеанс не найден",
+		Kazakh:  "сеанс табылмады",
+	},
+
+	// Idempotency keys.
+	"invalid_idempotency_key": {
+		English: "Idempotency-Key must be 1 to 255 printable characters",
+		Russian: "Idempotency-Key должен содержать от 1 до 255 печатных символов",
+		Kazakh:  "Idempotency-Key 1-ден 255-ке дейін басылатын таңбадан тұруы керек",
+	},
+	"idempotency_key_reused": {
+		English: "Idempotency-Key was already used for a different request",
+		Russian: "Idempotency-Key уже использован для другого запроса",
+		Kazakh:  "Idempotency-Key басқа сұраныс үшін қолданылған",
+	},
+	"idempotency_key_in_use": {
+		English: "a request with this Idempotency-Key is still in progress",
+		Russian: "запрос с этим Idempotency-Key ещё выполняется",
+		Kazakh:  "осы Idempotency-Key бар сұраныс әлі орындалуда",
+	},
+
+	// GraphQL.
+	"query_too_deep": {
+		English: "query depth %d exceeds the limit of %d",
+		Russian: "глубина запроса %d превышает предел %d",
+		Kazakh:  "сұраныс тереңдігі %d шектен асады: %d",
+	},
+	"query_too_complex": {
+		English: "query complexity %d exceeds the limit of %d",
+		Russian: "сложность запроса %d превышает предел %d",
+		Kazakh:  "сұраныс күрделілігі %d шектен асады: %d",
+	},
+
+	// API keys.
+	"api_key_not_found": {
+		English: "api key not found",
+		Russian: "API-ключ не найден",
+		Kazakh:  "API кілті табылмады",
+	},
+	"invalid_scope": {
+		English: "invalid scope %s",
+		Russian: "недопустимая область доступа %s",
+		Kazakh:  "рұқсат аясы жарамсыз: %s",
+	},
+	"invalid_expiry": {
+		English: "api key expiry must be in the future",
+		Russian: "срок действия API-ключа должен быть в будущем",
+		Kazakh:  "API кілтінің жарамдылық мерзімі болашақта болуы керек",
+	},
+
+	// Success messages.
+	"student_deleted": {
+		English: "Student deleted",
+		Russian: "Студент успешно удален",
+		Kazakh:  "Студент жойылды",
+	},
+	"account_unlocked": {
+		English: "Account unlocked",
+		Russian: "Учётная запись разблокирована",
+		Kazakh:  "Есептік жазба бұғаттан шығарылды",
+	},
+	"account_deleted": {
+		English: "Account deleted",
+		Russian: "Учётная запись удалена",
+		Kazakh:  "Есептік жазба жойылды",
+	},
+	"logged_out": {
+		English: "Successfully logged out",
+		Russian: "Выход выполнен",
+		Kazakh:  "Жүйеден шықтыңыз",
+	},
+	"logged_out_all": {
+		English: "Successfully logged out of all sessions",
+		Russian: "Выход выполнен во всех сеансах",
+		Kazakh:  "Барлық сеанстардан шықтыңыз",
+	},
+	"session_revoked": {
+		English: "Session revoked",
+		Russian: "Сеанс завершён",
+		Kazakh:  "Сеанс аяқталды",
+	},
+	"password_changed": {
+		English: "Password changed",
+		Russian: "Пароль изменён",
+		Kazakh:  "Құпиясөз өзгертілді",
+	},
+	"password_reset": {
+		English: "Password has been reset",
+		Russian: "Пароль сброшен",
+		Kazakh:  "Құпиясөз қалпына келтірілді",
+	},
+	"reset_link_sent": {
+		English: "If the email is registered, a reset link has been sent",
+		Russian: "Если email зарегистрирован, на него отправлена ссылка для сброса пароля",
+		Kazakh:  "Егер email тіркелген болса, оған қалпына келтіру сілтемесі жіберілді",
+	},
+	"email_verified": {
+		English: "Email verified",
+		Russian: "Email подтверждён",
+		Kazakh:  "Email расталды",
+	},
+	"verification_sent": {
+		English: "Verification email sent",
+		Russian: "Письмо для подтверждения отправлено",
+		Kazakh:  "Растау хаты жіберілді",
+	},
+	"email_change_sent": {
+		English: "Verification email sent to the new address",
+		Russian: "Письмо для подтверждения отправлено на новый адрес",
+		Kazakh:  "Растау хаты жаңа мекенжайға жіберілді",
+	},
+	"two_factor_disabled": {
+		English: "Two-factor authentication disabled",
+		Russian: "Двухфакторная аутентификация отключена",
+		Kazakh:  "Екі факторлы аутентификация өшірілді",
+	},
+	"api_key_revoked": {
+		English: "API key revoked",
+		Russian: "API-ключ отозван",
+		Kazakh:  "API кілті кері қайтарылды",
+	},
+}
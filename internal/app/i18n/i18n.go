@@ -0,0 +1,69 @@
+// Package i18n translates the messages of the API. Messages are looked up
+// by key: the code of a domain error or the key of a success message.
+// Codes stay the same in every language; only the text is translated.
+package i18n
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+	Kazakh  = "kk"
+
+	// Default is the language of clients that ask for none we have.
+	Default = English
+)
+
+// supported is in the order of preference for ties; the first is the
+// fallback of the matcher.
+var supported = []string{English, Russian, Kazakh}
+
+var matcher = language.NewMatcher([]language.Tag{
+	language.English,
+	language.Russian,
+	language.Kazakh,
+})
+
+// Negotiate picks the supported language that best matches an
+// Accept-Language header, or Default.
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return supported[index]
+}
+
+// Message returns the text of key in lang with args formatted into it,
+// falling back to English. ok is false for unknown keys.
+func Message(lang, key string, args ...interface{}) (text string, ok bool) {
+	translations, ok := catalog[key]
+	if !ok {
+		return "", false
+	}
+	text, ok = translations[lang]
+	if !ok {
+		text = translations[Default]
+	}
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	return text, true
+}
+
+// Text is Message for keys that are in the catalog. An unknown key comes
+// back as it is.
+func Text(lang, key string, args ...interface{}) string {
+	if text, ok := Message(lang, key, args...); ok {
+		return text
+	}
+	return key
+}
@@ -0,0 +1,59 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", English},
+		{"ru", Russian},
+		{"ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", Russian},
+		{"kk-KZ", Kazakh},
+		{"en;q=0.5, kk;q=0.8", Kazakh},
+		{"de-DE", English},
+		{"*", English},
+		{"not a language;;", English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.acceptLanguage))
+		})
+	}
+}
+
+var verb = regexp.MustCompile(`%[a-z]`)
+
+// Every message must exist in every language and take the same arguments.
+func TestCatalogIsComplete(t *testing.T) {
+	for key, translations := range catalog {
+		english := translations[English]
+		assert.NotEmpty(t, english, key)
+		for _, lang := range supported {
+			text, ok := translations[lang]
+			if assert.True(t, ok, "%s has no %s translation", key, lang) {
+				assert.Equal(t, verb.FindAllString(english, -1), verb.FindAllString(text, -1), "verbs of %s in %s", key, lang)
+			}
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	text, ok := Message(Kazakh, "password_too_short", 12)
+	assert.True(t, ok)
+	assert.Equal(t, "құпиясөз тым қысқа: кемінде 12 таңба қажет", text)
+
+	text, ok = Message("fr", "student_not_found")
+	assert.True(t, ok)
+	assert.Equal(t, "student not found", text)
+
+	_, ok = Message(English, "no_such_key")
+	assert.False(t, ok)
+	assert.Equal(t, "no_such_key", Text(Russian, "no_such_key"))
+}
@@ -0,0 +1,355 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	manifestFile = "keys.json"
+	rsaKeyBits   = 2048
+
+	// JWKSMaxAge is how long clients may cache the JWKS.
+	JWKSMaxAge = 5 * time.Minute
+
+	// PublishDelay is how long a new key is published before it signs, so
+	// services holding a cached JWKS know it by then.
+	PublishDelay = JWKSMaxAge + time.Minute
+)
+
+var (
+	ErrNoActiveKey = errors.New("no active signing key")
+	ErrUnknownKey  = errors.New("unknown key id")
+)
+
+// Entry is the manifest record of a key. The private key itself lives next
+// to the manifest in <id>.pem.
+type Entry struct {
+	ID        string     `json:"id"`
+	Algorithm string     `json:"alg"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt,omitempty"`
+}
+
+func (e Entry) Active() bool {
+	return e.RetiredAt == nil
+}
+
+type Key struct {
+	Entry
+	Private crypto.Signer
+}
+
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+func (k *Key) SigningMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// KeySet holds every active key of a key directory. The newest active key
+// published for PublishDelay signs new tokens, all active keys verify, so
+// tokens signed before a rotation stay valid until the old key is retired.
+type KeySet struct {
+	mu      sync.RWMutex
+	dir     string
+	now     func() time.Time
+	keys    map[string]*Key
+	signing *Key
+}
+
+func Load(dir string) (*KeySet, error) {
+	ks := &KeySet{dir: dir, now: time.Now}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload re-reads the key directory so keys generated or retired with the
+// keys command are picked up without a restart. It also hands signing over
+// to new keys once they were published for PublishDelay.
+func (ks *KeySet) Reload() error {
+	entries, err := ReadManifest(ks.dir)
+	if err != nil {
+		return err
+	}
+
+	signingEntry := signingEntry(entries, ks.now())
+	if signingEntry == nil {
+		return ErrNoActiveKey
+	}
+	loaded := make(map[string]*Key)
+	for _, entry := range entries {
+		if !entry.Active() {
+			continue
+		}
+		private, err := readPrivateKey(ks.dir, entry.ID)
+		if err != nil {
+			return err
+		}
+		loaded[entry.ID] = &Key{Entry: entry, Private: private}
+	}
+	signing := loaded[signingEntry.ID]
+
+	ks.mu.Lock()
+	ks.keys = loaded
+	ks.signing = signing
+	ks.mu.Unlock()
+	return nil
+}
+
+// signingEntry returns the key that signs at now: the newest active key
+// published for PublishDelay or, when every key is newer, as in a fresh key
+// directory, the oldest, which has been published the longest. It is nil
+// without active keys.
+func signingEntry(entries []Entry, now time.Time) *Entry {
+	publishedBefore := now.Add(-PublishDelay)
+	var signing, oldest *Entry
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.Active() {
+			continue
+		}
+		if !entry.CreatedAt.After(publishedBefore) && (signing == nil || entry.CreatedAt.After(signing.CreatedAt)) {
+			signing = entry
+		}
+		if oldest == nil || entry.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = entry
+		}
+	}
+	if signing == nil {
+		return oldest
+	}
+	return signing
+}
+
+// Watch reloads the key set every interval until stop is closed.
+func (ks *KeySet) Watch(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (ks *KeySet) SigningKey() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.signing
+}
+
+func (ks *KeySet) Key(id string) (*Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// Keyfunc resolves the verification key of a token by its kid header.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+	key, err := ks.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public(), nil
+}
+
+// Sign signs the claims with the current signing key and stamps its kid.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := ks.SigningKey()
+	token := jwt.NewWithClaims(key.SigningMethod(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		set.Keys = append(set.Keys, NewJWK(key))
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// Generate creates a new key in dir and registers it in the manifest. The
+// new key is published on the next Reload and signs once it was published
+// for PublishDelay.
+func Generate(dir, alg string) (*Entry, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newKeyID()
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(keyPath(dir, id), pemBytes, 0600); err != nil {
+		return nil, err
+	}
+
+	entries, err := ReadManifest(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	entry := Entry{ID: id, Algorithm: alg, CreatedAt: time.Now().UTC()}
+	entries = append(entries, entry)
+	if err := writeManifest(dir, entries); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Retire stops publishing and accepting the key. Its file is kept so the
+// manifest stays an audit trail of past keys. The key signing right now is
+// only retired when another active key has been published for
+// PublishDelay, so that caches of the JWKS know the key signing next, or
+// with force.
+func Retire(dir, id string, force bool) error {
+	return retire(dir, id, force, time.Now().UTC())
+}
+
+func retire(dir, id string, force bool, now time.Time) error {
+	entries, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	var retired *Entry
+	for i := range entries {
+		if entries[i].ID == id {
+			retired = &entries[i]
+		}
+	}
+	if retired == nil {
+		return ErrUnknownKey
+	}
+	if !retired.Active() {
+		return nil
+	}
+	signing := signingEntry(entries, now)
+	retired.RetiredAt = &now
+
+	successor := signingEntry(entries, now)
+	if successor == nil {
+		return fmt.Errorf("refusing to retire %s: it is the last active key", id)
+	}
+	if signing.ID == id && successor.CreatedAt.After(now.Add(-PublishDelay)) && !force {
+		return fmt.Errorf("refusing to retire %s: it is the signing key and no other key has been published for %s yet", id, PublishDelay)
+	}
+	return writeManifest(dir, entries)
+}
+
+func ReadManifest(dir string) ([]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode key manifest: %v", err)
+	}
+	return entries, nil
+}
+
+func writeManifest(dir string, entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, manifestFile))
+}
+
+func readPrivateKey(dir, id string) (crypto.Signer, error) {
+	data, err := os.ReadFile(keyPath(dir, id))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM block found", id)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %v", id, err)
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, parsed)
+	}
+	return signer, nil
+}
+
+func keyPath(dir, id string) string {
+	return filepath.Join(dir, id+".pem")
+}
+
+func newKeyID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,111 @@
+package keys
+
+import (
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySet_Rotation(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			dir := t.TempDir()
+
+			first, err := Generate(dir, alg)
+			require.NoError(t, err)
+			ks, err := Load(dir)
+			require.NoError(t, err)
+
+			oldToken, err := ks.Sign(jwt.MapClaims{"sub": "student"})
+			require.NoError(t, err)
+
+			second, err := Generate(dir, alg)
+			require.NoError(t, err)
+			ks.now = func() time.Time { return second.CreatedAt.Add(PublishDelay) }
+			require.NoError(t, ks.Reload())
+			assert.Equal(t, second.ID, ks.SigningKey().ID)
+			assert.Len(t, ks.JWKS().Keys, 2)
+
+			newToken, err := ks.Sign(jwt.MapClaims{"sub": "student"})
+			require.NoError(t, err)
+
+			for _, token := range []string{oldToken, newToken} {
+				parsed, err := jwt.Parse(token, ks.Keyfunc)
+				require.NoError(t, err)
+				assert.True(t, parsed.Valid)
+			}
+
+			require.NoError(t, retire(dir, first.ID, false, ks.now()))
+			require.NoError(t, ks.Reload())
+			assert.Len(t, ks.JWKS().Keys, 1)
+
+			_, err = jwt.Parse(oldToken, ks.Keyfunc)
+			assert.Error(t, err)
+			_, err = jwt.Parse(newToken, ks.Keyfunc)
+			assert.NoError(t, err)
+
+			assert.Error(t, Retire(dir, second.ID, true), "the last active key must not be retired")
+		})
+	}
+}
+
+func TestKeySet_PublishesNewKeysBeforeSigning(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Generate(dir, AlgEdDSA)
+	require.NoError(t, err)
+	ks, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, ks.SigningKey().ID, "a fresh key directory signs right away")
+
+	second, err := Generate(dir, AlgEdDSA)
+	require.NoError(t, err)
+	ks.now = func() time.Time { return second.CreatedAt.Add(JWKSMaxAge) }
+	require.NoError(t, ks.Reload())
+	assert.Equal(t, first.ID, ks.SigningKey().ID, "the new key waits until cached JWKS expired")
+	assert.Len(t, ks.JWKS().Keys, 2, "the new key is published meanwhile")
+
+	ks.now = func() time.Time { return second.CreatedAt.Add(PublishDelay) }
+	require.NoError(t, ks.Reload())
+	assert.Equal(t, second.ID, ks.SigningKey().ID)
+}
+
+func TestRetire_SigningKey(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Generate(dir, AlgEdDSA)
+	require.NoError(t, err)
+	second, err := Generate(dir, AlgEdDSA)
+	require.NoError(t, err)
+
+	assert.Error(t, retire(dir, first.ID, false, second.CreatedAt.Add(JWKSMaxAge)), "the successor is not published long enough")
+	entries, err := ReadManifest(dir)
+	require.NoError(t, err)
+	assert.True(t, entries[0].Active(), "a refused retirement leaves the manifest alone")
+
+	require.NoError(t, retire(dir, second.ID, false, second.CreatedAt.Add(JWKSMaxAge)), "keys that do not sign may go any time")
+	third, err := Generate(dir, AlgEdDSA)
+	require.NoError(t, err)
+	require.NoError(t, retire(dir, first.ID, true, third.CreatedAt), "unless forced")
+
+	ks, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, third.ID, ks.SigningKey().ID)
+}
+
+func TestKeySet_RejectsAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	entry, err := Generate(dir, AlgRS256)
+	require.NoError(t, err)
+	ks, err := Load(dir)
+	require.NoError(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "admin"})
+	forged.Header["kid"] = entry.ID
+	token, err := forged.SignedString([]byte("guessed"))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(token, ks.Keyfunc)
+	assert.Error(t, err)
+}
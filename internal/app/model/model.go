@@ -6,25 +6,198 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	RoleAdmin      = "admin"
+	RoleInstructor = "instructor"
+	RoleStudent    = "student"
+)
+
 type Student struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
 	FirstName string             `bson:"firstName" json:"firstName"`
 	LastName  string             `json:"lastName" json:"lastName"`
-	Password  string             `json:"password"`
+	Password  string             `json:"password,omitempty"`
 	Email     string             `json:"email"`
 	Age       string             `json:"age" json:"age"`
 	Courses   []string           `bson:"courses" json:"courses"`
+	Role      string             `bson:"role" json:"role"`
+
+	EmailVerified bool `bson:"emailVerified" json:"emailVerified"`
+	// PendingEmail holds a requested new address until it is verified.
+	PendingEmail string `bson:"pendingEmail,omitempty" json:"pendingEmail,omitempty"`
+}
+
+// EffectiveRole treats records created before roles existed as students.
+func (s *Student) EffectiveRole() string {
+	if s.Role == "" {
+		return RoleStudent
+	}
+	return s.Role
+}
+
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleInstructor || role == RoleStudent
+}
+
+type RoleUpdate struct {
+	Role string `json:"role" binding:"required"`
 }
 
 type SignInData struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// TwoFactorEnrollment is the secret of a started TOTP enrollment. URI is
+// the otpauth:// provisioning URI to show as a QR code.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauthUrl"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// TwoFactorSignInRequest completes a sign-in with a TOTP or recovery code.
+type TwoFactorSignInRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	ClientIP       string `json:"-"`
+	UserAgent      string `json:"-"`
+}
+
+type TwoFactorRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// APIKey lets another service call the API with the permissions listed in
+// Scopes. Only the hash of the key is stored; Key is set once, in the
+// response that creates it. Prefix identifies the key in listings.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Hash       string             `bson:"hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedBy  string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	Key        string             `bson:"-" json:"key,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// Session is a sign-in of a student on one device, as listed to the
+// student. Current marks the session of the listing request.
+type Session struct {
+	ID            string    `json:"id"`
+	UserAgent     string    `json:"userAgent"`
+	IP            string    `json:"ip"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastRefreshAt time.Time `json:"lastRefreshAt"`
+	Current       bool      `json:"current"`
+}
+
+// OIDCLogin is a started single sign-on: the provider URL to redirect the
+// browser to, and the state it comes back with before ExpiresIn runs out.
+type OIDCLogin struct {
+	URL       string
+	State     string
+	ExpiresIn time.Duration
+}
+
+// OIDCCallbackRequest is the redirect back from the identity provider.
+type OIDCCallbackRequest struct {
+	Code      string
+	State     string
+	ClientIP  string
+	UserAgent string
+}
+
+// IntrospectionRequest is an RFC 7662 introspection request.
+type IntrospectionRequest struct {
+	Token         string `form:"token" json:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
+
+// TokenIntrospection is the RFC 7662 answer about a token. Inactive tokens
+// carry only Active, and Revoked with the token's identifiers when they
+// were revoked rather than expired or unknown.
+type TokenIntrospection struct {
+	Active    bool     `json:"active"`
+	Revoked   bool     `json:"revoked,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Tenant    string   `json:"tenant,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	JTI       string   `json:"jti,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
 }
 
 type AuthToken struct {
-	UserID    string    `json:"userId"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expiresAt"`
+	UserID       string    `json:"userId"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	// RecoveryCodes is only set when the sign-in completed a required
+	// two-factor enrollment.
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ProfileUpdate is a partial update of the fields students may change on
+// their own record. Nil fields are left alone.
+type ProfileUpdate struct {
+	FirstName *string `json:"firstName"`
+	LastName  *string `json:"lastName"`
+	Age       *string `json:"age"`
+}
+
+// PasswordChangeRequest changes the password of a signed-in student.
+// UserID, SessionID and ClientIP come from the request, not the body.
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+	UserID          string `json:"-"`
+	SessionID       string `json:"-"`
+	ClientIP        string `json:"-"`
+}
+
+type EmailChangeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `form:"refresh_token" json:"refresh_token" binding:"required"`
 }
 
 type Course struct {
@@ -37,3 +210,18 @@ type Course struct {
 type CourseResponse struct {
 	CourseData []Course `json:"data"`
 }
+
+// EnrollmentRequest names the course to enroll a student in.
+type EnrollmentRequest struct {
+	CourseID string `json:"courseId" binding:"required"`
+}
+
+// IdempotentResponse is the response stored for an Idempotency-Key, with
+// the hash of the request it answered. Status is zero while the first
+// request is still running.
+type IdempotentResponse struct {
+	RequestHash string `json:"requestHash"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
@@ -0,0 +1,206 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nurmeden/students-service/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+)
+
+const (
+	defaultArgon2Memory      = 64 * 1024
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher hashes passwords with the configured algorithm. Hashes keep their
+// parameters in the encoded string (the bcrypt format, or the PHC format
+// $argon2id$v=19$m=...,t=...,p=...$salt$key), so hashes made under an older
+// configuration still verify and NeedsRehash can tell them apart.
+type Hasher struct {
+	algorithm         string
+	bcryptCost        int
+	argon2Memory      uint32
+	argon2Iterations  uint32
+	argon2Parallelism uint8
+}
+
+// NewHasher returns a hasher for cfg. Unset parameters fall back to the
+// library defaults, and an empty algorithm means bcrypt.
+func NewHasher(cfg config.PasswordConfig) (*Hasher, error) {
+	h := &Hasher{
+		algorithm:         cfg.Algorithm,
+		bcryptCost:        cfg.BcryptCost,
+		argon2Memory:      cfg.Argon2Memory,
+		argon2Iterations:  cfg.Argon2Iterations,
+		argon2Parallelism: cfg.Argon2Parallelism,
+	}
+	if h.algorithm == "" {
+		h.algorithm = AlgBcrypt
+	}
+	if h.bcryptCost == 0 {
+		h.bcryptCost = bcrypt.DefaultCost
+	}
+	if h.argon2Memory == 0 {
+		h.argon2Memory = defaultArgon2Memory
+	}
+	if h.argon2Iterations == 0 {
+		h.argon2Iterations = defaultArgon2Iterations
+	}
+	if h.argon2Parallelism == 0 {
+		h.argon2Parallelism = defaultArgon2Parallelism
+	}
+
+	switch h.algorithm {
+	case AlgBcrypt:
+		if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost %d out of range", h.bcryptCost)
+		}
+	case AlgArgon2id:
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", h.algorithm)
+	}
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2Iterations, h.argon2Memory, h.argon2Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2Memory, h.argon2Iterations, h.argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches the encoded hash, whichever
+// supported algorithm produced it.
+func (h *Hasher) Verify(encoded, password string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// DummyHashes returns a hash of a random password for every supported
+// algorithm, made with the parameters of the hasher. Sign-ins check them
+// when there is no real hash to check, or a real one of another algorithm,
+// so that they take as long whichever hash an account has.
+func (h *Hasher) DummyHashes() (map[string]string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	dummy := base64.RawURLEncoding.EncodeToString(b)
+
+	hashes := make(map[string]string)
+	for _, algorithm := range []string{AlgBcrypt, AlgArgon2id} {
+		other := *h
+		other.algorithm = algorithm
+		hash, err := other.Hash(dummy)
+		if err != nil {
+			return nil, err
+		}
+		hashes[algorithm] = hash
+	}
+	return hashes, nil
+}
+
+// Algorithm returns the algorithm of an encoded hash, or "" when no
+// supported one made it.
+func Algorithm(encoded string) string {
+	if isBcrypt(encoded) {
+		return AlgBcrypt
+	}
+	if _, _, _, err := decodeArgon2id(encoded); err == nil {
+		return AlgArgon2id
+	}
+	return ""
+}
+
+// NeedsRehash reports whether the encoded hash was made with another
+// algorithm or other parameters than the hasher is configured with.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if isBcrypt(encoded) {
+		if h.algorithm != AlgBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.bcryptCost
+	}
+
+	params, _, key, err := decodeArgon2id(encoded)
+	if err != nil || h.algorithm != AlgArgon2id {
+		return true
+	}
+	return params.memory != h.argon2Memory ||
+		params.iterations != h.argon2Iterations ||
+		params.parallelism != h.argon2Parallelism ||
+		len(key) != argon2KeyLength
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgArgon2id {
+		return params, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownHash
+	}
+	return params, salt, key, nil
+}
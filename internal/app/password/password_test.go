@@ -0,0 +1,144 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2 keeps argon2id cheap enough for tests.
+var fastArgon2 = config.PasswordConfig{Algorithm: AlgArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	for _, cfg := range []config.PasswordConfig{{Algorithm: AlgBcrypt, BcryptCost: bcrypt.MinCost}, fastArgon2} {
+		t.Run(cfg.Algorithm, func(t *testing.T) {
+			h, err := NewHasher(cfg)
+			require.NoError(t, err)
+
+			hash, err := h.Hash("correct horse battery staple")
+			require.NoError(t, err)
+			assert.False(t, h.NeedsRehash(hash))
+
+			ok, err := h.Verify(hash, "correct horse battery staple")
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = h.Verify(hash, "correct horse battery stapler")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	oldBcrypt, err := NewHasher(config.PasswordConfig{BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+	bcryptHash, err := oldBcrypt.Hash("correct horse battery staple")
+	require.NoError(t, err)
+
+	costlier, err := NewHasher(config.PasswordConfig{BcryptCost: bcrypt.MinCost + 1})
+	require.NoError(t, err)
+	assert.True(t, costlier.NeedsRehash(bcryptHash), "bcrypt cost raised")
+
+	argon, err := NewHasher(fastArgon2)
+	require.NoError(t, err)
+	assert.True(t, argon.NeedsRehash(bcryptHash), "algorithm changed")
+	ok, err := argon.Verify(bcryptHash, "correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, ok, "old hashes still verify after the algorithm changes")
+
+	argonHash, err := argon.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	moreMemory := fastArgon2
+	moreMemory.Argon2Memory = 2048
+	stronger, err := NewHasher(moreMemory)
+	require.NoError(t, err)
+	assert.True(t, stronger.NeedsRehash(argonHash), "argon2id parameters raised")
+	assert.True(t, oldBcrypt.NeedsRehash(argonHash), "algorithm changed back")
+}
+
+func TestHasher_DummyHashes(t *testing.T) {
+	cfg := fastArgon2
+	cfg.BcryptCost = bcrypt.MinCost
+	h, err := NewHasher(cfg)
+	require.NoError(t, err)
+
+	hashes, err := h.DummyHashes()
+	require.NoError(t, err)
+	require.Len(t, hashes, 2)
+	for algorithm, hash := range hashes {
+		assert.Equal(t, algorithm, Algorithm(hash))
+		if algorithm == AlgArgon2id {
+			assert.False(t, h.NeedsRehash(hash), "made with the configured parameters")
+		}
+		ok, err := h.Verify(hash, "correct horse battery staple")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+	cost, err := bcrypt.Cost([]byte(hashes[AlgBcrypt]))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+	assert.Equal(t, "", Algorithm("$md5$hash"))
+}
+
+func TestHasher_VerifyMalformed(t *testing.T) {
+	h, err := NewHasher(fastArgon2)
+	require.NoError(t, err)
+
+	ok, err := h.Verify("$argon2id$v=19$m=1024$broken", "password")
+	assert.False(t, ok)
+	assert.Equal(t, ErrUnknownHash, err)
+
+	_, err = NewHasher(config.PasswordConfig{Algorithm: "md5"})
+	assert.Error(t, err)
+}
+
+func TestPolicy_Check(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(blocklist, []byte("# breached\nTr0ub4dor&3\n"), 0o600))
+
+	p, err := NewPolicy(config.PasswordConfig{MinLength: 10, MaxLength: 20, BlocklistFile: blocklist})
+	require.NoError(t, err)
+
+	tests := []struct {
+		password string
+		want     error
+	}{
+		{password: "correct horse", want: nil},
+		{password: "short", want: ErrTooShort},
+		{password: strings.Repeat("long", 6), want: ErrTooLong},
+		{password: "Password123", want: ErrCommon},
+		{password: "QWERTYUIOP", want: ErrCommon},
+		{password: "tr0ub4dor&3", want: ErrCommon},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			err := p.Check(tt.password)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.want), "got %v", err)
+		})
+	}
+}
+
+func TestPolicy_BcryptInputLimit(t *testing.T) {
+	p, err := NewPolicy(config.PasswordConfig{MaxLength: 64})
+	require.NoError(t, err)
+	// 40 characters but 80 bytes: bcrypt would ignore the tail.
+	assert.True(t, errors.Is(p.Check(strings.Repeat("ж", 40)), ErrTooLong))
+
+	p, err = NewPolicy(config.PasswordConfig{MaxLength: 64, Algorithm: AlgArgon2id})
+	require.NoError(t, err)
+	assert.NoError(t, p.Check(strings.Repeat("ж", 40)))
+}
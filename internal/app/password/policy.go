@@ -0,0 +1,135 @@
+// Package password enforces the password policy and hashes passwords.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nurmeden/students-service/config"
+)
+
+const (
+	defaultMinLength = 8
+	defaultMaxLength = 64
+	// bcryptMaxBytes is the input length bcrypt reads; anything after it
+	// would be silently ignored.
+	bcryptMaxBytes = 72
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrTooLong  = errors.New("password is too long")
+	ErrCommon   = errors.New("password is too common")
+)
+
+const (
+	UnitCharacters = "characters"
+	UnitBytes      = "bytes"
+)
+
+// LengthError is a password outside of the allowed length. Err is
+// ErrTooShort or ErrTooLong and Limit the bound it broke, in Unit.
+type LengthError struct {
+	Err   error
+	Limit int
+	Unit  string
+}
+
+func (e *LengthError) Error() string {
+	if e.Err == ErrTooShort {
+		return fmt.Sprintf("%v: at least %d %s required", e.Err, e.Limit, e.Unit)
+	}
+	return fmt.Sprintf("%v: at most %d %s allowed", e.Err, e.Limit, e.Unit)
+}
+
+func (e *LengthError) Unwrap() error {
+	return e.Err
+}
+
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// Policy decides which passwords students may choose: a length range and
+// no password from the built-in common password list or the optional
+// offline blocklist file.
+type Policy struct {
+	minLength int
+	maxLength int
+	maxBytes  int
+	blocklist map[string]struct{}
+}
+
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	p := &Policy{
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+		blocklist: make(map[string]struct{}),
+	}
+	if p.minLength == 0 {
+		p.minLength = defaultMinLength
+	}
+	if p.maxLength == 0 {
+		p.maxLength = defaultMaxLength
+	}
+	if cfg.Algorithm == "" || cfg.Algorithm == AlgBcrypt {
+		p.maxBytes = bcryptMaxBytes
+	}
+
+	if err := p.readBlocklist(strings.NewReader(commonPasswords)); err != nil {
+		return nil, err
+	}
+	if cfg.BlocklistFile != "" {
+		f, err := os.Open(cfg.BlocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if err := p.readBlocklist(f); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", cfg.BlocklistFile, err)
+		}
+	}
+	return p, nil
+}
+
+// Check returns nil when password satisfies the policy, otherwise an error
+// wrapping ErrTooShort, ErrTooLong or ErrCommon.
+func (p *Policy) Check(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength {
+		return &LengthError{Err: ErrTooShort, Limit: p.minLength, Unit: UnitCharacters}
+	}
+	if length > p.maxLength {
+		return &LengthError{Err: ErrTooLong, Limit: p.maxLength, Unit: UnitCharacters}
+	}
+	if p.maxBytes > 0 && len(password) > p.maxBytes {
+		return &LengthError{Err: ErrTooLong, Limit: p.maxBytes, Unit: UnitBytes}
+	}
+	if _, ok := p.blocklist[normalize(password)]; ok {
+		return ErrCommon
+	}
+	return nil
+}
+
+// readBlocklist adds one password per line; blank lines and lines starting
+// with # are skipped.
+func (p *Policy) readBlocklist(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[normalize(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+func normalize(password string) string {
+	return strings.ToLower(strings.TrimSpace(password))
+}
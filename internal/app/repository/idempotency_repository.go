@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+)
+
+const idempotencyPrefix = "idempotency:"
+
+// IdempotencyRepository stores the responses of requests by their
+// Idempotency-Key.
+type IdempotencyRepository struct {
+	cache  *redis.Client
+	logger *logrus.Logger
+}
+
+func NewIdempotencyRepository(cache *redis.Client, logger *logrus.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// Reserve claims key for the request with the given hash until Save or
+// Release, or until lockTTL passes. It returns nil if the key was free and
+// otherwise the response stored for it, which is pending if its request is
+// still running.
+func (r *IdempotencyRepository) Reserve(key, requestHash string, lockTTL time.Duration) (*model.IdempotentResponse, error) {
+	pending, err := json.Marshal(&model.IdempotentResponse{RequestHash: requestHash})
+	if err != nil {
+		return nil, err
+	}
+	// The stored response may expire between SETNX and GET; then the key is
+	// free again.
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := r.cache.SetNX(idempotencyPrefix+key, pending, lockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+		data, err := r.cache.Get(idempotencyPrefix + key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stored := &model.IdempotentResponse{}
+		if err := json.Unmarshal(data, stored); err != nil {
+			return nil, err
+		}
+		return stored, nil
+	}
+	return nil, nil
+}
+
+// Save stores the response to the request holding key for ttl.
+func (r *IdempotencyRepository) Save(key string, response *model.IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return r.cache.Set(idempotencyPrefix+key, data, ttl).Err()
+}
+
+// Release frees key without storing a response, so the request can be
+// retried.
+func (r *IdempotencyRepository) Release(key string) error {
+	return r.cache.Del(idempotencyPrefix + key).Err()
+}
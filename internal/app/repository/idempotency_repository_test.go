@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyRepository(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer cache.Close()
+	r := NewIdempotencyRepository(cache, logrus.New())
+
+	stored, err := r.Reserve("user:s1:key-1", "hash-1", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "a free key is reserved")
+
+	stored, err = r.Reserve("user:s1:key-1", "hash-1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, &model.IdempotentResponse{RequestHash: "hash-1"}, stored, "a reserved key is pending")
+
+	response := &model.IdempotentResponse{RequestHash: "hash-1", Status: 201, ContentType: "application/json", Body: []byte(`{"id":"s2"}`)}
+	require.NoError(t, r.Save("user:s1:key-1", response, time.Hour))
+	stored, err = r.Reserve("user:s1:key-1", "hash-2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, response, stored)
+
+	server.FastForward(2 * time.Hour)
+	stored, err = r.Reserve("user:s1:key-1", "hash-2", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "responses expire")
+
+	require.NoError(t, r.Release("user:s1:key-1"))
+	stored, err = r.Reserve("user:s1:key-1", "hash-3", time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "a released key is free")
+}
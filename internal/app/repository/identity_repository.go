@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const identityCollection = "identities"
+
+// Identity links an account at an external identity provider, named by
+// issuer and subject, to a student.
+type Identity struct {
+	ID       string             `bson:"_id"`
+	Issuer   string             `bson:"issuer"`
+	Subject  string             `bson:"subject"`
+	UserID   primitive.ObjectID `bson:"userID"`
+	Email    string             `bson:"email"`
+	LinkedAt time.Time          `bson:"linkedAt"`
+}
+
+type IdentityRepository struct {
+	collection *mongo.Collection
+	logger     *logrus.Logger
+}
+
+func NewIdentityRepository(client *mongo.Client, dbName string, logger *logrus.Logger) *IdentityRepository {
+	return &IdentityRepository{
+		collection: client.Database(dbName).Collection(identityCollection),
+		logger:     logger,
+	}
+}
+
+func identityID(issuer, subject string) string {
+	return issuer + "|" + subject
+}
+
+// Find returns the identity of the subject at the issuer, or nil if it is
+// not linked to a student.
+func (r *IdentityRepository) Find(ctx context.Context, issuer, subject string) (*Identity, error) {
+	var identity Identity
+	err := r.collection.FindOne(ctx, bson.M{"_id": identityID(issuer, subject)}).Decode(&identity)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %v", err)
+	}
+	return &identity, nil
+}
+
+func (r *IdentityRepository) Link(ctx context.Context, issuer, subject string, userID primitive.ObjectID, email string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": identityID(issuer, subject)},
+		bson.M{"$set": bson.M{
+			"issuer":   issuer,
+			"subject":  subject,
+			"userID":   userID,
+			"email":    email,
+			"linkedAt": time.Now(),
+		}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %v", err)
+	}
+	return nil
+}
+
+// UnlinkUser removes every identity linked to the student.
+func (r *IdentityRepository) UnlinkUser(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"userID": userID}); err != nil {
+		return fmt.Errorf("failed to unlink identities: %v", err)
+	}
+	return nil
+}
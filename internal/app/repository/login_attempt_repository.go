@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	accountFailuresPrefix = "login_failures:account:"
+	ipFailuresPrefix      = "login_failures:ip:"
+	accountLockPrefix     = "login_lock:account:"
+	ipLockPrefix          = "login_lock:ip:"
+)
+
+// LoginAttemptRepository counts failed sign-ins per account and per client
+// IP inside a sliding expiry window and keeps temporary locks.
+type LoginAttemptRepository struct {
+	cache  *redis.Client
+	logger *logrus.Logger
+}
+
+func NewLoginAttemptRepository(cache *redis.Client, logger *logrus.Logger) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// RecordFailure counts a failed sign-in and returns the failures of the
+// account and of the IP inside the window.
+func (r *LoginAttemptRepository) RecordFailure(account, ip string, window time.Duration) (int64, int64, error) {
+	var accountFailures, ipFailures *redis.IntCmd
+	_, err := r.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		accountFailures = pipe.Incr(accountFailuresPrefix + account)
+		pipe.Expire(accountFailuresPrefix+account, window)
+		ipFailures = pipe.Incr(ipFailuresPrefix + ip)
+		pipe.Expire(ipFailuresPrefix+ip, window)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return accountFailures.Val(), ipFailures.Val(), nil
+}
+
+func (r *LoginAttemptRepository) Failures(account string) (int64, error) {
+	failures, err := r.cache.Get(accountFailuresPrefix + account).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return failures, err
+}
+
+func (r *LoginAttemptRepository) LockAccount(account string, duration time.Duration) error {
+	return r.cache.Set(accountLockPrefix+account, 1, duration).Err()
+}
+
+func (r *LoginAttemptRepository) LockIP(ip string, duration time.Duration) error {
+	return r.cache.Set(ipLockPrefix+ip, 1, duration).Err()
+}
+
+// LockedFor returns how long sign-ins for the account or from the IP stay
+// locked, or zero when neither is locked.
+func (r *LoginAttemptRepository) LockedFor(account, ip string) (time.Duration, error) {
+	var accountTTL, ipTTL *redis.DurationCmd
+	_, err := r.cache.Pipelined(func(pipe redis.Pipeliner) error {
+		accountTTL = pipe.PTTL(accountLockPrefix + account)
+		ipTTL = pipe.PTTL(ipLockPrefix + ip)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	lockedFor := accountTTL.Val()
+	if ipTTL.Val() > lockedFor {
+		lockedFor = ipTTL.Val()
+	}
+	if lockedFor < 0 {
+		return 0, nil
+	}
+	return lockedFor, nil
+}
+
+// ResetAccount forgets the failures of an account after a successful
+// sign-in. IP counters are kept, so one valid account cannot be used to
+// reset the counter of an IP guessing passwords for others.
+func (r *LoginAttemptRepository) ResetAccount(account string) error {
+	return r.cache.Del(accountFailuresPrefix + account).Err()
+}
+
+// UnlockAccount lifts the lock of an account and forgets its failures.
+func (r *LoginAttemptRepository) UnlockAccount(account string) error {
+	return r.cache.Del(accountLockPrefix+account, accountFailuresPrefix+account).Err()
+}
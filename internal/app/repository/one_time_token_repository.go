@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/sirupsen/logrus"
+)
+
+const oneTimeTokenPrefix = "one_time_token:"
+
+const issueCountPrefix = "one_time_token_issues:"
+
+var ErrOneTimeTokenNotFound = apperror.NotFound("one_time_token_not_found", "one-time token not found or expired")
+
+// OneTimeTokenRepository stores single-use tokens, such as password reset
+// tokens, by the hash of the token. Each subject holds at most one token
+// per purpose, so issuing a new one invalidates the previous one.
+type OneTimeTokenRepository struct {
+	cache  *redis.Client
+	logger *logrus.Logger
+}
+
+func NewOneTimeTokenRepository(cache *redis.Client, logger *logrus.Logger) *OneTimeTokenRepository {
+	return &OneTimeTokenRepository{
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+func (r *OneTimeTokenRepository) Save(purpose, subject, hash, value string, ttl time.Duration) error {
+	subjectKey := oneTimeTokenPrefix + purpose + ":subject:" + subject
+	previous, err := r.cache.Get(subjectKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	_, err = r.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(oneTimeTokenPrefix + purpose + ":" + previous)
+		}
+		pipe.Set(oneTimeTokenPrefix+purpose+":"+hash, value, ttl)
+		pipe.Set(subjectKey, hash, ttl)
+		return nil
+	})
+	return err
+}
+
+// Get returns the value stored for the token without redeeming it.
+func (r *OneTimeTokenRepository) Get(purpose, hash string) (string, error) {
+	value, err := r.cache.Get(oneTimeTokenPrefix + purpose + ":" + hash).Result()
+	if err == redis.Nil {
+		return "", ErrOneTimeTokenNotFound
+	}
+	return value, err
+}
+
+// Consume returns the value stored for the token and deletes the token in
+// the same transaction, so a token can be redeemed only once.
+func (r *OneTimeTokenRepository) Consume(purpose, hash string) (string, error) {
+	key := oneTimeTokenPrefix + purpose + ":" + hash
+	var get *redis.StringCmd
+	_, err := r.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", ErrOneTimeTokenNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+// ReserveIssue allows issuing a token to the subject at most once per
+// cooldown and maxIssues times per window. It returns zero when issuing is
+// allowed, and otherwise how long the subject has to wait.
+func (r *OneTimeTokenRepository) ReserveIssue(purpose, subject string, cooldown time.Duration, maxIssues int64, window time.Duration) (time.Duration, error) {
+	cooldownKey := issueCountPrefix + purpose + ":cooldown:" + subject
+	countKey := issueCountPrefix + purpose + ":count:" + subject
+
+	reserved, err := r.cache.SetNX(cooldownKey, 1, cooldown).Result()
+	if err != nil {
+		return 0, err
+	}
+	if !reserved {
+		ttl, err := r.cache.PTTL(cooldownKey).Result()
+		if err != nil {
+			return 0, err
+		}
+		return ttl, nil
+	}
+
+	count, err := r.cache.Incr(countKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		r.cache.Expire(countKey, window)
+	}
+	if count > maxIssues {
+		ttl, err := r.cache.PTTL(countKey).Result()
+		if err != nil {
+			return 0, err
+		}
+		return ttl, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOneTimeTokenRepository(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer cache.Close()
+	r := NewOneTimeTokenRepository(cache, logrus.New())
+
+	require.NoError(t, r.Save("password_reset", "student-1", "hash-1", "student-1", time.Minute))
+
+	value, err := r.Consume("password_reset", "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "student-1", value)
+
+	_, err = r.Consume("password_reset", "hash-1")
+	assert.Equal(t, ErrOneTimeTokenNotFound, err, "tokens are single-use")
+
+	require.NoError(t, r.Save("password_reset", "student-1", "hash-2", "student-1", time.Minute))
+	require.NoError(t, r.Save("password_reset", "student-1", "hash-3", "student-1", time.Minute))
+	_, err = r.Consume("password_reset", "hash-2")
+	assert.Equal(t, ErrOneTimeTokenNotFound, err, "a new token replaces the previous one")
+
+	server.FastForward(2 * time.Minute)
+	_, err = r.Consume("password_reset", "hash-3")
+	assert.Equal(t, ErrOneTimeTokenNotFound, err, "tokens expire")
+}
+
+func TestOneTimeTokenRepositoryReserveIssue(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer cache.Close()
+	r := NewOneTimeTokenRepository(cache, logrus.New())
+
+	retryAfter, err := r.ReserveIssue("email_verification", "student-1", time.Minute, 2, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	retryAfter, err = r.ReserveIssue("email_verification", "student-1", time.Minute, 2, time.Hour)
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, time.Duration(0), "cooldown applies between issues")
+
+	server.FastForward(2 * time.Minute)
+	retryAfter, err = r.ReserveIssue("email_verification", "student-1", time.Minute, 2, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	server.FastForward(2 * time.Minute)
+	retryAfter, err = r.ReserveIssue("email_verification", "student-1", time.Minute, 2, time.Hour)
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, time.Minute, "issues are capped per window")
+}
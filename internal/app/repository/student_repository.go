@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/internal/app/apperror"
 	"github.com/nurmeden/students-service/internal/app/model"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,6 +15,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrStudentNotFound = apperror.NotFound("student_not_found", "student not found")
+
 type StudentRepository struct {
 	client     *mongo.Client
 	collection *mongo.Collection
@@ -35,7 +38,7 @@ func NewStudentRepository(client *mongo.Client, dbName string, collectionName st
 }
 
 func (r *StudentRepository) CreateStudent(ctx context.Context, student *model.Student) (*model.Student, error) {
-	r.logger.Infof("Creating new student: %+v", student)
+	r.logger.WithContext(ctx).Infof("Creating new student: %+v", student)
 
 	err := r.client.Ping(ctx, nil)
 	if err != nil {
@@ -43,7 +46,7 @@ func (r *StudentRepository) CreateStudent(ctx context.Context, student *model.St
 	}
 	result, err := r.collection.InsertOne(ctx, student)
 	if err != nil {
-		r.logger.Errorf("Failed to create student: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to create student: %v", err)
 		return nil, fmt.Errorf("failed to create student: %v", err)
 	}
 	insertedID, ok := result.InsertedID.(primitive.ObjectID)
@@ -51,20 +54,21 @@ func (r *StudentRepository) CreateStudent(ctx context.Context, student *model.St
 		return nil, fmt.Errorf("failed to convert InsertedID to ObjectID")
 	}
 	insertedIDStr := insertedID.Hex()
+	student.ID = insertedID
 
 	studentJSON, err := json.Marshal(student)
 	if err != nil {
-		r.logger.Errorf("Failed to marshal student data for caching: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to marshal student data for caching: %v", err)
 		return nil, err
 	}
 
 	err = r.cache.Set(insertedIDStr, studentJSON, 0).Err()
 	if err != nil {
-		r.logger.Errorf("Failed to cache student data: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to cache student data: %v", err)
 		return nil, err
 	}
 
-	r.logger.Infof("Student created successfully")
+	r.logger.WithContext(ctx).Infof("Student created successfully")
 
 	return student, nil
 }
@@ -75,40 +79,41 @@ func (r *StudentRepository) GetStudentByID(ctx context.Context, id string) (*mod
 		student := &model.Student{}
 		err = json.Unmarshal([]byte(cachedResult), student)
 		if err != nil {
-			r.logger.Errorf("Error unmarshalling cached result for student with ID %s: %s", id, err)
+			r.logger.WithContext(ctx).Errorf("Error unmarshalling cached result for student with ID %s: %s", id, err)
 			return nil, err
 		}
 		return student, nil
 	} else if err != redis.Nil {
-		r.logger.Errorf("Error getting cached result for student with ID %s: %s", id, err)
+		r.logger.WithContext(ctx).Errorf("Error getting cached result for student with ID %s: %s", id, err)
 		return nil, err
 	}
 
 	var student model.Student
 	studentId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		r.logger.Errorf("Invalid ID")
+		r.logger.WithContext(ctx).Infof("Invalid student ID %q", id)
+		return nil, nil
 	}
 	filter := bson.M{"_id": studentId}
 	err = r.collection.FindOne(ctx, filter).Decode(&student)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			r.logger.Infof("Student with id %s not found in database", id)
+			r.logger.WithContext(ctx).Infof("Student with id %s not found in database", id)
 			return nil, nil // Если студент не найден, возвращаем nil и ошибку nil
 		}
-		r.logger.Errorf("failed to read student: %v", err)
+		r.logger.WithContext(ctx).Errorf("failed to read student: %v", err)
 		return nil, fmt.Errorf("failed to read student: %v", err)
 	}
 
 	studentJSON, err := json.Marshal(student)
 	if err != nil {
-		r.logger.Errorf("Failed to marshal student data for caching: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to marshal student data for caching: %v", err)
 		return nil, err
 	}
 
 	err = r.cache.Set(id, studentJSON, 0).Err()
 	if err != nil {
-		r.logger.Errorf("Failed to cache student data: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to cache student data: %v", err)
 		return nil, err
 	}
 
@@ -117,13 +122,11 @@ func (r *StudentRepository) GetStudentByID(ctx context.Context, id string) (*mod
 
 func (r *StudentRepository) GetStudentsByCourseID(ctx context.Context, id string) ([]*model.Student, error) {
 	cachedResult, err := r.cache.Get(id).Result()
-	fmt.Printf("id in the course id: %v\n", id)
 	if err == nil {
 		students := []*model.Student{}
-		fmt.Printf("students in the err: %v\n", students)
 		err = json.Unmarshal([]byte(cachedResult), &students)
 		if err != nil {
-			r.logger.Errorf("Error unmarshalling cached result for students with course ID %s: %s", id, err)
+			r.logger.WithContext(ctx).Errorf("Error unmarshalling cached result for students with course ID %s: %s", id, err)
 			return nil, err
 		}
 		return students, nil
@@ -133,7 +136,6 @@ func (r *StudentRepository) GetStudentsByCourseID(ctx context.Context, id string
 
 	filter := bson.M{"courses": bson.M{"$in": []string{id}}}
 	cursor, err := r.collection.Find(ctx, filter)
-	fmt.Printf("cursor: %v\n", cursor)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query students: %v", err)
 	}
@@ -141,38 +143,125 @@ func (r *StudentRepository) GetStudentsByCourseID(ctx context.Context, id string
 	for cursor.Next(ctx) {
 		var student model.Student
 		if err := cursor.Decode(&student); err != nil {
-			r.logger.Errorf("Error decoding student: %s", err)
+			r.logger.WithContext(ctx).Errorf("Error decoding student: %s", err)
 			continue
 		}
-		fmt.Printf("student following: %v\n", student)
 		students = append(students, &student)
 	}
-	fmt.Printf("students: %v\n", students)
 
 	if err := cursor.Err(); err != nil {
 		return nil, fmt.Errorf("cursor error: %v", err)
 	}
 
 	if len(students) == 0 {
-		r.logger.Infof("No students found for course ID %s in database", id)
+		r.logger.WithContext(ctx).Infof("No students found for course ID %s in database", id)
 		return nil, nil
 	}
 
 	studentsJSON, err := json.Marshal(students)
 	if err != nil {
-		r.logger.Errorf("Failed to marshal students data for caching: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to marshal students data for caching: %v", err)
 		return nil, err
 	}
 
 	err = r.cache.Set(id, studentsJSON, 0).Err()
 	if err != nil {
-		r.logger.Errorf("Failed to cache students data: %v", err)
+		r.logger.WithContext(ctx).Errorf("Failed to cache students data: %v", err)
 		return nil, err
 	}
 
 	return students, nil
 }
 
+// GetStudentsByIDs returns the students with the given IDs, from the cache
+// where it has them and from the database otherwise, in no particular
+// order. Unknown IDs are left out.
+func (r *StudentRepository) GetStudentsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*model.Student, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.Hex()
+	}
+	cached, err := r.cache.MGet(keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached students: %v", err)
+	}
+
+	students := make([]*model.Student, 0, len(ids))
+	var missing []primitive.ObjectID
+	for i, value := range cached {
+		data, ok := value.(string)
+		if !ok {
+			missing = append(missing, ids[i])
+			continue
+		}
+		student := &model.Student{}
+		if err := json.Unmarshal([]byte(data), student); err != nil {
+			r.logger.WithContext(ctx).Errorf("Error unmarshalling cached result for student with ID %s: %s", keys[i], err)
+			missing = append(missing, ids[i])
+			continue
+		}
+		students = append(students, student)
+	}
+	if len(missing) == 0 {
+		return students, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": missing}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query students: %v", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var student model.Student
+		if err := cursor.Decode(&student); err != nil {
+			return nil, fmt.Errorf("failed to decode student: %v", err)
+		}
+		students = append(students, &student)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %v", err)
+	}
+	return students, nil
+}
+
+// GetStudentsByCourseIDs returns the students enrolled in each of the given
+// courses in one query, by course ID. Courses nobody is enrolled in are
+// left out.
+func (r *StudentRepository) GetStudentsByCourseIDs(ctx context.Context, courseIDs []string) (map[string][]*model.Student, error) {
+	rosters := make(map[string][]*model.Student, len(courseIDs))
+	if len(courseIDs) == 0 {
+		return rosters, nil
+	}
+	wanted := make(map[string]bool, len(courseIDs))
+	for _, id := range courseIDs {
+		wanted[id] = true
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"courses": bson.M{"$in": courseIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query students: %v", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		student := &model.Student{}
+		if err := cursor.Decode(student); err != nil {
+			return nil, fmt.Errorf("failed to decode student: %v", err)
+		}
+		for _, courseID := range student.Courses {
+			if wanted[courseID] {
+				rosters[courseID] = append(rosters[courseID], student)
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %v", err)
+	}
+	return rosters, nil
+}
+
 func (r *StudentRepository) UpdateStudents(ctx context.Context, student *model.Student, studentID primitive.ObjectID) (*model.Student, error) {
 	filter := bson.M{"_id": studentID}
 	update := bson.M{"$set": bson.M{
@@ -181,16 +270,13 @@ func (r *StudentRepository) UpdateStudents(ctx context.Context, student *model.S
 		"age":       student.Age,
 		"courses":   student.Courses,
 	}}
-	fmt.Printf("student.Courses: %v\n", student.Courses)
-	fmt.Printf("student.ID: %v\n", studentID)
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
 	result := r.collection.FindOneAndUpdate(ctx, filter, update, opts)
 	if err := result.Err(); err != nil {
-		fmt.Printf("err.Error(): %v\n", err.Error())
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("student not found")
+			return nil, ErrStudentNotFound
 		}
 		return nil, err
 	}
@@ -207,18 +293,124 @@ func (r *StudentRepository) UpdateStudents(ctx context.Context, student *model.S
 
 	err = r.cache.Set(studentID.Hex(), studentJSON, 0).Err()
 	if err != nil {
-		fmt.Printf("Failed to update student data in Redis: %v\n", err)
+		r.logger.WithContext(ctx).Errorf("Failed to update student data in Redis: %s", err)
+	}
+
+	return updatedStudent, nil
+}
+
+func (r *StudentRepository) UpdateRole(ctx context.Context, studentID primitive.ObjectID, role string) (*model.Student, error) {
+	filter := bson.M{"_id": studentID}
+	update := bson.M{"$set": bson.M{"role": role}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updatedStudent *model.Student
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedStudent); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrStudentNotFound
+		}
+		return nil, err
+	}
+
+	if err := r.cache.Del(studentID.Hex()).Err(); err != nil {
+		r.logger.WithContext(ctx).Errorf("Failed to invalidate cached student %s: %v", studentID.Hex(), err)
+	}
+
+	return updatedStudent, nil
+}
+
+// AddCourse enrolls the student in the course and returns the updated
+// student. Enrolling twice changes nothing.
+func (r *StudentRepository) AddCourse(ctx context.Context, studentID primitive.ObjectID, courseID string) (*model.Student, error) {
+	return r.updateCourses(ctx, studentID, courseID, bson.M{"$addToSet": bson.M{"courses": courseID}})
+}
+
+// RemoveCourse takes the course from the courses of the student and
+// returns the updated student.
+func (r *StudentRepository) RemoveCourse(ctx context.Context, studentID primitive.ObjectID, courseID string) (*model.Student, error) {
+	return r.updateCourses(ctx, studentID, courseID, bson.M{"$pull": bson.M{"courses": courseID}})
+}
+
+// updateCourses applies an update of the courses and drops the cached
+// student and the cached roster of the course.
+func (r *StudentRepository) updateCourses(ctx context.Context, studentID primitive.ObjectID, courseID string, update bson.M) (*model.Student, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updatedStudent *model.Student
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": studentID}, update, opts).Decode(&updatedStudent); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrStudentNotFound
+		}
+		return nil, fmt.Errorf("failed to update courses: %v", err)
 	}
 
+	if err := r.cache.Del(studentID.Hex(), courseID).Err(); err != nil {
+		r.logger.WithContext(ctx).Errorf("Failed to invalidate cached student %s and roster of course %s: %v", studentID.Hex(), courseID, err)
+	}
 	return updatedStudent, nil
 }
 
+func (r *StudentRepository) UpdatePassword(ctx context.Context, studentID primitive.ObjectID, passwordHash string) error {
+	return r.updateFields(ctx, studentID, bson.M{"password": passwordHash})
+}
+
+// UpdateProfile sets the given profile fields and returns the updated
+// student.
+func (r *StudentRepository) UpdateProfile(ctx context.Context, studentID primitive.ObjectID, fields bson.M) (*model.Student, error) {
+	if len(fields) > 0 {
+		if err := r.updateFields(ctx, studentID, fields); err != nil {
+			return nil, err
+		}
+	}
+	return r.GetStudentByID(ctx, studentID.Hex())
+}
+
+func (r *StudentRepository) SetPendingEmail(ctx context.Context, studentID primitive.ObjectID, email string) error {
+	return r.updateFields(ctx, studentID, bson.M{"pendingEmail": email})
+}
+
+// MarkEmailVerified stores email as the verified address of the student and
+// clears a pending email change.
+func (r *StudentRepository) MarkEmailVerified(ctx context.Context, studentID primitive.ObjectID, email string) error {
+	return r.updateFields(ctx, studentID, bson.M{
+		"email":         email,
+		"emailVerified": true,
+		"pendingEmail":  "",
+	})
+}
+
+func (r *StudentRepository) updateFields(ctx context.Context, studentID primitive.ObjectID, fields bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": studentID}, bson.M{"$set": fields})
+	if err != nil {
+		return fmt.Errorf("failed to update student: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrStudentNotFound
+	}
+
+	if err := r.cache.Del(studentID.Hex()).Err(); err != nil {
+		r.logger.WithContext(ctx).Errorf("Failed to invalidate cached student %s: %v", studentID.Hex(), err)
+	}
+	return nil
+}
+
 func (r *StudentRepository) Delete(ctx context.Context, id string) error {
-	filter := bson.M{"_id": id}
-	_, err := r.collection.DeleteOne(ctx, filter)
+	studentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrStudentNotFound
+	}
+	filter := bson.M{"_id": studentID}
+	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return fmt.Errorf("failed to delete student: %v", err)
 	}
+	if result.DeletedCount == 0 {
+		return ErrStudentNotFound
+	}
+
+	if err := r.cache.Del(id).Err(); err != nil {
+		r.logger.WithContext(ctx).Errorf("Failed to invalidate cached student %s: %v", id, err)
+	}
 	return nil
 }
 
@@ -233,7 +425,6 @@ func (r *StudentRepository) GetByEmail(ctx context.Context, email string) (*mode
 		}
 		return nil, err
 	}
-	fmt.Printf("student: %v\n", student)
 
 	return &student, nil
 }
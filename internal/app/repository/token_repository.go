@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	refreshTokenPrefix   = "refresh_token:"
+	refreshFamilyPrefix  = "refresh_family:"
+	familyTokensPrefix   = "refresh_family_tokens:"
+	userFamiliesPrefix   = "user_refresh_families:"
+	deniedTokenPrefix    = "access_denylist:jti:"
+	deniedSessionPrefix  = "access_denylist:sid:"
+	revokedBeforePrefix  = "access_revoked_before:"
+	refreshFieldUserID   = "userID"
+	refreshFieldFamilyID = "familyID"
+	refreshFieldUses     = "uses"
+)
+
+var ErrRefreshTokenNotFound = apperror.NotFound("refresh_token_not_found", "refresh token not found")
+
+// RefreshToken is the server-side record of an opaque refresh token. Only
+// the hash of the token is ever stored.
+type RefreshToken struct {
+	Hash     string
+	UserID   string
+	FamilyID string
+	Used     bool
+	// ExpiresAt is only set by GetRefreshToken.
+	ExpiresAt time.Time
+}
+
+// RefreshFamily groups every refresh token that descends from one sign-in,
+// which makes it the record of a session. UserAgent and IP are those of
+// the sign-in.
+type RefreshFamily struct {
+	ID            string
+	UserID        string
+	UserAgent     string
+	IP            string
+	CreatedAt     time.Time
+	LastRefreshAt time.Time
+}
+
+type TokenRepository struct {
+	cache  *redis.Client
+	logger *logrus.Logger
+}
+
+func NewTokenRepository(cache *redis.Client, logger *logrus.Logger) *TokenRepository {
+	return &TokenRepository{
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// CreateFamily starts a new refresh-token family for a sign-in.
+func (r *TokenRepository) CreateFamily(family *RefreshFamily, ttl time.Duration) error {
+	_, err := r.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HMSet(refreshFamilyPrefix+family.ID, map[string]interface{}{
+			"userID":        family.UserID,
+			"userAgent":     family.UserAgent,
+			"ip":            family.IP,
+			"createdAt":     family.CreatedAt.Unix(),
+			"lastRefreshAt": family.LastRefreshAt.Unix(),
+		})
+		pipe.Expire(refreshFamilyPrefix+family.ID, ttl)
+		pipe.SAdd(userFamiliesPrefix+family.UserID, family.ID)
+		pipe.Expire(userFamiliesPrefix+family.UserID, ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *TokenRepository) GetFamily(familyID string) (*RefreshFamily, error) {
+	fields, err := r.cache.HGetAll(refreshFamilyPrefix + familyID).Result()
+	if err != nil {
+		return nil, err
+	}
+	if fields["userID"] == "" {
+		return nil, ErrRefreshTokenNotFound
+	}
+	createdAt, _ := strconv.ParseInt(fields["createdAt"], 10, 64)
+	lastRefreshAt, _ := strconv.ParseInt(fields["lastRefreshAt"], 10, 64)
+	return &RefreshFamily{
+		ID:            familyID,
+		UserID:        fields["userID"],
+		UserAgent:     fields["userAgent"],
+		IP:            fields["ip"],
+		CreatedAt:     time.Unix(createdAt, 0),
+		LastRefreshAt: time.Unix(lastRefreshAt, 0),
+	}, nil
+}
+
+// saveRefreshTokenScript stores a token only while its family exists, in
+// one step, so a refresh racing with the revocation of the family does not
+// bring the session back or recreate the family without its fields.
+var saveRefreshTokenScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[3], ARGV[1]) == 0 then
+	return false
+end
+redis.call("HMSET", KEYS[1], ARGV[1], ARGV[4], ARGV[2], ARGV[5], ARGV[3], 0)
+redis.call("PEXPIRE", KEYS[1], ARGV[6])
+redis.call("SADD", KEYS[2], ARGV[7])
+redis.call("PEXPIRE", KEYS[2], ARGV[6])
+redis.call("HSET", KEYS[3], "lastRefreshAt", ARGV[8])
+redis.call("PEXPIRE", KEYS[3], ARGV[6])
+redis.call("PEXPIRE", KEYS[4], ARGV[6])
+return 1
+`)
+
+// SaveRefreshToken stores a new token of an existing family and extends the
+// lifetime of the family to the lifetime of its newest token. It returns
+// ErrRefreshTokenNotFound when the family has been revoked.
+func (r *TokenRepository) SaveRefreshToken(token *RefreshToken, ttl time.Duration) error {
+	keys := []string{
+		refreshTokenPrefix + token.Hash,
+		familyTokensPrefix + token.FamilyID,
+		refreshFamilyPrefix + token.FamilyID,
+		userFamiliesPrefix + token.UserID,
+	}
+	err := saveRefreshTokenScript.Run(r.cache, keys,
+		refreshFieldUserID, refreshFieldFamilyID, refreshFieldUses,
+		token.UserID, token.FamilyID, ttl.Milliseconds(), token.Hash, time.Now().Unix(),
+	).Err()
+	if err == redis.Nil {
+		return ErrRefreshTokenNotFound
+	}
+	return err
+}
+
+// useRefreshTokenScript counts a use of the token if it still exists, in
+// one step, so a token revoked or expired meanwhile is not recreated
+// without a TTL. It returns the user ID, the family ID and the uses.
+var useRefreshTokenScript = redis.NewScript(`
+local userID = redis.call("HGET", KEYS[1], ARGV[1])
+local familyID = redis.call("HGET", KEYS[1], ARGV[2])
+if not userID or not familyID or userID == "" or familyID == "" then
+	return false
+end
+local uses = redis.call("HINCRBY", KEYS[1], ARGV[3], 1)
+return {userID, familyID, uses}
+`)
+
+// UseRefreshToken marks the token as used and returns its record. Used is
+// true when the token had already been used before, which means it was
+// replayed. Marking is atomic, so two concurrent refreshes with the same
+// token cannot both succeed.
+func (r *TokenRepository) UseRefreshToken(hash string) (*RefreshToken, error) {
+	result, err := useRefreshTokenScript.Run(r.cache, []string{refreshTokenPrefix + hash}, refreshFieldUserID, refreshFieldFamilyID, refreshFieldUses).Result()
+	if err == redis.Nil {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 3 {
+		return nil, fmt.Errorf("unexpected result of refresh token use: %v", result)
+	}
+	userID, _ := values[0].(string)
+	familyID, _ := values[1].(string)
+	uses, _ := values[2].(int64)
+
+	return &RefreshToken{
+		Hash:     hash,
+		UserID:   userID,
+		FamilyID: familyID,
+		Used:     uses > 1,
+	}, nil
+}
+
+// GetRefreshToken returns the record of the token without using it.
+func (r *TokenRepository) GetRefreshToken(hash string) (*RefreshToken, error) {
+	var fieldsCmd *redis.StringStringMapCmd
+	var ttlCmd *redis.DurationCmd
+	_, err := r.cache.Pipelined(func(pipe redis.Pipeliner) error {
+		fieldsCmd = pipe.HGetAll(refreshTokenPrefix + hash)
+		ttlCmd = pipe.TTL(refreshTokenPrefix + hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fields := fieldsCmd.Val()
+	if fields[refreshFieldUserID] == "" || fields[refreshFieldFamilyID] == "" {
+		return nil, ErrRefreshTokenNotFound
+	}
+	uses, _ := strconv.Atoi(fields[refreshFieldUses])
+	token := &RefreshToken{
+		Hash:     hash,
+		UserID:   fields[refreshFieldUserID],
+		FamilyID: fields[refreshFieldFamilyID],
+		Used:     uses > 0,
+	}
+	if ttl := ttlCmd.Val(); ttl > 0 {
+		token.ExpiresAt = time.Now().Add(ttl)
+	}
+	return token, nil
+}
+
+// RevokeFamily deletes the family together with every token issued in it.
+func (r *TokenRepository) RevokeFamily(familyID string) error {
+	family, err := r.GetFamily(familyID)
+	if err != nil && err != ErrRefreshTokenNotFound {
+		return err
+	}
+
+	hashes, err := r.cache.SMembers(familyTokensPrefix + familyID).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		for _, hash := range hashes {
+			pipe.Del(refreshTokenPrefix + hash)
+		}
+		pipe.Del(familyTokensPrefix+familyID, refreshFamilyPrefix+familyID)
+		if family != nil {
+			pipe.SRem(userFamiliesPrefix+family.UserID, familyID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.Infof("Revoked refresh token family %s", familyID)
+	return nil
+}
+
+func (r *TokenRepository) ListFamilyIDs(userID string) ([]string, error) {
+	return r.cache.SMembers(userFamiliesPrefix + userID).Result()
+}
+
+// ListFamilies returns the live families of the student. IDs of families
+// that expired are dropped from the student's set on the way.
+func (r *TokenRepository) ListFamilies(userID string) ([]*RefreshFamily, error) {
+	familyIDs, err := r.ListFamilyIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	families := make([]*RefreshFamily, 0, len(familyIDs))
+	for _, familyID := range familyIDs {
+		family, err := r.GetFamily(familyID)
+		if err == ErrRefreshTokenNotFound {
+			r.cache.SRem(userFamiliesPrefix+userID, familyID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, family)
+	}
+	return families, nil
+}
+
+// DenyToken rejects the access token with the given jti until it would have
+// expired anyway.
+func (r *TokenRepository) DenyToken(jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.cache.Set(deniedTokenPrefix+jti, 1, ttl).Err()
+}
+
+// DenySession rejects every access token issued for the session.
+func (r *TokenRepository) DenySession(sessionID string, ttl time.Duration) error {
+	return r.cache.Set(deniedSessionPrefix+sessionID, 1, ttl).Err()
+}
+
+// RevokeUserTokensBefore rejects every access token of the user issued
+// before the second of t. Access tokens carry their issue time in whole
+// seconds, so tokens of that second are let through, or a session started
+// right after the revocation would be rejected; callers deny the sessions
+// they end to reject the older tokens of that second.
+func (r *TokenRepository) RevokeUserTokensBefore(userID string, t time.Time, ttl time.Duration) error {
+	return r.cache.Set(revokedBeforePrefix+userID, t.Unix(), ttl).Err()
+}
+
+// IsAccessTokenRevoked reports whether an access token was revoked by jti,
+// by session or by a revocation of all tokens of its user.
+func (r *TokenRepository) IsAccessTokenRevoked(jti, sessionID, userID string, issuedAt time.Time) (bool, error) {
+	var deniedToken, deniedSession *redis.IntCmd
+	var revokedBefore *redis.StringCmd
+	_, err := r.cache.Pipelined(func(pipe redis.Pipeliner) error {
+		deniedToken = pipe.Exists(deniedTokenPrefix + jti)
+		deniedSession = pipe.Exists(deniedSessionPrefix + sessionID)
+		revokedBefore = pipe.Get(revokedBeforePrefix + userID)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if deniedToken.Val() > 0 || deniedSession.Val() > 0 {
+		return true, nil
+	}
+	if before, err := revokedBefore.Int64(); err == nil && issuedAt.Unix() < before {
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTokenRepository(t *testing.T) (*TokenRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { cache.Close() })
+	return NewTokenRepository(cache, logrus.New()), server
+}
+
+func TestTokenRepository_UseRefreshToken(t *testing.T) {
+	r, server := newTestTokenRepository(t)
+	require.NoError(t, r.CreateFamily(&RefreshFamily{ID: "f1", UserID: "s1", CreatedAt: time.Now()}, time.Hour))
+	require.NoError(t, r.SaveRefreshToken(&RefreshToken{Hash: "h1", UserID: "s1", FamilyID: "f1"}, time.Hour))
+
+	token, err := r.UseRefreshToken("h1")
+	require.NoError(t, err)
+	assert.Equal(t, &RefreshToken{Hash: "h1", UserID: "s1", FamilyID: "f1"}, token)
+
+	token, err = r.UseRefreshToken("h1")
+	require.NoError(t, err)
+	assert.True(t, token.Used, "a second use is a replay")
+	assert.Equal(t, time.Hour, server.TTL(refreshTokenPrefix+"h1"), "uses keep the TTL")
+
+	require.NoError(t, r.RevokeFamily("f1"))
+	_, err = r.UseRefreshToken("h1")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+	assert.False(t, server.Exists(refreshTokenPrefix+"h1"), "a revoked token is not recreated")
+}
+
+func TestTokenRepository_SaveRefreshToken(t *testing.T) {
+	r, server := newTestTokenRepository(t)
+	createdAt := time.Unix(1700000000, 0)
+	require.NoError(t, r.CreateFamily(&RefreshFamily{ID: "f1", UserID: "s1", CreatedAt: createdAt, LastRefreshAt: createdAt}, time.Minute))
+
+	require.NoError(t, r.SaveRefreshToken(&RefreshToken{Hash: "h1", UserID: "s1", FamilyID: "f1"}, time.Hour))
+	assert.Equal(t, time.Hour, server.TTL(refreshFamilyPrefix+"f1"), "the family lives as long as its newest token")
+	family, err := r.GetFamily("f1")
+	require.NoError(t, err)
+	assert.True(t, family.LastRefreshAt.After(createdAt))
+
+	require.NoError(t, r.RevokeFamily("f1"))
+	err = r.SaveRefreshToken(&RefreshToken{Hash: "h2", UserID: "s1", FamilyID: "f1"}, time.Hour)
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+	assert.False(t, server.Exists(refreshFamilyPrefix+"f1"), "a revoked family is not recreated")
+	assert.False(t, server.Exists(refreshTokenPrefix+"h2"), "no token is stored for a revoked family")
+}
+
+func TestTokenRepository_RevokeUserTokensBefore(t *testing.T) {
+	r, _ := newTestTokenRepository(t)
+	revokedAt := time.Unix(1700000000, 500*int64(time.Millisecond))
+	require.NoError(t, r.RevokeUserTokensBefore("s1", revokedAt, time.Hour))
+
+	revoked, err := r.IsAccessTokenRevoked("jti-1", "sid-1", "s1", time.Unix(1699999999, 0))
+	require.NoError(t, err)
+	assert.True(t, revoked, "tokens of earlier seconds are revoked")
+
+	revoked, err = r.IsAccessTokenRevoked("jti-2", "sid-2", "s1", time.Unix(1700000000, 0))
+	require.NoError(t, err)
+	assert.False(t, revoked, "tokens issued in the second of the revocation pass")
+
+	revoked, err = r.IsAccessTokenRevoked("jti-3", "sid-3", "s2", time.Unix(1699999999, 0))
+	require.NoError(t, err)
+	assert.False(t, revoked, "other users are unaffected")
+}
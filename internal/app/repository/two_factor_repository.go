@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	twoFactorCollection = "two_factor"
+	settingsCollection  = "settings"
+	twoFactorPolicyID   = "two_factor_policy"
+)
+
+// TwoFactor is the TOTP enrollment of a student. It is kept out of the
+// student document so the secret never reaches the student cache or API
+// responses. RecoveryCodes holds SHA-256 hashes of the unused codes.
+type TwoFactor struct {
+	UserID        primitive.ObjectID `bson:"_id"`
+	Secret        string             `bson:"secret,omitempty"`
+	PendingSecret string             `bson:"pendingSecret,omitempty"`
+	Enabled       bool               `bson:"enabled"`
+	RecoveryCodes []string           `bson:"recoveryCodes,omitempty"`
+	LastUsedStep  int64              `bson:"lastUsedStep"`
+	EnabledAt     time.Time          `bson:"enabledAt,omitempty"`
+}
+
+type twoFactorPolicy struct {
+	ID            string   `bson:"_id"`
+	RequiredRoles []string `bson:"requiredRoles"`
+}
+
+type TwoFactorRepository struct {
+	collection *mongo.Collection
+	settings   *mongo.Collection
+	logger     *logrus.Logger
+}
+
+func NewTwoFactorRepository(client *mongo.Client, dbName string, logger *logrus.Logger) *TwoFactorRepository {
+	db := client.Database(dbName)
+	return &TwoFactorRepository{
+		collection: db.Collection(twoFactorCollection),
+		settings:   db.Collection(settingsCollection),
+		logger:     logger,
+	}
+}
+
+// Get returns the enrollment of the student, or nil if they never started
+// one.
+func (r *TwoFactorRepository) Get(ctx context.Context, userID primitive.ObjectID) (*TwoFactor, error) {
+	var twoFactor TwoFactor
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&twoFactor)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor enrollment: %v", err)
+	}
+	return &twoFactor, nil
+}
+
+// SetPendingSecret stores a secret that becomes active once the student
+// confirms it with a code. An enabled secret stays in effect until then.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID primitive.ObjectID, secret string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"pendingSecret": secret}, "$setOnInsert": bson.M{"enabled": false, "lastUsedStep": int64(0)}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to store two-factor secret: %v", err)
+	}
+	return nil
+}
+
+// Enable activates the pending secret, provided it is still the one the
+// code was checked against, and replaces the recovery codes.
+func (r *TwoFactorRepository) Enable(ctx context.Context, userID primitive.ObjectID, secret string, step int64, recoveryCodes []string) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "pendingSecret": secret},
+		bson.M{
+			"$set": bson.M{
+				"secret":        secret,
+				"enabled":       true,
+				"recoveryCodes": recoveryCodes,
+				"lastUsedStep":  step,
+				"enabledAt":     time.Now(),
+			},
+			"$unset": bson.M{"pendingSecret": ""},
+		})
+	if err != nil {
+		return false, fmt.Errorf("failed to enable two-factor authentication: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *TwoFactorRepository) Disable(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %v", err)
+	}
+	return nil
+}
+
+// UseStep records step as used. It reports false when the step or a later
+// one was used before, so a code cannot be replayed.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "enabled": true, "lastUsedStep": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"lastUsedStep": step}})
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes the recovery code with the given hash. It reports
+// false when there is no such unused code.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, codeHash string) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "enabled": true, "recoveryCodes": codeHash},
+		bson.M{"$pull": bson.M{"recoveryCodes": codeHash}})
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID primitive.ObjectID, recoveryCodes []string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "enabled": true},
+		bson.M{"$set": bson.M{"recoveryCodes": recoveryCodes}})
+	if err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("two-factor authentication not enabled")
+	}
+	return nil
+}
+
+// RequiredRoles returns the roles that must use two-factor authentication
+// as set by an admin. found is false until an admin sets them.
+func (r *TwoFactorRepository) RequiredRoles(ctx context.Context) (roles []string, found bool, err error) {
+	var policy twoFactorPolicy
+	err = r.settings.FindOne(ctx, bson.M{"_id": twoFactorPolicyID}).Decode(&policy)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get two-factor policy: %v", err)
+	}
+	return policy.RequiredRoles, true, nil
+}
+
+func (r *TwoFactorRepository) SetRequiredRoles(ctx context.Context, roles []string) error {
+	_, err := r.settings.UpdateOne(ctx,
+		bson.M{"_id": twoFactorPolicyID},
+		bson.M{"$set": bson.M{"requiredRoles": roles}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to store two-factor policy: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcKey is the SHA1 key of the RFC 6238 appendix B test vectors.
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTP_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		step := Step(time.Unix(tt.unix, 0))
+		assert.Equal(t, tt.want, hotp(rfcKey, uint64(step), 8), "T=%d", tt.unix)
+	}
+}
+
+func TestCodeAndValidate(t *testing.T) {
+	secret := encoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111109, 0)
+
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+	assert.Equal(t, "081804", code, "6 digit codes are the low digits of the RFC vector")
+
+	step, ok := Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	_, ok = Validate(secret, code, now.Add(Period), 1)
+	assert.True(t, ok, "one step of clock skew is tolerated")
+
+	_, ok = Validate(secret, code, now.Add(3*Period), 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "08180", now, 1)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	key, err := decodeSecret(strings.ToLower(secret))
+	require.NoError(t, err)
+	assert.Len(t, key, secretSize)
+
+	uri, err := url.Parse(URI("students-service", "student@example.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/students-service:student@example.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "students-service", uri.Query().Get("issuer"))
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nurmeden/students-service/internal/app/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UpdateProfile applies the non-nil fields of the update to the student's
+// own record. Email, password, role and courses have routes of their own.
+func (u *studentUsecase) UpdateProfile(ctx context.Context, userID string, update *model.ProfileUpdate) (*model.Student, error) {
+	studentID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrStudentNotFound
+	}
+
+	fields := bson.M{}
+	if update.FirstName != nil {
+		fields["firstName"] = strings.TrimSpace(*update.FirstName)
+	}
+	if update.LastName != nil {
+		fields["lastName"] = strings.TrimSpace(*update.LastName)
+	}
+	if update.Age != nil {
+		fields["age"] = strings.TrimSpace(*update.Age)
+	}
+
+	student, err := u.studentRepo.UpdateProfile(ctx, studentID, fields)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		return nil, ErrStudentNotFound
+	}
+	return student, nil
+}
+
+// ChangePassword replaces the password of a signed-in student after
+// checking the current one. Wrong current passwords count as failed
+// sign-ins, so a stolen session cannot be used to guess the password. Every
+// other session of the student is ended; the one making the change stays.
+func (u *studentUsecase) ChangePassword(ctx context.Context, request *model.PasswordChangeRequest) error {
+	student, err := u.studentRepo.GetStudentByID(ctx, request.UserID)
+	if err != nil {
+		return err
+	}
+	if student == nil {
+		return ErrStudentNotFound
+	}
+
+	account := strings.ToLower(strings.TrimSpace(student.Email))
+	lockedFor, err := u.attemptRepo.LockedFor(account, request.ClientIP)
+	if err != nil {
+		return err
+	}
+	if lockedFor > 0 {
+		return &LockoutError{RetryAfter: lockedFor}
+	}
+	if ok, _ := u.hasher.Verify(student.Password, request.CurrentPassword); !ok {
+		u.logger.WithContext(ctx).Warnf("Wrong current password in password change of student %s from %s", request.UserID, request.ClientIP)
+		return u.signInFailed(ctx, account, request.ClientIP)
+	}
+
+	hashedPassword, err := u.hashNewPassword(ctx, request.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := u.studentRepo.UpdatePassword(ctx, student.ID, hashedPassword); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Password of student %s changed", request.UserID)
+
+	return u.revokeOtherSessions(ctx, request.UserID, request.SessionID)
+}
+
+func (u *studentUsecase) revokeOtherSessions(ctx context.Context, userID, keepSessionID string) error {
+	familyIDs, err := u.tokenRepo.ListFamilyIDs(userID)
+	if err != nil {
+		return err
+	}
+	for _, familyID := range familyIDs {
+		if familyID == keepSessionID {
+			continue
+		}
+		if err := u.tokenRepo.DenySession(familyID, u.jwtConfig.AccessTTL); err != nil {
+			u.logger.WithContext(ctx).Errorf("Error denylisting session %s: %v", familyID, err)
+			return err
+		}
+		if err := u.tokenRepo.RevokeFamily(familyID); err != nil {
+			u.logger.WithContext(ctx).Errorf("Error revoking refresh token family %s: %v", familyID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAccount deletes the student's own account.
+func (u *studentUsecase) DeleteAccount(ctx context.Context, userID string) error {
+	if err := u.deleteStudent(ctx, userID); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Student %s deleted their account", userID)
+	return nil
+}
+
+// deleteStudent deletes a student however the deletion was asked for.
+// Sessions are ended first, so a failure later leaves a signed-out account
+// rather than a deleted one with live tokens. Two-factor settings and
+// linked identities go with it.
+func (u *studentUsecase) deleteStudent(ctx context.Context, userID string) error {
+	studentID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrStudentNotFound
+	}
+	student, err := u.studentRepo.GetStudentByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if student == nil {
+		return ErrStudentNotFound
+	}
+
+	if err := u.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+	if err := u.twoFactorRepo.Disable(ctx, studentID); err != nil {
+		return err
+	}
+	if err := u.identityRepo.UnlinkUser(ctx, studentID); err != nil {
+		return err
+	}
+	return u.studentRepo.Delete(ctx, userID)
+}
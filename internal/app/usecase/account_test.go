@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_studentUsecase_ChangePasswordChecksCurrentPassword(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	var err error
+	u.hasher, err = password.NewHasher(config.PasswordConfig{})
+	require.NoError(t, err)
+	u.passwordPolicy, err = password.NewPolicy(config.PasswordConfig{})
+	require.NoError(t, err)
+
+	hash, err := u.hasher.Hash("correct horse battery")
+	require.NoError(t, err)
+	student := &model.Student{ID: testStudentIDs["student-1"], Email: "student-1@example.com", Password: hash}
+	seedStudent(t, u, student)
+
+	err = u.ChangePassword(ctx, &model.PasswordChangeRequest{
+		UserID:          student.ID.Hex(),
+		CurrentPassword: "wrong password",
+		NewPassword:     "a brand new passphrase",
+		ClientIP:        "10.0.0.1",
+	})
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	err = u.ChangePassword(ctx, &model.PasswordChangeRequest{
+		UserID:          student.ID.Hex(),
+		CurrentPassword: "correct horse battery",
+		NewPassword:     "short",
+		ClientIP:        "10.0.0.1",
+	})
+	var weakErr *WeakPasswordError
+	assert.True(t, errors.As(err, &weakErr), "the new password goes through the policy, got %v", err)
+
+	for i := int64(0); i < u.lockoutConfig.MaxAccountFailures; i++ {
+		u.ChangePassword(ctx, &model.PasswordChangeRequest{UserID: student.ID.Hex(), CurrentPassword: "guess", NewPassword: "a brand new passphrase", ClientIP: "10.0.0.1"})
+	}
+	err = u.ChangePassword(ctx, &model.PasswordChangeRequest{UserID: student.ID.Hex(), CurrentPassword: "correct horse battery", NewPassword: "a brand new passphrase", ClientIP: "10.0.0.1"})
+	var lockoutErr *LockoutError
+	assert.True(t, errors.As(err, &lockoutErr), "wrong current passwords count towards the lockout, got %v", err)
+}
+
+func Test_studentUsecase_revokeOtherSessions(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	current := signInForTest(t, u, "student-1")
+	other := signInForTest(t, u, "student-1")
+	userID := testStudentIDs["student-1"].Hex()
+
+	claims, err := u.ValidateAccessToken(ctx, current.Token)
+	require.NoError(t, err)
+	require.NoError(t, u.revokeOtherSessions(ctx, userID, claims.SessionID))
+
+	_, err = u.ValidateAccessToken(ctx, current.Token)
+	assert.NoError(t, err, "the session that changed the password stays")
+	_, err = u.ValidateAccessToken(ctx, other.Token)
+	assert.Equal(t, ErrTokenRevoked, err)
+	_, err = u.Refresh(ctx, other.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func Test_studentUsecase_UnknownStudentIsNotFound(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+
+	student, err := u.GetStudentByID(ctx, "not-an-id")
+	assert.Nil(t, student)
+	assert.ErrorIs(t, err, ErrStudentNotFound)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	_, err = u.UpdateStudent(ctx, "not-an-id", &model.Student{FirstName: "Aigerim"})
+	assert.ErrorIs(t, err, ErrStudentNotFound)
+
+	_, err = u.SetRole(ctx, "not-an-id", model.RoleInstructor)
+	assert.ErrorIs(t, err, ErrStudentNotFound)
+}
+
+func Test_studentUsecase_DeleteStudentEndsSessions(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	authToken := signInForTest(t, u, "student-1")
+
+	// The fixture's Mongo is unreachable, so the deletion itself fails; the
+	// sessions must be over by then.
+	assert.Error(t, u.DeleteStudent(ctx, testStudentIDs["student-1"].Hex()))
+
+	_, err := u.Refresh(ctx, authToken.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+	_, err = u.ValidateAccessToken(ctx, authToken.Token)
+	assert.Equal(t, ErrTokenRevoked, err)
+}
+
+func TestWeakPasswordError_AppError(t *testing.T) {
+	policy, err := password.NewPolicy(config.PasswordConfig{MinLength: 12})
+	require.NoError(t, err)
+
+	tests := []struct {
+		password string
+		code     string
+		args     []interface{}
+	}{
+		{password: "short", code: "password_too_short", args: []interface{}{12}},
+		{password: strings.Repeat("ж", 40), code: "password_too_long_bytes", args: []interface{}{72}},
+		{password: strings.Repeat("x", 65), code: "password_too_long", args: []interface{}{64}},
+		{password: "password1234", code: "password_too_common"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			appErr := apperror.From(&WeakPasswordError{Err: policy.Check(tt.password)})
+			assert.Equal(t, tt.code, appErr.Code)
+			assert.Equal(t, tt.args, appErr.Args)
+			assert.ErrorIs(t, appErr, apperror.ErrValidation)
+		})
+	}
+}
+
+func Test_studentUsecase_RequestEmailChangeCooldown(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	u.verificationConfig = config.VerificationConfig{ResendCooldown: time.Minute, MaxResends: 5, ResendWindow: time.Hour}
+	student := &model.Student{ID: testStudentIDs["student-1"], Email: "student-1@example.com"}
+	seedStudent(t, u, student)
+
+	// The fixture has no database, so the first request fails after taking
+	// its reservation.
+	err := u.RequestEmailChange(ctx, student.ID.Hex(), "victim@example.com")
+	var rateLimitErr *RateLimitError
+	require.False(t, errors.As(err, &rateLimitErr), "the first request is allowed, got %v", err)
+
+	err = u.RequestEmailChange(ctx, student.ID.Hex(), "victim@example.com")
+	require.True(t, errors.As(err, &rateLimitErr), "a second request within the cooldown is refused, got %v", err)
+	assert.Greater(t, rateLimitErr.RetryAfter, time.Duration(0))
+
+	err = u.ResendVerification(ctx, student.ID.Hex())
+	assert.True(t, errors.As(err, &rateLimitErr), "resends share the cooldown, got %v", err)
+}
+
+func Test_studentUsecase_ForgotPasswordCooldown(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	u.verificationConfig = config.VerificationConfig{ResendCooldown: time.Minute, MaxResends: 5, ResendWindow: time.Hour}
+
+	// The fixture has no database, so a request that is let through fails
+	// looking the email up; a throttled one never gets that far.
+	assert.Error(t, u.ForgotPassword(ctx, "victim@example.com"))
+	assert.NoError(t, u.ForgotPassword(ctx, "Victim@example.com "), "a second request within the cooldown is dropped")
+	assert.Error(t, u.ForgotPassword(ctx, "other@example.com"), "other emails have their own limit")
+}
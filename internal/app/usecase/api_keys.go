@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	apiKeyPrefix = "sk_"
+	// apiKeyTouchInterval limits how often the last-used time of a key is
+	// written, so busy clients do not cause a write per request.
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = apperror.Unauthorized("invalid_api_key", "invalid, expired or revoked api key")
+	ErrAPIKeyNotFound = repository.ErrAPIKeyNotFound
+	ErrInvalidExpiry  = apperror.Validation("invalid_expiry", "api key expiry must be in the future")
+)
+
+// CreateAPIKey issues a key with the scopes. The returned key carries the
+// plain key once; only its hash is stored.
+func (u *studentUsecase) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time, createdBy string) (*model.APIKey, error) {
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	plain := apiKeyPrefix + secret
+
+	key := &model.APIKey{
+		Name:      name,
+		Prefix:    plain[:len(apiKeyPrefix)+6],
+		Hash:      hashOneTimeToken(plain),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := u.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	u.logger.WithContext(ctx).Infof("API key %s (%s) created by %s with scopes %v", key.ID.Hex(), name, createdBy, scopes)
+	key.Key = plain
+	return key, nil
+}
+
+func (u *studentUsecase) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	return u.apiKeyRepo.List(ctx)
+}
+
+func (u *studentUsecase) RevokeAPIKey(ctx context.Context, id string) error {
+	keyID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+	err = u.apiKeyRepo.Revoke(ctx, keyID, time.Now())
+	if err == repository.ErrAPIKeyNotFound {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("API key %s revoked", id)
+	return nil
+}
+
+// AuthenticateAPIKey returns the key matching plain if it is neither
+// expired nor revoked, and records its use.
+func (u *studentUsecase) AuthenticateAPIKey(ctx context.Context, plain string) (*model.APIKey, error) {
+	key, err := u.apiKeyRepo.FindByHash(ctx, hashOneTimeToken(plain))
+	if err == repository.ErrAPIKeyNotFound {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := u.apiKeyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			u.logger.WithContext(ctx).Errorf("Error recording use of API key %s: %v", key.ID.Hex(), err)
+		}
+	}
+	return key, nil
+}
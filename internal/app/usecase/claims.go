@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+)
+
+var ErrInvalidAccessToken = apperror.Unauthorized("invalid_token", "invalid access token")
+
+// AccessClaims are the claims of an access token. Subject is the student's
+// ID and SessionID the refresh-token family the token was issued in.
+type AccessClaims struct {
+	Subject       string   `json:"sub"`
+	Roles         []string `json:"roles"`
+	Tenant        string   `json:"tenant,omitempty"`
+	ID            string   `json:"jti"`
+	SessionID     string   `json:"sid"`
+	EmailVerified bool     `json:"email_verified"`
+	Issuer        string   `json:"iss"`
+	Audience      Audience `json:"aud"`
+	IssuedAt      int64    `json:"iat"`
+	NotBefore     int64    `json:"nbf"`
+	ExpiresAt     int64    `json:"exp"`
+}
+
+// Valid checks the claims without leeway. ValidateAccessToken uses validate
+// instead, which knows the configured clock skew and expected values.
+func (c *AccessClaims) Valid() error {
+	return c.validateTime(time.Now(), 0)
+}
+
+// validate checks the registered claims against the expected issuer,
+// audience and tenant. An empty tenant accepts any.
+func (c *AccessClaims) validate(now time.Time, skew time.Duration, issuer, audience, tenant string) error {
+	if c.Subject == "" || c.ID == "" || c.SessionID == "" {
+		return fmt.Errorf("%w: missing sub, jti or sid", ErrInvalidAccessToken)
+	}
+	if c.Issuer != issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidAccessToken, c.Issuer)
+	}
+	if !c.Audience.Contains(audience) {
+		return fmt.Errorf("%w: not issued for audience %q", ErrInvalidAccessToken, audience)
+	}
+	if tenant != "" && c.Tenant != tenant {
+		return fmt.Errorf("%w: unexpected tenant %q", ErrInvalidAccessToken, c.Tenant)
+	}
+	return c.validateTime(now, skew)
+}
+
+func (c *AccessClaims) validateTime(now time.Time, skew time.Duration) error {
+	if c.ExpiresAt == 0 || !now.Before(time.Unix(c.ExpiresAt, 0).Add(skew)) {
+		return fmt.Errorf("%w: expired", ErrInvalidAccessToken)
+	}
+	if c.NotBefore != 0 && now.Add(skew).Before(time.Unix(c.NotBefore, 0)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidAccessToken)
+	}
+	if time.Unix(c.IssuedAt, 0).After(now.Add(skew)) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidAccessToken)
+	}
+	return nil
+}
+
+func (c *AccessClaims) ExpiresAtTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Audience is the aud claim, which JWTs carry as a string or an array of
+// strings. A single audience is written as a string.
+type Audience []string
+
+func (a Audience) Contains(audience string) bool {
+	for _, value := range a {
+		if value == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
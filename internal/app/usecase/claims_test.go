@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessClaims_validate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	valid := func() *AccessClaims {
+		return &AccessClaims{
+			Subject:   "student-1",
+			ID:        "jti-1",
+			SessionID: "sid-1",
+			Issuer:    "students-service",
+			Audience:  Audience{"students-api"},
+			Tenant:    "kaznu",
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(time.Minute).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(c *AccessClaims)
+		at      time.Time
+		tenant  string
+		wantErr bool
+	}{
+		{name: "valid", at: now, tenant: "kaznu"},
+		{name: "any tenant when none is configured", mutate: func(c *AccessClaims) { c.Tenant = "other" }, at: now},
+		{name: "other tenant", mutate: func(c *AccessClaims) { c.Tenant = "other" }, at: now, tenant: "kaznu", wantErr: true},
+		{name: "other issuer", mutate: func(c *AccessClaims) { c.Issuer = "courses-service" }, at: now, wantErr: true},
+		{name: "other audience", mutate: func(c *AccessClaims) { c.Audience = Audience{"courses-api"} }, at: now, wantErr: true},
+		{name: "one of several audiences", mutate: func(c *AccessClaims) { c.Audience = Audience{"courses-api", "students-api"} }, at: now},
+		{name: "missing sid", mutate: func(c *AccessClaims) { c.SessionID = "" }, at: now, wantErr: true},
+		{name: "expired within skew", at: now.Add(time.Minute + 20*time.Second)},
+		{name: "expired beyond skew", at: now.Add(time.Minute + time.Hour), wantErr: true},
+		{name: "not valid yet within skew", mutate: func(c *AccessClaims) { c.NotBefore = now.Add(20 * time.Second).Unix() }, at: now},
+		{name: "not valid yet beyond skew", mutate: func(c *AccessClaims) { c.NotBefore = now.Add(time.Minute).Unix() }, at: now, wantErr: true},
+		{name: "issued in the future", mutate: func(c *AccessClaims) { c.IssuedAt = now.Add(time.Minute).Unix() }, at: now, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			if tt.mutate != nil {
+				tt.mutate(claims)
+			}
+			err := claims.validate(tt.at, 30*time.Second, "students-service", "students-api", tt.tenant)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidAccessToken), "got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAudience_JSON(t *testing.T) {
+	var single, many Audience
+	require.NoError(t, json.Unmarshal([]byte(`"students-api"`), &single))
+	require.NoError(t, json.Unmarshal([]byte(`["students-api","courses-api"]`), &many))
+	assert.Equal(t, Audience{"students-api"}, single)
+	assert.Equal(t, Audience{"students-api", "courses-api"}, many)
+
+	data, err := json.Marshal(single)
+	require.NoError(t, err)
+	assert.Equal(t, `"students-api"`, string(data))
+}
+
+func Test_studentUsecase_ValidateAccessTokenChecksAudience(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	authToken := signInForTest(t, u, "student-1")
+
+	claims, err := u.ValidateAccessToken(ctx, authToken.Token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"student"}, claims.Roles)
+	assert.NotZero(t, claims.NotBefore)
+
+	u.jwtConfig.Audience = "courses-api"
+	_, err = u.ValidateAccessToken(ctx, authToken.Token)
+	assert.True(t, errors.Is(err, ErrInvalidAccessToken))
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/nurmeden/students-service/internal/mailer"
+	"github.com/nurmeden/students-service/internal/requestid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const purposeEmailVerification = "email_verification"
+
+// emailVerification is the value stored for a verification token. The
+// address is part of it, so a token only ever verifies the address it was
+// mailed to.
+type emailVerification struct {
+	UserID string `json:"userID"`
+	Email  string `json:"email"`
+}
+
+// VerifyEmail redeems a verification token. For a new account it marks the
+// email as verified; for an email change it replaces the stored email with
+// the pending one.
+func (u *studentUsecase) VerifyEmail(ctx context.Context, verificationToken string) error {
+	value, err := u.oneTimeRepo.Consume(purposeEmailVerification, hashOneTimeToken(verificationToken))
+	if err == repository.ErrOneTimeTokenNotFound {
+		return ErrInvalidVerification
+	}
+	if err != nil {
+		return err
+	}
+
+	var verification emailVerification
+	if err := json.Unmarshal([]byte(value), &verification); err != nil {
+		return err
+	}
+	student, err := u.studentRepo.GetStudentByID(ctx, verification.UserID)
+	if err != nil {
+		return err
+	}
+	if student == nil {
+		return ErrInvalidVerification
+	}
+
+	switch verification.Email {
+	case student.Email:
+	case student.PendingEmail:
+		exists, err := u.studentRepo.CheckEmailExistence(ctx, verification.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEmailTaken
+		}
+	default:
+		// The address changed again after this token was mailed.
+		return ErrInvalidVerification
+	}
+
+	if err := u.studentRepo.MarkEmailVerified(ctx, student.ID, verification.Email); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Email %s of student %s verified", verification.Email, verification.UserID)
+	return nil
+}
+
+// ResendVerification mails a new verification link for the pending email
+// change, or for the account email if it is still unverified.
+func (u *studentUsecase) ResendVerification(ctx context.Context, id string) error {
+	student, err := u.studentRepo.GetStudentByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if student == nil {
+		return ErrStudentNotFound
+	}
+
+	email := student.PendingEmail
+	if email == "" {
+		if student.EmailVerified {
+			return ErrAlreadyVerified
+		}
+		email = student.Email
+	}
+
+	if err := u.reserveMail(purposeEmailVerification, id); err != nil {
+		return err
+	}
+	return u.sendVerification(ctx, student, email)
+}
+
+// reserveMail limits the mails of a purpose to a subject to one per
+// ResendCooldown and MaxResends per ResendWindow, so the service cannot be
+// used to flood a mailbox. Verification mails count for resends and email
+// changes alike.
+func (u *studentUsecase) reserveMail(purpose, subject string) error {
+	cfg := u.verificationConfig
+	retryAfter, err := u.oneTimeRepo.ReserveIssue(purpose, subject, cfg.ResendCooldown, cfg.MaxResends, cfg.ResendWindow)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RequestEmailChange keeps the new address pending and mails a verification
+// link to it. The stored email changes only once the link is used. Requests
+// count against the same limits as resends.
+func (u *studentUsecase) RequestEmailChange(ctx context.Context, id string, newEmail string) error {
+	studentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrStudentNotFound
+	}
+	student, err := u.studentRepo.GetStudentByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if student == nil {
+		return ErrStudentNotFound
+	}
+	if err := u.reserveMail(purposeEmailVerification, id); err != nil {
+		return err
+	}
+
+	exists, err := u.studentRepo.CheckEmailExistence(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrEmailTaken
+	}
+
+	if err := u.studentRepo.SetPendingEmail(ctx, studentID, newEmail); err != nil {
+		return err
+	}
+	student.PendingEmail = newEmail
+	return u.sendVerification(ctx, student, newEmail)
+}
+
+func (u *studentUsecase) sendVerification(ctx context.Context, student *model.Student, email string) error {
+	verificationToken, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	userID := student.ID.Hex()
+	value, err := json.Marshal(emailVerification{UserID: userID, Email: email})
+	if err != nil {
+		return err
+	}
+	if err := u.oneTimeRepo.Save(purposeEmailVerification, userID, hashOneTimeToken(verificationToken), string(value), u.verificationConfig.TokenTTL); err != nil {
+		return err
+	}
+
+	go u.sendMail(requestid.FromContext(ctx), mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Confirm that %s belongs to you by opening the link below within %s:\n%s\n\n"+
+			"If you did not request this, ignore this email.",
+			email, u.verificationConfig.TokenTTL, linkWithToken(u.verificationConfig.LinkURL, verificationToken)),
+	})
+	return nil
+}
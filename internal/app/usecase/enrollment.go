@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidCourseID = apperror.Validation("invalid_course_id", "invalid course ID")
+
+// EnrollStudent adds the course to the courses of the student. Enrolling
+// in a course twice is no error.
+func (u *studentUsecase) EnrollStudent(ctx context.Context, studentID, courseID string) (*model.Student, error) {
+	id, err := u.enrollmentIDs(studentID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	student, err := u.studentRepo.AddCourse(ctx, id, courseID)
+	if err != nil {
+		return nil, err
+	}
+	u.logger.WithContext(ctx).Infof("Student %s enrolled in course %s", studentID, courseID)
+	return student, nil
+}
+
+// UnenrollStudent removes the course from the courses of the student.
+func (u *studentUsecase) UnenrollStudent(ctx context.Context, studentID, courseID string) (*model.Student, error) {
+	id, err := u.enrollmentIDs(studentID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	student, err := u.studentRepo.RemoveCourse(ctx, id, courseID)
+	if err != nil {
+		return nil, err
+	}
+	u.logger.WithContext(ctx).Infof("Student %s unenrolled from course %s", studentID, courseID)
+	return student, nil
+}
+
+// enrollmentIDs checks the IDs of an enrollment. Course IDs are owned by the
+// courses service and only need to be usable as a cache key.
+func (u *studentUsecase) enrollmentIDs(studentID, courseID string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return primitive.NilObjectID, ErrStudentNotFound
+	}
+	if courseID == "" || strings.TrimSpace(courseID) != courseID || len(courseID) > 64 {
+		return primitive.NilObjectID, ErrInvalidCourseID
+	}
+	return id, nil
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_studentUsecase_GetStudentsByIDs(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	for name, id := range testStudentIDs {
+		seedStudent(t, u, &model.Student{ID: id, FirstName: name, Email: name + "@example.com"})
+	}
+	first, second := testStudentIDs["student-1"].Hex(), testStudentIDs["student-2"].Hex()
+
+	students, err := u.GetStudentsByIDs(context.Background(), []string{second, "not-an-id", first, second})
+	require.NoError(t, err)
+	require.Len(t, students, 2)
+	assert.Equal(t, "student-2", students[0].FirstName)
+	assert.Equal(t, "student-1", students[1].FirstName)
+
+	students, err = u.GetStudentsByIDs(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, students)
+
+	_, err = u.GetStudentsByIDs(context.Background(), make([]string, MaxBatchSize+1))
+	assert.ErrorIs(t, err, ErrTooManyIDs)
+	assert.Equal(t, []interface{}{MaxBatchSize}, apperror.From(err).Args)
+
+	_, err = u.GetStudentsByCourseIDs(context.Background(), make([]string, MaxBatchSize+1))
+	assert.ErrorIs(t, err, ErrTooManyIDs)
+}
+
+func Test_studentUsecase_EnrollmentIDs(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	studentID := testStudentIDs["student-1"].Hex()
+
+	_, err := u.EnrollStudent(ctx, "not-an-id", "course-1")
+	assert.ErrorIs(t, err, ErrStudentNotFound)
+
+	for _, courseID := range []string{"", " course-1", strings.Repeat("c", 65)} {
+		_, err = u.EnrollStudent(ctx, studentID, courseID)
+		assert.ErrorIs(t, err, ErrInvalidCourseID, "course %q", courseID)
+		_, err = u.UnenrollStudent(ctx, studentID, courseID)
+		assert.ErrorIs(t, err, ErrInvalidCourseID, "course %q", courseID)
+	}
+}
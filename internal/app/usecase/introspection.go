@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+)
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// IntrospectToken tells another service whether an access or refresh token
+// is active, checking the same denylist and session store as
+// ValidateAccessToken and Refresh. The hint only decides which kind is
+// tried first. Malformed, expired and unknown tokens are inactive, not
+// errors.
+func (u *studentUsecase) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*model.TokenIntrospection, error) {
+	introspectors := []func(context.Context, string) (*model.TokenIntrospection, error){
+		u.introspectAccessToken,
+		u.introspectRefreshToken,
+	}
+	if tokenTypeHint == TokenTypeRefresh {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, introspect := range introspectors {
+		introspection, err := introspect(ctx, token)
+		if err != nil || introspection != nil {
+			return introspection, err
+		}
+	}
+	return &model.TokenIntrospection{Active: false}, nil
+}
+
+// introspectAccessToken returns nil when the token is no valid access token.
+func (u *studentUsecase) introspectAccessToken(ctx context.Context, token string) (*model.TokenIntrospection, error) {
+	claims, err := u.parseAccessToken(token)
+	if err != nil {
+		return nil, nil
+	}
+	revoked, err := u.isAccessTokenRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return &model.TokenIntrospection{Revoked: true, TokenType: TokenTypeAccess, Subject: claims.Subject, SessionID: claims.SessionID, JTI: claims.ID}, nil
+	}
+
+	return &model.TokenIntrospection{
+		Active:    true,
+		TokenType: TokenTypeAccess,
+		Subject:   claims.Subject,
+		Roles:     claims.Roles,
+		Tenant:    claims.Tenant,
+		SessionID: claims.SessionID,
+		JTI:       claims.ID,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+// introspectRefreshToken returns nil when no refresh token has the hash of
+// the token. A refresh token that was already rotated is inactive.
+func (u *studentUsecase) introspectRefreshToken(ctx context.Context, token string) (*model.TokenIntrospection, error) {
+	record, err := u.tokenRepo.GetRefreshToken(u.hashRefreshToken(token))
+	if err == repository.ErrRefreshTokenNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.Used {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+	family, err := u.tokenRepo.GetFamily(record.FamilyID)
+	if err == repository.ErrRefreshTokenNotFound {
+		return &model.TokenIntrospection{Revoked: true, TokenType: TokenTypeRefresh, Subject: record.UserID, SessionID: record.FamilyID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	student, err := u.studentRepo.GetStudentByID(ctx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		return &model.TokenIntrospection{Active: false}, nil
+	}
+
+	introspection := &model.TokenIntrospection{
+		Active:    true,
+		TokenType: TokenTypeRefresh,
+		Subject:   record.UserID,
+		Roles:     []string{student.EffectiveRole()},
+		Tenant:    u.jwtConfig.Tenant,
+		SessionID: record.FamilyID,
+		Issuer:    u.jwtConfig.Issuer,
+		IssuedAt:  family.LastRefreshAt.Unix(),
+	}
+	if !record.ExpiresAt.IsZero() {
+		introspection.ExpiresAt = record.ExpiresAt.Unix()
+	}
+	return introspection, nil
+}
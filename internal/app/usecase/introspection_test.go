@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_studentUsecase_IntrospectToken(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	authToken := signInForTest(t, u, "student-1")
+	userID := testStudentIDs["student-1"].Hex()
+
+	access, err := u.IntrospectToken(ctx, authToken.Token, "")
+	require.NoError(t, err)
+	assert.True(t, access.Active)
+	assert.Equal(t, TokenTypeAccess, access.TokenType)
+	assert.Equal(t, userID, access.Subject)
+	assert.Equal(t, []string{"student"}, access.Roles)
+	assert.NotEmpty(t, access.JTI)
+	assert.NotZero(t, access.ExpiresAt)
+
+	refresh, err := u.IntrospectToken(ctx, authToken.RefreshToken, TokenTypeRefresh)
+	require.NoError(t, err)
+	assert.True(t, refresh.Active)
+	assert.Equal(t, TokenTypeRefresh, refresh.TokenType)
+	assert.Equal(t, userID, refresh.Subject)
+	assert.Equal(t, access.SessionID, refresh.SessionID)
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), refresh.ExpiresAt, 5)
+
+	hinted, err := u.IntrospectToken(ctx, authToken.RefreshToken, TokenTypeAccess)
+	require.NoError(t, err)
+	assert.True(t, hinted.Active, "a wrong hint only changes the order")
+
+	unknown, err := u.IntrospectToken(ctx, "not-a-token", "")
+	require.NoError(t, err)
+	assert.False(t, unknown.Active)
+	assert.Empty(t, unknown.Subject)
+
+	rotated, err := u.Refresh(ctx, authToken.RefreshToken)
+	require.NoError(t, err)
+	used, err := u.IntrospectToken(ctx, authToken.RefreshToken, TokenTypeRefresh)
+	require.NoError(t, err)
+	assert.False(t, used.Active, "a rotated refresh token is no longer active")
+
+	require.NoError(t, u.LogoutSession(ctx, userID, access.SessionID, access.JTI, time.Now().Add(time.Minute)))
+	revoked, err := u.IntrospectToken(ctx, rotated.Token, TokenTypeAccess)
+	require.NoError(t, err)
+	assert.False(t, revoked.Active)
+	assert.True(t, revoked.Revoked)
+	assert.Equal(t, userID, revoked.Subject)
+	loggedOut, err := u.IntrospectToken(ctx, rotated.RefreshToken, TokenTypeRefresh)
+	require.NoError(t, err)
+	assert.False(t, loggedOut.Active)
+}
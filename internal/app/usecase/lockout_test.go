@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_studentUsecase_signInFailedLocksAccount(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ErrInvalidCredentials, u.signInFailed(ctx, "student@example.com", "10.0.0.1"))
+	}
+
+	lockedFor, err := u.attemptRepo.LockedFor("student@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.True(t, lockedFor > 0, "account is locked from any IP")
+
+	lockedFor, err = u.attemptRepo.LockedFor("other@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Zero(t, lockedFor)
+
+	require.NoError(t, u.attemptRepo.UnlockAccount("student@example.com"))
+	lockedFor, err = u.attemptRepo.LockedFor("student@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Zero(t, lockedFor)
+}
+
+func Test_studentUsecase_signInFailedLocksIP(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+
+	for _, account := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		assert.Equal(t, ErrInvalidCredentials, u.signInFailed(ctx, account, "10.0.0.1"))
+	}
+
+	lockedFor, err := u.attemptRepo.LockedFor("f@example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, lockedFor > 0)
+}
+
+func Test_studentUsecase_SignInWhileLocked(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	require.NoError(t, u.attemptRepo.LockAccount("student@example.com", time.Minute))
+
+	_, err := u.SignIn(context.Background(), &model.SignInData{Email: "Student@example.com", Password: "password", ClientIP: "10.0.0.1"})
+
+	var lockoutErr *LockoutError
+	require.True(t, errors.As(err, &lockoutErr))
+	assert.True(t, lockoutErr.RetryAfter > 0)
+}
+
+func Test_progressiveDelay(t *testing.T) {
+	cfg := config.LockoutConfig{DelayAfter: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	assert.Zero(t, progressiveDelay(cfg, 3))
+	assert.Equal(t, 100*time.Millisecond, progressiveDelay(cfg, 4))
+	assert.Equal(t, 200*time.Millisecond, progressiveDelay(cfg, 5))
+	assert.Equal(t, 800*time.Millisecond, progressiveDelay(cfg, 7))
+	assert.Equal(t, time.Second, progressiveDelay(cfg, 20))
+}
@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/nurmeden/students-service/internal/oidc"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	purposeOIDCState = "oidc_state"
+	defaultStateTTL  = 10 * time.Minute
+)
+
+var (
+	ErrOIDCDisabled         = apperror.NotFound("oidc_disabled", "single sign-on is not enabled")
+	ErrInvalidOIDCState     = apperror.Validation("invalid_oidc_state", "invalid or expired single sign-on state, start again")
+	ErrOIDCEmailNotVerified = apperror.Forbidden("oidc_email_not_verified", "the identity provider did not verify the email")
+)
+
+// oidcLogin is what a sign-in remembers between the redirect to the
+// provider and the callback.
+type oidcLogin struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+// OIDCLogin starts a single sign-on and returns the provider URL to redirect
+// the browser to, with the state the handler binds to that browser.
+func (u *studentUsecase) OIDCLogin(ctx context.Context) (*model.OIDCLogin, error) {
+	if u.oidcClient == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	state, err := oidc.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	login := oidcLogin{}
+	if login.Nonce, err = oidc.NewRandom(); err != nil {
+		return nil, err
+	}
+	if login.CodeVerifier, err = oidc.NewRandom(); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(login)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := u.oidcConfig.StateTTL
+	if ttl == 0 {
+		ttl = defaultStateTTL
+	}
+	stateHash := hashOneTimeToken(state)
+	if err := u.oneTimeRepo.Save(purposeOIDCState, stateHash, stateHash, string(value), ttl); err != nil {
+		return nil, err
+	}
+	authURL, err := u.oidcClient.AuthCodeURL(ctx, state, login.Nonce, oidc.CodeChallenge(login.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+	return &model.OIDCLogin{URL: authURL, State: state, ExpiresIn: ttl}, nil
+}
+
+// OIDCCallback finishes a single sign-on: it redeems the code, verifies the
+// ID token, finds or provisions the student and signs them in as SignIn
+// would, including the two-factor challenge.
+func (u *studentUsecase) OIDCCallback(ctx context.Context, request *model.OIDCCallbackRequest) (*model.AuthToken, error) {
+	if u.oidcClient == nil {
+		return nil, ErrOIDCDisabled
+	}
+
+	value, err := u.oneTimeRepo.Consume(purposeOIDCState, hashOneTimeToken(request.State))
+	if err == repository.ErrOneTimeTokenNotFound {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var login oidcLogin
+	if err := json.Unmarshal([]byte(value), &login); err != nil {
+		return nil, err
+	}
+
+	tokens, err := u.oidcClient.Exchange(ctx, request.Code, login.CodeVerifier)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("Error exchanging single sign-on code: %v", err)
+		return nil, err
+	}
+	claims, err := u.oidcClient.Verify(ctx, tokens.IDToken, login.Nonce)
+	if err != nil {
+		u.logger.WithContext(ctx).Warnf("Rejected single sign-on ID token: %v", err)
+		return nil, err
+	}
+
+	student, err := u.studentForIdentity(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := u.twoFactorChallengeFor(ctx, student)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return nil, challenge
+	}
+	return u.startSession(ctx, student, request.ClientIP, request.UserAgent)
+}
+
+// studentForIdentity returns the student linked to the provider account.
+// An unlinked account is linked to the student with the same email, or to
+// a new student, but only if the provider verified the email.
+func (u *studentUsecase) studentForIdentity(ctx context.Context, claims *oidc.Claims) (*model.Student, error) {
+	issuer := u.oidcClient.Issuer()
+	identity, err := u.identityRepo.Find(ctx, issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		student, err := u.studentRepo.GetStudentByID(ctx, identity.UserID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		if student != nil {
+			return student, nil
+		}
+		// The student was deleted since; link the identity anew.
+	}
+
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	email := strings.TrimSpace(claims.Email)
+
+	student, err := u.studentRepo.GetByEmail(ctx, email)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if student == nil {
+		student, err = u.provisionStudent(ctx, claims, email)
+	} else if !student.EmailVerified {
+		err = u.claimUnverifiedStudent(ctx, student)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.identityRepo.Link(ctx, issuer, claims.Subject, student.ID, email); err != nil {
+		return nil, err
+	}
+	u.logger.WithContext(ctx).Infof("Single sign-on identity %s linked to student %s", claims.Subject, student.ID.Hex())
+	return student, nil
+}
+
+// provisionStudent creates a student for a provider account. The password
+// is random; the student can set one through the password reset flow.
+func (u *studentUsecase) provisionStudent(ctx context.Context, claims *oidc.Claims, email string) (*model.Student, error) {
+	unusablePassword, err := u.unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+	student := &model.Student{
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+		Email:         email,
+		Password:      unusablePassword,
+		Role:          model.RoleStudent,
+		EmailVerified: true,
+	}
+	return u.studentRepo.CreateStudent(ctx, student)
+}
+
+// claimUnverifiedStudent hands an account whose owner never proved the
+// email to the provider account that did. Whoever registered it cannot
+// know the address is theirs, so their password and sessions are dropped.
+func (u *studentUsecase) claimUnverifiedStudent(ctx context.Context, student *model.Student) error {
+	unusablePassword, err := u.unusablePasswordHash()
+	if err != nil {
+		return err
+	}
+	if err := u.studentRepo.UpdatePassword(ctx, student.ID, unusablePassword); err != nil {
+		return err
+	}
+	if err := u.studentRepo.MarkEmailVerified(ctx, student.ID, student.Email); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Warnf("Unverified student %s claimed through single sign-on", student.ID.Hex())
+	student.EmailVerified = true
+	student.Password = unusablePassword
+	return u.LogoutAll(ctx, student.ID.Hex())
+}
+
+func (u *studentUsecase) unusablePasswordHash() (string, error) {
+	password, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return u.hasher.Hash(password)
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/oidc"
+	"github.com/nurmeden/students-service/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_studentUsecase_OIDCLoginState(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+
+	_, err := u.OIDCLogin(ctx)
+	assert.Equal(t, ErrOIDCDisabled, err)
+
+	provider := oidctest.NewProvider(t, "students-service", "s3cret")
+	u.oidcClient = oidc.NewClient(config.OIDCConfig{
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "students-service",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost/api/auth/oidc/callback",
+	}, provider.Server.Client())
+
+	login, err := u.OIDCLogin(ctx)
+	require.NoError(t, err)
+	authURL := login.URL
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.NotEmpty(t, parsed.Query().Get("nonce"))
+
+	code, state, err := provider.Authorize(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, parsed.Query().Get("state"), state)
+	assert.Equal(t, state, login.State)
+
+	_, err = u.OIDCCallback(ctx, &model.OIDCCallbackRequest{Code: code, State: "forged-state"})
+	assert.Equal(t, ErrInvalidOIDCState, err)
+
+	value, err := u.oneTimeRepo.Consume(purposeOIDCState, hashOneTimeToken(state))
+	require.NoError(t, err, "the login is remembered under the state")
+	var remembered oidcLogin
+	require.NoError(t, json.Unmarshal([]byte(value), &remembered))
+	assert.Equal(t, parsed.Query().Get("nonce"), remembered.Nonce)
+	assert.Equal(t, parsed.Query().Get("code_challenge"), oidc.CodeChallenge(remembered.CodeVerifier))
+
+	_, err = u.OIDCCallback(ctx, &model.OIDCCallbackRequest{Code: code, State: state})
+	assert.Equal(t, ErrInvalidOIDCState, err, "a state is redeemed once")
+}
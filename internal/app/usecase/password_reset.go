@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/nurmeden/students-service/internal/mailer"
+	"github.com/nurmeden/students-service/internal/requestid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	purposePasswordReset = "password_reset"
+	mailTimeout          = 30 * time.Second
+)
+
+// ForgotPassword mails a password reset link to the student. It succeeds
+// for unknown emails too and sends the mail in the background, so neither
+// the response nor its timing reveals whether the email is registered. An
+// email gets at most one reset mail per Verification.ResendCooldown and
+// Verification.MaxResends per ResendWindow.
+func (u *studentUsecase) ForgotPassword(ctx context.Context, email string) error {
+	// Reset mails are limited like verification mails, per email whether it
+	// is registered or not. A throttled request succeeds as well, as telling
+	// it apart would reveal that the email got a mail before.
+	err := u.reserveMail(purposePasswordReset, strings.ToLower(strings.TrimSpace(email)))
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		u.logger.WithContext(ctx).Warnf("Password reset mail to %s throttled for %s", email, rateLimitErr.RetryAfter)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	student, err := u.studentRepo.GetByEmail(ctx, email)
+	if err == mongo.ErrNoDocuments {
+		u.logger.WithContext(ctx).Infof("Password reset requested for unknown email %s", email)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	userID := student.ID.Hex()
+	if err := u.oneTimeRepo.Save(purposePasswordReset, userID, hashOneTimeToken(resetToken), userID, u.passwordResetConfig.TokenTTL); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error saving password reset token for student %s: %v", userID, err)
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      student.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Open the link below within %s to choose a new password:\n%s\n\n"+
+			"If it was not you, ignore this email; your password stays unchanged.",
+			u.passwordResetConfig.TokenTTL, linkWithToken(u.passwordResetConfig.LinkURL, resetToken)),
+	}
+	go u.sendMail(requestid.FromContext(ctx), msg)
+	return nil
+}
+
+// ResetPassword redeems a reset token, sets the new password and ends every
+// session of the student.
+func (u *studentUsecase) ResetPassword(ctx context.Context, resetToken string, newPassword string) error {
+	// The policy is checked before the token is consumed, so a rejected
+	// password does not burn the link.
+	hashedPassword, err := u.hashNewPassword(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+
+	userID, err := u.oneTimeRepo.Consume(purposePasswordReset, hashOneTimeToken(resetToken))
+	if err == repository.ErrOneTimeTokenNotFound {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	studentID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	if err := u.studentRepo.UpdatePassword(ctx, studentID, hashedPassword); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Password of student %s reset", userID)
+
+	return u.LogoutAll(ctx, userID)
+}
+
+// sendMail runs apart from the request, which may end first, so it takes
+// only the request ID along for its log line.
+func (u *studentUsecase) sendMail(requestID string, msg mailer.Message) {
+	ctx, cancel := context.WithTimeout(requestid.NewContext(context.Background(), requestID), mailTimeout)
+	defer cancel()
+	if err := u.mailer.Send(ctx, msg); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error sending %q mail to %s: %v", msg.Subject, msg.To, err)
+	}
+}
+
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func linkWithToken(link, token string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
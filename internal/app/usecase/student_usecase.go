@@ -2,88 +2,375 @@ package usecase
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
+	"sort"
+	"strings"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
-	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/keys"
 	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/password"
 	"github.com/nurmeden/students-service/internal/app/repository"
-	"github.com/pkg/errors"
+	"github.com/nurmeden/students-service/internal/mailer"
+	"github.com/nurmeden/students-service/internal/oidc"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type StudentUsecase interface {
 	CreateStudent(ctx context.Context, student *model.Student) (*model.Student, error)
 	GetStudentByID(ctx context.Context, id string) (*model.Student, error)
 	GetStudentsByCourseID(ctx context.Context, id string) ([]*model.Student, error)
+	GetStudentsByIDs(ctx context.Context, ids []string) ([]*model.Student, error)
+	GetStudentsByCourseIDs(ctx context.Context, courseIDs []string) (map[string][]*model.Student, error)
+	EnrollStudent(ctx context.Context, studentID, courseID string) (*model.Student, error)
+	UnenrollStudent(ctx context.Context, studentID, courseID string) (*model.Student, error)
 	UpdateStudent(ctx context.Context, student_id string, student *model.Student) (*model.Student, error)
 	DeleteStudent(ctx context.Context, id string) error
+	SetRole(ctx context.Context, id string, role string) (*model.Student, error)
+	UnlockAccount(ctx context.Context, id string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, resetToken string, newPassword string) error
+	VerifyEmail(ctx context.Context, verificationToken string) error
+	ResendVerification(ctx context.Context, id string) error
+	RequestEmailChange(ctx context.Context, id string, newEmail string) error
+	UpdateProfile(ctx context.Context, userID string, update *model.ProfileUpdate) (*model.Student, error)
+	ChangePassword(ctx context.Context, request *model.PasswordChangeRequest) error
+	DeleteAccount(ctx context.Context, userID string) error
 	SignIn(ctx context.Context, signInData *model.SignInData) (*model.AuthToken, error)
-	SaveRefreshToken(userID string, refreshToken string) error
-	GenerateToken(studentID string) (string, error)
-	ValidateRefreshToken(refreshToken string) (string, error)
-	DeleteRefreshToken(userID string) error
+	CompleteTwoFactorSignIn(ctx context.Context, request *model.TwoFactorSignInRequest) (*model.AuthToken, error)
+	BeginChallengeEnrollment(ctx context.Context, challengeToken string) (*model.TwoFactorEnrollment, error)
+	BeginTwoFactorEnrollment(ctx context.Context, userID string) (*model.TwoFactorEnrollment, error)
+	EnableTwoFactor(ctx context.Context, userID string, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, userID string, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID string, code string) ([]string, error)
+	TwoFactorRequiredRoles(ctx context.Context) ([]string, error)
+	SetTwoFactorRequiredRoles(ctx context.Context, roles []string) ([]string, error)
+	OIDCLogin(ctx context.Context) (*model.OIDCLogin, error)
+	OIDCCallback(ctx context.Context, request *model.OIDCCallbackRequest) (*model.AuthToken, error)
+	CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time, createdBy string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+	IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*model.TokenIntrospection, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.AuthToken, error)
+	LogoutSession(ctx context.Context, userID, sessionID, jti string, expiresAt time.Time) error
+	LogoutAll(ctx context.Context, userID string) error
+	ListSessions(ctx context.Context, userID string) ([]*model.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	GenerateToken(student *model.Student, sessionID string) (string, error)
+	ValidateAccessToken(ctx context.Context, accessToken string) (*AccessClaims, error)
 	GetByEmail(ctx context.Context, email string) (*model.Student, error)
 	CheckEmailExistence(ctx context.Context, email string) (bool, error)
 }
 
-const jwtSecret = "dfhdfjhgdjkff"
+var (
+	ErrInvalidRefreshToken = apperror.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenReused  = apperror.Unauthorized("refresh_token_reused", "refresh token reused")
+	ErrTokenRevoked        = apperror.Unauthorized("token_revoked", "access token revoked")
+	ErrInvalidRole         = apperror.Validation("invalid_role", "invalid role")
+	ErrInvalidCredentials  = apperror.Unauthorized("invalid_credentials", "invalid email or password")
+	ErrInvalidResetToken   = apperror.Validation("invalid_reset_token", "invalid or expired password reset token")
+	ErrInvalidVerification = apperror.Validation("invalid_verification_token", "invalid or expired email verification token")
+	ErrAlreadyVerified     = apperror.Conflict("email_already_verified", "email already verified")
+	ErrEmailTaken          = apperror.Conflict("email_taken", "email already exists")
+	ErrStudentNotFound     = repository.ErrStudentNotFound
+	ErrSessionNotFound     = apperror.NotFound("session_not_found", "session not found")
+	ErrTooManyIDs          = apperror.Validation("too_many_ids", "at most %d IDs per request")
+)
+
+// LockoutError is returned by SignIn while the account or the client IP is
+// locked after too many failed attempts.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("sign-in locked for %s", e.RetryAfter)
+}
+
+func (e *LockoutError) AppError() *apperror.Error {
+	return apperror.TooManyRequests("account_locked", "too many failed sign-in attempts, try again later").Wrap(e)
+}
+
+// RateLimitError is returned when an action was repeated too often.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited for %s", e.RetryAfter)
+}
+
+func (e *RateLimitError) AppError() *apperror.Error {
+	return apperror.TooManyRequests("rate_limited", "too many requests, try again later").Wrap(e)
+}
+
+// WeakPasswordError is returned when a new password is rejected by the
+// password policy. Err wraps one of the password.Err* values.
+type WeakPasswordError struct {
+	Err error
+}
+
+func (e *WeakPasswordError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WeakPasswordError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errPasswordTooShort     = apperror.Validation("password_too_short", "password is too short: at least %d characters required")
+	errPasswordTooLong      = apperror.Validation("password_too_long", "password is too long: at most %d characters allowed")
+	errPasswordTooLongBytes = apperror.Validation("password_too_long_bytes", "password is too long: at most %d bytes allowed")
+	errPasswordTooCommon    = apperror.Validation("password_too_common", "password is too common")
+)
+
+// AppError tells the reasons of the policy apart, with the limit a
+// password broke, so clients can explain it in their language.
+func (e *WeakPasswordError) AppError() *apperror.Error {
+	var lengthErr *password.LengthError
+	switch {
+	case errors.As(e.Err, &lengthErr) && lengthErr.Err == password.ErrTooShort:
+		return errPasswordTooShort.With(lengthErr.Limit).Wrap(e)
+	case errors.As(e.Err, &lengthErr) && lengthErr.Unit == password.UnitBytes:
+		return errPasswordTooLongBytes.With(lengthErr.Limit).Wrap(e)
+	case errors.As(e.Err, &lengthErr):
+		return errPasswordTooLong.With(lengthErr.Limit).Wrap(e)
+	default:
+		return errPasswordTooCommon.Wrap(e)
+	}
+}
 
 type studentUsecase struct {
-	studentRepo  repository.StudentRepository
-	logger       *logrus.Logger
-	jwtSecret    []byte
-	jwtGenerator *jwt.Token
-	cache        *redis.Client
+	studentRepo         repository.StudentRepository
+	tokenRepo           repository.TokenRepository
+	attemptRepo         repository.LoginAttemptRepository
+	oneTimeRepo         repository.OneTimeTokenRepository
+	twoFactorRepo       repository.TwoFactorRepository
+	identityRepo        repository.IdentityRepository
+	apiKeyRepo          repository.APIKeyRepository
+	mailer              mailer.Mailer
+	logger              *logrus.Logger
+	jwtConfig           config.JWTConfig
+	lockoutConfig       config.LockoutConfig
+	passwordResetConfig config.PasswordResetConfig
+	verificationConfig  config.VerificationConfig
+	twoFactorConfig     config.TwoFactorConfig
+	oidcConfig          config.OIDCConfig
+	keySet              *keys.KeySet
+	// oidcClient is nil unless single sign-on is enabled.
+	oidcClient     *oidc.Client
+	passwordPolicy *password.Policy
+	hasher         *password.Hasher
+	// dummyHashes, one per hash algorithm, are checked when the email is
+	// unknown or its hash is of another algorithm, so that unknown emails
+	// take as long to reject as wrong passwords of any account.
+	dummyHashes map[string]string
 }
 
-func NewStudentUsecase(studentRepo repository.StudentRepository, logger *logrus.Logger, cache *redis.Client) StudentUsecase {
-	jwtGenerator := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{})
-	jwtGenerator.Claims.(jwt.MapClaims)["exp"] = time.Now().Add(time.Hour * 24).Unix()
+func NewStudentUsecase(studentRepo repository.StudentRepository, tokenRepo repository.TokenRepository, attemptRepo repository.LoginAttemptRepository, oneTimeRepo repository.OneTimeTokenRepository, twoFactorRepo repository.TwoFactorRepository, identityRepo repository.IdentityRepository, apiKeyRepo repository.APIKeyRepository, mailer mailer.Mailer, logger *logrus.Logger, cfg *config.Config, keySet *keys.KeySet, oidcClient *oidc.Client) (StudentUsecase, error) {
+	passwordPolicy, err := password.NewPolicy(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	hasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	dummyHashes, err := hasher.DummyHashes()
+	if err != nil {
+		return nil, err
+	}
 
 	return &studentUsecase{
-		studentRepo:  studentRepo,
-		logger:       logger,
-		jwtSecret:    []byte(jwtSecret),
-		jwtGenerator: jwtGenerator,
-		cache:        cache,
-	}
+		studentRepo:         studentRepo,
+		tokenRepo:           tokenRepo,
+		attemptRepo:         attemptRepo,
+		oneTimeRepo:         oneTimeRepo,
+		twoFactorRepo:       twoFactorRepo,
+		identityRepo:        identityRepo,
+		apiKeyRepo:          apiKeyRepo,
+		mailer:              mailer,
+		logger:              logger,
+		jwtConfig:           cfg.JWT,
+		lockoutConfig:       cfg.Lockout,
+		passwordResetConfig: cfg.PasswordReset,
+		verificationConfig:  cfg.Verification,
+		twoFactorConfig:     cfg.TwoFactor,
+		oidcConfig:          cfg.OIDC,
+		keySet:              keySet,
+		oidcClient:          oidcClient,
+		passwordPolicy:      passwordPolicy,
+		hasher:              hasher,
+		dummyHashes:         dummyHashes,
+	}, nil
 }
 
 func (u *studentUsecase) CreateStudent(ctx context.Context, student *model.Student) (*model.Student, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(student.Password), bcrypt.DefaultCost)
+	hashedPassword, err := u.hashNewPassword(ctx, student.Password)
 	if err != nil {
-		u.logger.Errorf("Error generating password hash: %v", err)
 		return nil, err
 	}
-	student.Password = string(hashedPassword)
+	student.Password = hashedPassword
+	// Roles are only granted by admins and addresses only verified by the
+	// owner, never through sign-up.
+	student.Role = model.RoleStudent
+	student.EmailVerified = false
+	student.PendingEmail = ""
+
+	createdStudent, err := u.studentRepo.CreateStudent(ctx, student)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.sendVerification(ctx, createdStudent, createdStudent.Email); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error sending verification email to student %s: %v", createdStudent.ID.Hex(), err)
+	}
+	return createdStudent, nil
+}
 
-	return u.studentRepo.CreateStudent(ctx, student)
+// hashNewPassword checks a password the student chose against the policy
+// and hashes it with the configured algorithm.
+func (u *studentUsecase) hashNewPassword(ctx context.Context, newPassword string) (string, error) {
+	if err := u.passwordPolicy.Check(newPassword); err != nil {
+		return "", &WeakPasswordError{Err: err}
+	}
+	hashedPassword, err := u.hasher.Hash(newPassword)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("Error generating password hash: %v", err)
+		return "", err
+	}
+	return hashedPassword, nil
 }
 
+// GetStudentByID returns ErrStudentNotFound for unknown IDs.
 func (u *studentUsecase) GetStudentByID(ctx context.Context, id string) (*model.Student, error) {
-	return u.studentRepo.GetStudentByID(ctx, id)
+	student, err := u.studentRepo.GetStudentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		return nil, ErrStudentNotFound
+	}
+	return student, nil
 }
 
 func (u *studentUsecase) GetStudentsByCourseID(ctx context.Context, id string) ([]*model.Student, error) {
 	return u.studentRepo.GetStudentsByCourseID(ctx, id)
 }
 
+// MaxBatchSize bounds the IDs of one GetStudentsByIDs call.
+const MaxBatchSize = 100
+
+// GetStudentsByIDs returns the students with the given IDs in the order of
+// ids, once each. Unknown and malformed IDs are left out.
+func (u *studentUsecase) GetStudentsByIDs(ctx context.Context, ids []string) ([]*model.Student, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, ErrTooManyIDs.With(MaxBatchSize)
+	}
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+	found, err := u.studentRepo.GetStudentsByIDs(ctx, objectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.Student, len(found))
+	for _, student := range found {
+		byID[student.ID.Hex()] = student
+	}
+	students := make([]*model.Student, 0, len(found))
+	for _, id := range ids {
+		if student, ok := byID[id]; ok {
+			students = append(students, student)
+			delete(byID, id)
+		}
+	}
+	return students, nil
+}
+
+// GetStudentsByCourseIDs returns the rosters of the given courses by
+// course ID, with a nil roster for courses nobody is enrolled in.
+func (u *studentUsecase) GetStudentsByCourseIDs(ctx context.Context, courseIDs []string) (map[string][]*model.Student, error) {
+	if len(courseIDs) > MaxBatchSize {
+		return nil, ErrTooManyIDs.With(MaxBatchSize)
+	}
+	return u.studentRepo.GetStudentsByCourseIDs(ctx, courseIDs)
+}
+
 func (u *studentUsecase) UpdateStudent(ctx context.Context, student_id string, student *model.Student) (*model.Student, error) {
-	studentforID, err := u.studentRepo.GetStudentByID(ctx, student_id)
+	studentforID, err := u.GetStudentByID(ctx, student_id)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("studentforID.ID: %v\n", studentforID.ID)
-	fmt.Printf("student in the usecase 2: %v\n", student)
 	return u.studentRepo.UpdateStudents(ctx, student, studentforID.ID)
 }
 
+// DeleteStudent deletes a student as DeleteAccount does, ending their
+// sessions first.
 func (u *studentUsecase) DeleteStudent(ctx context.Context, id string) error {
-	return u.studentRepo.Delete(ctx, id)
+	if err := u.deleteStudent(ctx, id); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Student %s deleted", id)
+	return nil
+}
+
+// SetRole changes the role of a student and logs them out everywhere, so no
+// token carrying the old role outlives the change.
+func (u *studentUsecase) SetRole(ctx context.Context, id string, role string) (*model.Student, error) {
+	if !model.IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	studentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrStudentNotFound
+	}
+
+	student, err := u.studentRepo.UpdateRole(ctx, studentID, role)
+	if err != nil {
+		return nil, err
+	}
+	u.logger.WithContext(ctx).Infof("Role of student %s changed to %s", id, role)
+
+	if err := u.LogoutAll(ctx, id); err != nil {
+		return nil, err
+	}
+	return student, nil
+}
+
+// UnlockAccount lifts a sign-in lockout of the student.
+func (u *studentUsecase) UnlockAccount(ctx context.Context, id string) error {
+	student, err := u.studentRepo.GetStudentByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if student == nil {
+		return ErrStudentNotFound
+	}
+
+	account := strings.ToLower(strings.TrimSpace(student.Email))
+	if err := u.attemptRepo.UnlockAccount(account); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Sign-in lock of student %s lifted", id)
+	return nil
 }
 
 func (u *studentUsecase) GetByEmail(ctx context.Context, email string) (*model.Student, error) {
@@ -94,85 +381,413 @@ func (u *studentUsecase) CheckEmailExistence(ctx context.Context, email string)
 	return u.studentRepo.CheckEmailExistence(ctx, email)
 }
 
+// SignIn checks the credentials and starts a new session. Unknown emails and
+// wrong passwords fail alike with ErrInvalidCredentials, after the same
+// hashing work and the same progressive delay, so responses do not reveal
+// which emails are registered.
 func (u *studentUsecase) SignIn(ctx context.Context, signInData *model.SignInData) (*model.AuthToken, error) {
-	student, err := u.studentRepo.GetByEmail(ctx, signInData.Email)
+	account := strings.ToLower(strings.TrimSpace(signInData.Email))
+
+	lockedFor, err := u.attemptRepo.LockedFor(account, signInData.ClientIP)
 	if err != nil {
-		u.logger.Errorf("Error retrieving student with email %s: %v", signInData.Email, nil)
-		return nil, nil
+		u.logger.WithContext(ctx).Errorf("Error checking sign-in lock for %s: %v", account, err)
+		return nil, err
+	}
+	if lockedFor > 0 {
+		return nil, &LockoutError{RetryAfter: lockedFor}
+	}
+
+	student, err := u.studentRepo.GetByEmail(ctx, signInData.Email)
+	if err != nil && err != mongo.ErrNoDocuments {
+		u.logger.WithContext(ctx).Errorf("Error retrieving student with email %s: %v", signInData.Email, err)
+		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(student.Password), []byte(signInData.Password))
+	ok, err := u.verifySignInPassword(student, signInData.Password)
+	if err != nil && student != nil {
+		u.logger.WithContext(ctx).Errorf("Error verifying password of student %s: %v", student.ID.Hex(), err)
+	}
+	if student == nil || !ok {
+		u.logger.WithContext(ctx).Warnf("Failed sign-in for email %s from %s", account, signInData.ClientIP)
+		return nil, u.signInFailed(ctx, account, signInData.ClientIP)
+	}
+
+	if u.hasher.NeedsRehash(student.Password) {
+		u.rehashPassword(ctx, student, signInData.Password)
+	}
+
+	// The failure counter is only reset once every factor passed, so
+	// signing in with the password again does not buy more code guesses.
+	challenge, err := u.twoFactorChallengeFor(ctx, student)
 	if err != nil {
-		u.logger.Errorf("Incorrect password for student with email %s", signInData.Email)
+		u.logger.WithContext(ctx).Errorf("Error checking two-factor authentication of student %s: %v", student.ID.Hex(), err)
 		return nil, err
 	}
+	if challenge != nil {
+		return nil, challenge
+	}
+
+	if err := u.attemptRepo.ResetAccount(account); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error resetting sign-in failures for %s: %v", account, err)
+	}
+	return u.startSession(ctx, student, signInData.ClientIP, signInData.UserAgent)
+}
+
+// verifySignInPassword checks plaintext against the hash of the student, if
+// there is one, and against the dummy hash of every other algorithm. Every
+// sign-in so costs one check of each algorithm, whether the email is unknown
+// or its account still has a legacy hash.
+func (u *studentUsecase) verifySignInPassword(student *model.Student, plaintext string) (bool, error) {
+	var ok bool
+	var err error
+	checked := ""
+	if student != nil {
+		ok, err = u.hasher.Verify(student.Password, plaintext)
+		checked = password.Algorithm(student.Password)
+	}
+	for algorithm, dummy := range u.dummyHashes {
+		if algorithm != checked {
+			u.hasher.Verify(dummy, plaintext)
+		}
+	}
+	return ok, err
+}
+
+// maxUserAgentLength bounds the user agent kept with a session.
+const maxUserAgentLength = 256
+
+// startSession creates a refresh token family for a student who passed
+// every sign-in factor and issues its first tokens. The client IP and user
+// agent are kept with the family so the student can tell sessions apart.
+func (u *studentUsecase) startSession(ctx context.Context, student *model.Student, clientIP, userAgent string) (*model.AuthToken, error) {
 	idStr := student.ID.Hex()
-	token, err := u.GenerateToken(idStr)
+	familyID, err := newOpaqueToken()
 	if err != nil {
 		return nil, err
 	}
-
-	authToken := &model.AuthToken{
-		UserID:    idStr,
-		Token:     token,
-		ExpiresAt: time.Now().Add(time.Hour * 1),
+	now := time.Now()
+	family := &repository.RefreshFamily{
+		ID:            familyID,
+		UserID:        idStr,
+		UserAgent:     truncate(userAgent, maxUserAgentLength),
+		IP:            clientIP,
+		CreatedAt:     now,
+		LastRefreshAt: now,
 	}
-	return authToken, nil
+	if err := u.tokenRepo.CreateFamily(family, u.jwtConfig.RefreshTTL); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error creating refresh token family for student %s: %v", idStr, err)
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, student, familyID)
 }
 
-func (u *studentUsecase) SaveRefreshToken(userID string, refreshToken string) error {
-	return u.cache.Set(userID, refreshToken, time.Hour*24*30).Err()
+// rehashPassword replaces a hash made with an outdated algorithm or cost.
+// The sign-in goes ahead if this fails; the next one tries again.
+func (u *studentUsecase) rehashPassword(ctx context.Context, student *model.Student, plain string) {
+	hashedPassword, err := u.hasher.Hash(plain)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("Error rehashing password of student %s: %v", student.ID.Hex(), err)
+		return
+	}
+	if err := u.studentRepo.UpdatePassword(ctx, student.ID, hashedPassword); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error storing rehashed password of student %s: %v", student.ID.Hex(), err)
+		return
+	}
+	student.Password = hashedPassword
+	u.logger.WithContext(ctx).Infof("Password hash of student %s upgraded", student.ID.Hex())
 }
 
-func (uc *studentUsecase) GenerateToken(studentID string) (string, error) {
-	// Generate a new JWT token for the given student
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["userID"] = studentID
-	claims["exp"] = time.Now().Add(time.Hour * 1).Unix()
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+// signInFailed records a failed attempt, locks the account or IP once their
+// limit is reached and holds the response back by the progressive delay.
+func (u *studentUsecase) signInFailed(ctx context.Context, account, ip string) error {
+	cfg := u.lockoutConfig
+	accountFailures, ipFailures, err := u.attemptRepo.RecordFailure(account, ip, cfg.Window)
 	if err != nil {
-		return "", err
+		u.logger.WithContext(ctx).Errorf("Error recording failed sign-in for %s: %v", account, err)
+		return err
 	}
-	return tokenString, nil
+
+	if cfg.MaxAccountFailures > 0 && accountFailures >= cfg.MaxAccountFailures {
+		u.logger.WithContext(ctx).Warnf("Locking sign-in for %s after %d failures", account, accountFailures)
+		if err := u.attemptRepo.LockAccount(account, cfg.LockoutDuration); err != nil {
+			return err
+		}
+	}
+	if cfg.MaxIPFailures > 0 && ipFailures >= cfg.MaxIPFailures {
+		u.logger.WithContext(ctx).Warnf("Locking sign-in from %s after %d failures", ip, ipFailures)
+		if err := u.attemptRepo.LockIP(ip, cfg.LockoutDuration); err != nil {
+			return err
+		}
+	}
+
+	if delay := progressiveDelay(cfg, accountFailures); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return ErrInvalidCredentials
 }
 
-func (u *studentUsecase) ValidateRefreshToken(refreshToken string) (string, error) {
-	// Проверяем, что refresh token является действительным
-	_, err := u.cache.Get(refreshToken).Result()
+func progressiveDelay(cfg config.LockoutConfig, failures int64) time.Duration {
+	if cfg.BaseDelay <= 0 || failures <= cfg.DelayAfter {
+		return 0
+	}
+	delay := cfg.BaseDelay
+	for i := cfg.DelayAfter + 1; i < failures && delay < cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if cfg.MaxDelay > 0 && delay > cfg.MaxDelay {
+		delay = cfg.MaxDelay
+	}
+	return delay
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token of the same family. Presenting a token that was already exchanged
+// means it leaked, so the whole family is revoked.
+func (u *studentUsecase) Refresh(ctx context.Context, refreshToken string) (*model.AuthToken, error) {
+	record, err := u.tokenRepo.UseRefreshToken(u.hashRefreshToken(refreshToken))
 	if err != nil {
-		if err == redis.Nil {
-			return "", errors.New("refresh token not found")
+		if err == repository.ErrRefreshTokenNotFound {
+			return nil, ErrInvalidRefreshToken
 		}
-		return "", err
+		return nil, err
+	}
+
+	if record.Used {
+		u.logger.WithContext(ctx).Warnf("Refresh token reuse detected for student %s, revoking family %s", record.UserID, record.FamilyID)
+		if err := u.tokenRepo.RevokeFamily(record.FamilyID); err != nil {
+			u.logger.WithContext(ctx).Errorf("Error revoking refresh token family %s: %v", record.FamilyID, err)
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// Claims are rebuilt from the current record, so a refresh picks up
+	// changes such as a verified email.
+	student, err := u.studentRepo.GetStudentByID(ctx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		if err := u.tokenRepo.RevokeFamily(record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return u.issueTokens(ctx, student, record.FamilyID)
+}
+
+// LogoutSession ends one session: the access token it was called with, every
+// other access token of the session and its refresh-token family.
+func (u *studentUsecase) LogoutSession(ctx context.Context, userID, sessionID, jti string, expiresAt time.Time) error {
+	if err := u.tokenRepo.DenyToken(jti, time.Until(expiresAt)); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error denylisting access token %s: %v", jti, err)
+		return err
+	}
+	if err := u.tokenRepo.DenySession(sessionID, u.jwtConfig.AccessTTL); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error denylisting session %s: %v", sessionID, err)
+		return err
 	}
+	return u.tokenRepo.RevokeFamily(sessionID)
+}
 
-	// Разбираем токен и проверяем, что он является действительным
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем, что токен был подписан нашим ключом
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+// LogoutAll ends every session of the student and rejects all access tokens
+// issued to them so far.
+func (u *studentUsecase) LogoutAll(ctx context.Context, userID string) error {
+	familyIDs, err := u.tokenRepo.ListFamilyIDs(userID)
+	if err != nil {
+		return err
+	}
+	for _, familyID := range familyIDs {
+		if err := u.tokenRepo.DenySession(familyID, u.jwtConfig.AccessTTL); err != nil {
+			u.logger.WithContext(ctx).Errorf("Error denylisting session %s: %v", familyID, err)
+			return err
+		}
+		if err := u.tokenRepo.RevokeFamily(familyID); err != nil {
+			u.logger.WithContext(ctx).Errorf("Error revoking refresh token family %s: %v", familyID, err)
+			return err
 		}
-		return []byte(os.Getenv("REFRESH_SECRET")), nil
+	}
+	return u.tokenRepo.RevokeUserTokensBefore(userID, time.Now(), u.jwtConfig.AccessTTL)
+}
+
+// ListSessions returns the active sessions of the student, newest first.
+func (u *studentUsecase) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	families, err := u.tokenRepo.ListFamilies(userID)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("Error listing sessions of student %s: %v", userID, err)
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(families))
+	for _, family := range families {
+		sessions = append(sessions, &model.Session{
+			ID:            family.ID,
+			UserAgent:     family.UserAgent,
+			IP:            family.IP,
+			CreatedAt:     family.CreatedAt,
+			LastRefreshAt: family.LastRefreshAt,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
 	})
+	return sessions, nil
+}
+
+// RevokeSession ends one session of the student, as LogoutSession does for
+// the current one: its refresh-token family is deleted and its access tokens
+// are denied. Sessions of other students are reported as not found.
+func (u *studentUsecase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	family, err := u.tokenRepo.GetFamily(sessionID)
+	if err == repository.ErrRefreshTokenNotFound {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if family.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	if err := u.tokenRepo.DenySession(sessionID, u.jwtConfig.AccessTTL); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error denylisting session %s: %v", sessionID, err)
+		return err
+	}
+	return u.tokenRepo.RevokeFamily(sessionID)
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+func (u *studentUsecase) issueTokens(ctx context.Context, student *model.Student, familyID string) (*model.AuthToken, error) {
+	userID := student.ID.Hex()
+	accessToken, err := u.GenerateToken(student, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	record := &repository.RefreshToken{
+		Hash:     u.hashRefreshToken(refreshToken),
+		UserID:   userID,
+		FamilyID: familyID,
+	}
+	err = u.tokenRepo.SaveRefreshToken(record, u.jwtConfig.RefreshTTL)
+	if err == repository.ErrRefreshTokenNotFound {
+		// The session was ended while this refresh was under way.
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("Error saving refresh token for student %s: %v", userID, err)
+		return nil, err
+	}
+
+	return &model.AuthToken{
+		UserID:       userID,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(u.jwtConfig.AccessTTL),
+	}, nil
+}
+
+// hashRefreshToken keys refresh tokens in Redis by their HMAC, so a dump of
+// Redis does not contain usable tokens.
+func (u *studentUsecase) hashRefreshToken(refreshToken string) string {
+	mac := hmac.New(sha256.New, []byte(u.jwtConfig.RefreshSecret))
+	mac.Write([]byte(refreshToken))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (uc *studentUsecase) GenerateToken(student *model.Student, sessionID string) (string, error) {
+	// Generate a new JWT token for the given student
+	jti, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := &AccessClaims{
+		Subject:       student.ID.Hex(),
+		Roles:         []string{student.EffectiveRole()},
+		Tenant:        uc.jwtConfig.Tenant,
+		ID:            jti,
+		SessionID:     sessionID,
+		EmailVerified: student.EmailVerified,
+		Issuer:        uc.jwtConfig.Issuer,
+		Audience:      Audience{uc.jwtConfig.Audience},
+		IssuedAt:      now.Unix(),
+		NotBefore:     now.Unix(),
+		ExpiresAt:     now.Add(uc.jwtConfig.AccessTTL).Unix(),
+	}
+	tokenString, err := uc.keySet.Sign(claims)
 	if err != nil {
 		return "", err
 	}
+	return tokenString, nil
+}
 
-	// Извлекаем идентификатор пользователя из токена
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return "", errors.New("invalid refresh token")
+// ValidateAccessToken verifies the signature of an access token against the
+// key named by its kid header and checks its registered claims, allowing
+// the configured clock skew, and the denylist.
+func (u *studentUsecase) ValidateAccessToken(ctx context.Context, accessToken string) (*AccessClaims, error) {
+	claims, err := u.parseAccessToken(accessToken)
+	if err != nil {
+		return nil, err
 	}
 
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return "", errors.New("invalid refresh token")
+	revoked, err := u.isAccessTokenRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
 	}
+	return claims, nil
+}
 
-	return userID, nil
+// parseAccessToken checks everything about an access token but the
+// denylist.
+func (u *studentUsecase) parseAccessToken(accessToken string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	if _, err := parser.ParseWithClaims(accessToken, claims, u.keySet.Keyfunc); err != nil {
+		// Malformed tokens, bad signatures and unknown keys are all the
+		// client's fault.
+		return nil, ErrInvalidAccessToken.Wrap(err)
+	}
+	if err := claims.validate(time.Now(), u.jwtConfig.ClockSkew, u.jwtConfig.Issuer, u.jwtConfig.Audience, u.jwtConfig.Tenant); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
 
-func (u *studentUsecase) DeleteRefreshToken(userID string) error {
-	return u.cache.Del(userID).Err() // удаляем ключ из Redis
+func (u *studentUsecase) isAccessTokenRevoked(ctx context.Context, claims *AccessClaims) (bool, error) {
+	revoked, err := u.tokenRepo.IsAccessTokenRevoked(claims.ID, claims.SessionID, claims.Subject, time.Unix(claims.IssuedAt, 0))
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("Error checking access token denylist: %v", err)
+		return false, err
+	}
+	return revoked, nil
 }
@@ -5,8 +5,7 @@ import (
 	"reflect"
 	"testing"
 
-	jwt "github.com/dgrijalva/jwt-go"
-	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/config"
 	"github.com/nurmeden/students-service/internal/app/model"
 	"github.com/nurmeden/students-service/internal/app/repository"
 	"github.com/sirupsen/logrus"
@@ -14,11 +13,10 @@ import (
 
 func Test_studentUsecase_SignIn(t *testing.T) {
 	type fields struct {
-		studentRepo  repository.StudentRepository
-		logger       *logrus.Logger
-		jwtSecret    []byte
-		jwtGenerator *jwt.Token
-		cache        *redis.Client
+		studentRepo repository.StudentRepository
+		tokenRepo   repository.TokenRepository
+		logger      *logrus.Logger
+		jwtConfig   config.JWTConfig
 	}
 	type args struct {
 		signInData *model.SignInData
@@ -35,11 +33,10 @@ func Test_studentUsecase_SignIn(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			u := &studentUsecase{
-				studentRepo:  tt.fields.studentRepo,
-				logger:       tt.fields.logger,
-				jwtSecret:    tt.fields.jwtSecret,
-				jwtGenerator: tt.fields.jwtGenerator,
-				cache:        tt.fields.cache,
+				studentRepo: tt.fields.studentRepo,
+				tokenRepo:   tt.fields.tokenRepo,
+				logger:      tt.fields.logger,
+				jwtConfig:   tt.fields.jwtConfig,
 			}
 			got, err := u.SignIn(context.Background(), tt.args.signInData)
 			if (err != nil) != tt.wantErr {
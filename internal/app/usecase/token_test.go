@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/keys"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tokenFixture is a usecase backed by miniredis, with the Redis client
+// exposed so tests can seed cached students.
+type tokenFixture struct {
+	*studentUsecase
+	cache *redis.Client
+}
+
+func newTokenTestUsecase(t *testing.T) *tokenFixture {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { cache.Close() })
+
+	dir := t.TempDir()
+	_, err := keys.Generate(dir, keys.AlgEdDSA)
+	require.NoError(t, err)
+	keySet, err := keys.Load(dir)
+	require.NoError(t, err)
+
+	// The client never connects: students are served from the Redis cache,
+	// which the tests seed with seedStudent.
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	studentRepo, err := repository.NewStudentRepository(client, "studentsdb", "students", cache, logger)
+	require.NoError(t, err)
+	u := &studentUsecase{
+		studentRepo:   *studentRepo,
+		tokenRepo:     *repository.NewTokenRepository(cache, logger),
+		attemptRepo:   *repository.NewLoginAttemptRepository(cache, logger),
+		oneTimeRepo:   *repository.NewOneTimeTokenRepository(cache, logger),
+		twoFactorRepo: *repository.NewTwoFactorRepository(client, "studentsdb", logger),
+		identityRepo:  *repository.NewIdentityRepository(client, "studentsdb", logger),
+		logger:        logger,
+		jwtConfig: config.JWTConfig{
+			RefreshSecret: "test-refresh-secret",
+			Issuer:        "students-service",
+			Audience:      "students-api",
+			AccessTTL:     time.Minute,
+			RefreshTTL:    time.Hour,
+		},
+		lockoutConfig: config.LockoutConfig{
+			MaxAccountFailures: 3,
+			MaxIPFailures:      5,
+			Window:             time.Minute,
+			LockoutDuration:    time.Minute,
+		},
+		keySet: keySet,
+	}
+	return &tokenFixture{studentUsecase: u, cache: cache}
+}
+
+var testStudentIDs = map[string]primitive.ObjectID{
+	"student-1": primitive.NewObjectID(),
+	"student-2": primitive.NewObjectID(),
+}
+
+func seedStudent(t *testing.T, u *tokenFixture, student *model.Student) {
+	t.Helper()
+
+	data, err := json.Marshal(student)
+	require.NoError(t, err)
+	require.NoError(t, u.cache.Set(student.ID.Hex(), data, 0).Err())
+}
+
+func signInForTest(t *testing.T, u *tokenFixture, name string) *model.AuthToken {
+	t.Helper()
+
+	student := &model.Student{ID: testStudentIDs[name], Email: name + "@example.com", Role: model.RoleStudent}
+	seedStudent(t, u, student)
+
+	familyID, err := newOpaqueToken()
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, u.tokenRepo.CreateFamily(&repository.RefreshFamily{ID: familyID, UserID: student.ID.Hex(), CreatedAt: now, LastRefreshAt: now}, time.Hour))
+	authToken, err := u.issueTokens(context.Background(), student, familyID)
+	require.NoError(t, err)
+	return authToken
+}
+
+func Test_studentUsecase_RefreshRotates(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	first := signInForTest(t, u, "student-1")
+
+	second, err := u.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, testStudentIDs["student-1"].Hex(), second.UserID)
+
+	claims, err := u.ValidateAccessToken(ctx, second.Token)
+	require.NoError(t, err)
+	assert.Equal(t, testStudentIDs["student-1"].Hex(), claims.Subject)
+
+	third, err := u.Refresh(ctx, second.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEmpty(t, third.RefreshToken)
+}
+
+func Test_studentUsecase_RefreshReuseRevokesFamily(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	first := signInForTest(t, u, "student-1")
+	other := signInForTest(t, u, "student-1")
+
+	second, err := u.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+
+	_, err = u.Refresh(ctx, first.RefreshToken)
+	assert.Equal(t, ErrRefreshTokenReused, err)
+
+	_, err = u.Refresh(ctx, second.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err, "the whole family must be revoked")
+
+	_, err = u.Refresh(ctx, other.RefreshToken)
+	assert.NoError(t, err, "other sign-ins of the same student are unaffected")
+}
+
+func Test_studentUsecase_RefreshUnknownToken(t *testing.T) {
+	u := newTokenTestUsecase(t)
+
+	_, err := u.Refresh(context.Background(), "not-a-token")
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func Test_studentUsecase_LogoutSession(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	current := signInForTest(t, u, "student-1")
+	other := signInForTest(t, u, "student-1")
+
+	claims, err := u.ValidateAccessToken(ctx, current.Token)
+	require.NoError(t, err)
+	require.NoError(t, u.LogoutSession(ctx, testStudentIDs["student-1"].Hex(), claims.SessionID, claims.ID, time.Now().Add(time.Minute)))
+
+	_, err = u.ValidateAccessToken(ctx, current.Token)
+	assert.Equal(t, ErrTokenRevoked, err)
+	_, err = u.Refresh(ctx, current.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	_, err = u.ValidateAccessToken(ctx, other.Token)
+	assert.NoError(t, err, "other sessions stay logged in")
+}
+
+func Test_studentUsecase_LogoutAll(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	first := signInForTest(t, u, "student-1")
+	second := signInForTest(t, u, "student-1")
+	stranger := signInForTest(t, u, "student-2")
+
+	require.NoError(t, u.LogoutAll(ctx, testStudentIDs["student-1"].Hex()))
+
+	for _, authToken := range []*model.AuthToken{first, second} {
+		_, err := u.ValidateAccessToken(ctx, authToken.Token)
+		assert.Equal(t, ErrTokenRevoked, err)
+		_, err = u.Refresh(ctx, authToken.RefreshToken)
+		assert.Equal(t, ErrInvalidRefreshToken, err)
+	}
+
+	_, err := u.ValidateAccessToken(ctx, stranger.Token)
+	assert.NoError(t, err)
+
+	again := signInForTest(t, u, "student-1")
+	_, err = u.ValidateAccessToken(ctx, again.Token)
+	assert.NoError(t, err, "signing in right after the logout works")
+}
+
+func Test_studentUsecase_Sessions(t *testing.T) {
+	u := newTokenTestUsecase(t)
+	ctx := context.Background()
+	student := &model.Student{ID: testStudentIDs["student-1"], Email: "student-1@example.com", Role: model.RoleStudent}
+	seedStudent(t, u, student)
+	userID := student.ID.Hex()
+
+	laptop, err := u.startSession(ctx, student, "10.0.0.1", "Firefox")
+	require.NoError(t, err)
+	phone, err := u.startSession(ctx, student, "10.0.0.2", "Mobile Safari")
+	require.NoError(t, err)
+	stranger := signInForTest(t, u, "student-2")
+
+	sessions, err := u.ListSessions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	byAgent := map[string]*model.Session{}
+	for _, session := range sessions {
+		byAgent[session.UserAgent] = session
+	}
+	require.Contains(t, byAgent, "Firefox")
+	assert.Equal(t, "10.0.0.1", byAgent["Firefox"].IP)
+	assert.False(t, byAgent["Firefox"].CreatedAt.IsZero())
+
+	strangerClaims, err := u.ValidateAccessToken(ctx, stranger.Token)
+	require.NoError(t, err)
+	assert.Equal(t, ErrSessionNotFound, u.RevokeSession(ctx, userID, strangerClaims.SessionID))
+	assert.Equal(t, ErrSessionNotFound, u.RevokeSession(ctx, userID, "unknown"))
+
+	require.NoError(t, u.RevokeSession(ctx, userID, byAgent["Firefox"].ID))
+
+	_, err = u.ValidateAccessToken(ctx, laptop.Token)
+	assert.Equal(t, ErrTokenRevoked, err)
+	_, err = u.Refresh(ctx, laptop.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	_, err = u.ValidateAccessToken(ctx, phone.Token)
+	assert.NoError(t, err, "other sessions stay signed in")
+	sessions, err = u.ListSessions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Mobile Safari", sessions[0].UserAgent)
+}
@@ -0,0 +1,425 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/app/repository"
+	"github.com/nurmeden/students-service/internal/app/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const purposeTwoFactorChallenge = "two_factor_challenge"
+
+const (
+	defaultRecoveryCodeCount  = 10
+	defaultTwoFactorChallenge = 5 * time.Minute
+)
+
+var (
+	ErrInvalidChallenge      = apperror.Unauthorized("invalid_challenge", "invalid or expired two-factor challenge")
+	ErrInvalidTwoFactorCode  = apperror.Unauthorized("invalid_two_factor_code", "invalid two-factor code")
+	ErrTwoFactorEnabled      = apperror.Conflict("two_factor_enabled", "two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled   = apperror.Conflict("two_factor_not_enabled", "two-factor authentication not enabled")
+	ErrTwoFactorRequired     = apperror.Forbidden("two_factor_required", "two-factor authentication is required for this role")
+	ErrNoTwoFactorEnrollment = apperror.Conflict("no_two_factor_enrollment", "no two-factor enrollment in progress")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorRequiredError is returned by SignIn when the password was right
+// but the account also has to pass a second factor. The challenge token is
+// redeemed with CompleteTwoFactorSignIn; when EnrollmentRequired is set the
+// student has to enroll first through BeginChallengeEnrollment.
+type TwoFactorRequiredError struct {
+	ChallengeToken     string
+	EnrollmentRequired bool
+	ExpiresAt          time.Time
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+type twoFactorChallenge struct {
+	UserID string `json:"userID"`
+	Enroll bool   `json:"enroll"`
+}
+
+// twoFactorChallengeFor returns the challenge the student has to pass
+// after the password, or nil when the password is enough.
+func (u *studentUsecase) twoFactorChallengeFor(ctx context.Context, student *model.Student) (*TwoFactorRequiredError, error) {
+	twoFactor, err := u.twoFactorRepo.Get(ctx, student.ID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := twoFactor != nil && twoFactor.Enabled
+	required, err := u.twoFactorRequired(ctx, student.EffectiveRole())
+	if err != nil {
+		return nil, err
+	}
+	if !enabled && !required {
+		return nil, nil
+	}
+
+	challengeToken, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(twoFactorChallenge{UserID: student.ID.Hex(), Enroll: !enabled})
+	if err != nil {
+		return nil, err
+	}
+	ttl := u.twoFactorConfig.ChallengeTTL
+	if ttl == 0 {
+		ttl = defaultTwoFactorChallenge
+	}
+	if err := u.oneTimeRepo.Save(purposeTwoFactorChallenge, student.ID.Hex(), hashOneTimeToken(challengeToken), string(value), ttl); err != nil {
+		return nil, err
+	}
+	return &TwoFactorRequiredError{
+		ChallengeToken:     challengeToken,
+		EnrollmentRequired: !enabled,
+		ExpiresAt:          time.Now().Add(ttl),
+	}, nil
+}
+
+// CompleteTwoFactorSignIn finishes a sign-in that SignIn answered with a
+// TwoFactorRequiredError. Wrong codes count as failed sign-ins of the
+// account. If the challenge required enrollment, the code confirms the new
+// secret and the recovery codes come back with the tokens.
+func (u *studentUsecase) CompleteTwoFactorSignIn(ctx context.Context, request *model.TwoFactorSignInRequest) (*model.AuthToken, error) {
+	challengeHash := hashOneTimeToken(request.ChallengeToken)
+	challenge, student, err := u.loadChallenge(ctx, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	account := strings.ToLower(strings.TrimSpace(student.Email))
+	lockedFor, err := u.attemptRepo.LockedFor(account, request.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+	if lockedFor > 0 {
+		return nil, &LockoutError{RetryAfter: lockedFor}
+	}
+
+	var recoveryCodes []string
+	if challenge.Enroll {
+		recoveryCodes, err = u.enableTwoFactor(ctx, student, request.Code)
+	} else {
+		err = u.checkSecondFactor(ctx, student.ID, request.Code)
+	}
+	if err == ErrInvalidTwoFactorCode {
+		u.logger.WithContext(ctx).Warnf("Failed two-factor sign-in for email %s from %s", account, request.ClientIP)
+		if err := u.signInFailed(ctx, account, request.ClientIP); err != ErrInvalidCredentials {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := u.oneTimeRepo.Consume(purposeTwoFactorChallenge, challengeHash); err == repository.ErrOneTimeTokenNotFound {
+		return nil, ErrInvalidChallenge
+	} else if err != nil {
+		return nil, err
+	}
+	if err := u.attemptRepo.ResetAccount(account); err != nil {
+		u.logger.WithContext(ctx).Errorf("Error resetting sign-in failures for %s: %v", account, err)
+	}
+
+	authToken, err := u.startSession(ctx, student, request.ClientIP, request.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	authToken.RecoveryCodes = recoveryCodes
+	return authToken, nil
+}
+
+// BeginChallengeEnrollment starts the enrollment a sign-in challenge asks
+// for, for students whose role requires two-factor authentication.
+func (u *studentUsecase) BeginChallengeEnrollment(ctx context.Context, challengeToken string) (*model.TwoFactorEnrollment, error) {
+	challenge, student, err := u.loadChallenge(ctx, hashOneTimeToken(challengeToken))
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.Enroll {
+		return nil, ErrInvalidChallenge
+	}
+	return u.beginEnrollment(ctx, student)
+}
+
+func (u *studentUsecase) BeginTwoFactorEnrollment(ctx context.Context, userID string) (*model.TwoFactorEnrollment, error) {
+	student, err := u.studentForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.beginEnrollment(ctx, student)
+}
+
+// EnableTwoFactor confirms the pending secret with a code from the
+// authenticator app and returns fresh recovery codes.
+func (u *studentUsecase) EnableTwoFactor(ctx context.Context, userID string, code string) ([]string, error) {
+	student, err := u.studentForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.enableTwoFactor(ctx, student, code)
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a
+// current code. Students whose role requires it cannot turn it off.
+func (u *studentUsecase) DisableTwoFactor(ctx context.Context, userID string, code string) error {
+	student, err := u.studentForTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	required, err := u.twoFactorRequired(ctx, student.EffectiveRole())
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if err := u.checkSecondFactor(ctx, student.ID, code); err != nil {
+		return err
+	}
+	if err := u.twoFactorRepo.Disable(ctx, student.ID); err != nil {
+		return err
+	}
+	u.logger.WithContext(ctx).Infof("Two-factor authentication of student %s disabled", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// current code.
+func (u *studentUsecase) RegenerateRecoveryCodes(ctx context.Context, userID string, code string) ([]string, error) {
+	student, err := u.studentForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkSecondFactor(ctx, student.ID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := u.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.twoFactorRepo.ReplaceRecoveryCodes(ctx, student.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// TwoFactorRequiredRoles returns the roles that must sign in with a second
+// factor: the roles an admin set, or the configured default.
+func (u *studentUsecase) TwoFactorRequiredRoles(ctx context.Context) ([]string, error) {
+	roles, found, err := u.twoFactorRepo.RequiredRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		roles = u.twoFactorConfig.RequiredRoles
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return roles, nil
+}
+
+// SetTwoFactorRequiredRoles replaces the roles that must sign in with a
+// second factor. Students of those roles without an enrollment are asked
+// to enroll at their next sign-in.
+func (u *studentUsecase) SetTwoFactorRequiredRoles(ctx context.Context, roles []string) ([]string, error) {
+	unique := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !model.IsValidRole(role) {
+			return nil, ErrInvalidRole
+		}
+		if !hasString(unique, role) {
+			unique = append(unique, role)
+		}
+	}
+	if err := u.twoFactorRepo.SetRequiredRoles(ctx, unique); err != nil {
+		return nil, err
+	}
+	u.logger.WithContext(ctx).Infof("Two-factor authentication required for roles %v", unique)
+	return unique, nil
+}
+
+func (u *studentUsecase) twoFactorRequired(ctx context.Context, role string) (bool, error) {
+	roles, err := u.TwoFactorRequiredRoles(ctx)
+	if err != nil {
+		return false, err
+	}
+	return hasString(roles, role), nil
+}
+
+func (u *studentUsecase) loadChallenge(ctx context.Context, challengeHash string) (*twoFactorChallenge, *model.Student, error) {
+	value, err := u.oneTimeRepo.Get(purposeTwoFactorChallenge, challengeHash)
+	if err == repository.ErrOneTimeTokenNotFound {
+		return nil, nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var challenge twoFactorChallenge
+	if err := json.Unmarshal([]byte(value), &challenge); err != nil {
+		return nil, nil, err
+	}
+	student, err := u.studentRepo.GetStudentByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if student == nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	return &challenge, student, nil
+}
+
+func (u *studentUsecase) studentForTwoFactor(ctx context.Context, userID string) (*model.Student, error) {
+	student, err := u.studentRepo.GetStudentByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		return nil, ErrStudentNotFound
+	}
+	return student, nil
+}
+
+func (u *studentUsecase) beginEnrollment(ctx context.Context, student *model.Student) (*model.TwoFactorEnrollment, error) {
+	twoFactor, err := u.twoFactorRepo.Get(ctx, student.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.twoFactorRepo.SetPendingSecret(ctx, student.ID, secret); err != nil {
+		return nil, err
+	}
+	return &model.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(u.twoFactorConfig.Issuer, student.Email, secret),
+	}, nil
+}
+
+func (u *studentUsecase) enableTwoFactor(ctx context.Context, student *model.Student, code string) ([]string, error) {
+	twoFactor, err := u.twoFactorRepo.Get(ctx, student.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if twoFactor == nil || twoFactor.PendingSecret == "" {
+		return nil, ErrNoTwoFactorEnrollment
+	}
+
+	step, ok := totp.Validate(twoFactor.PendingSecret, normalizeCode(code), time.Now(), u.twoFactorConfig.Skew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, hashes, err := u.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := u.twoFactorRepo.Enable(ctx, student.ID, twoFactor.PendingSecret, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		// Another enrollment replaced the secret in the meantime.
+		return nil, ErrNoTwoFactorEnrollment
+	}
+	u.logger.WithContext(ctx).Infof("Two-factor authentication of student %s enabled", student.ID.Hex())
+	return codes, nil
+}
+
+// checkSecondFactor accepts a TOTP code whose step was not used before, or
+// an unused recovery code, which is used up.
+func (u *studentUsecase) checkSecondFactor(ctx context.Context, studentID primitive.ObjectID, code string) error {
+	twoFactor, err := u.twoFactorRepo.Get(ctx, studentID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now(), u.twoFactorConfig.Skew)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		fresh, err := u.twoFactorRepo.UseStep(ctx, studentID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := u.twoFactorRepo.UseRecoveryCode(ctx, studentID, hashOneTimeToken(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	u.logger.WithContext(ctx).Warnf("Recovery code used by student %s, %d left", studentID.Hex(), len(twoFactor.RecoveryCodes)-1)
+	return nil
+}
+
+// newRecoveryCodes returns codes to show to the student once, formatted as
+// xxxxx-xxxxx, and their hashes to store.
+func (u *studentUsecase) newRecoveryCodes() (codes []string, hashes []string, err error) {
+	count := u.twoFactorConfig.RecoveryCodes
+	if count == 0 {
+		count = defaultRecoveryCodeCount
+	}
+	for i := 0; i < count; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw)[:10])
+		codes = append(codes, fmt.Sprintf("%s-%s", code[:5], code[5:]))
+		hashes = append(hashes, hashOneTimeToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode strips the separators people type or paste along with a
+// code, so "123 456" and "abcde-fghij" are accepted.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
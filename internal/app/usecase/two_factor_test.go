@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_studentUsecase_newRecoveryCodes(t *testing.T) {
+	u := &studentUsecase{twoFactorConfig: config.TwoFactorConfig{RecoveryCodes: 8}}
+
+	codes, hashes, err := u.newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, 8)
+	require.Len(t, hashes, 8)
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		assert.False(t, seen[code], "codes are unique")
+		seen[code] = true
+		assert.Equal(t, hashes[i], hashOneTimeToken(normalizeCode(code)), "the stored hash matches the code as typed")
+		assert.Equal(t, hashes[i], hashOneTimeToken(normalizeCode(" "+code[:5]+" "+code[6:])), "separators do not matter")
+	}
+}
+
+func Test_normalizeCode(t *testing.T) {
+	assert.Equal(t, "123456", normalizeCode(" 123 456 "))
+	assert.Equal(t, "abcdefghij", normalizeCode("ABCDE-FGHIJ"))
+}
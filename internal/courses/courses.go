@@ -0,0 +1,84 @@
+// Package courses is the client of the courses service, which owns the
+// courses students enroll in.
+package courses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/apperror"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/requestid"
+)
+
+var ErrUnavailable = apperror.Unavailable("courses_unavailable", "courses service is unavailable")
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(cfg config.CoursesConfig, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// GetCourses fetches the courses with the given IDs in one request, in no
+// particular order. Unknown IDs are left out. Failures of the courses
+// service are ErrUnavailable.
+func (c *Client) GetCourses(ctx context.Context, ids []string) ([]model.Course, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := url.Values{}
+	query.Set("ids", strings.Join(ids, ","))
+
+	var resp model.CourseResponse
+	if err := c.getJSON(ctx, c.baseURL+"/courses?"+query.Encode(), &resp); err != nil {
+		return nil, ErrUnavailable.Wrap(err)
+	}
+	return resp.CourseData, nil
+}
+
+// GetStudentCourses fetches the courses the student is enrolled in.
+// Failures of the courses service are ErrUnavailable.
+func (c *Client) GetStudentCourses(ctx context.Context, studentID string) (*model.CourseResponse, error) {
+	var resp model.CourseResponse
+	if err := c.getJSON(ctx, c.baseURL+"/courses/"+url.PathEscape(studentID)+"/courses", &resp); err != nil {
+		return nil, ErrUnavailable.Wrap(err)
+	}
+	return &resp, nil
+}
+
+// getJSON forwards the request ID of ctx, so the logs of both services can
+// be matched up.
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if requestID := requestid.FromContext(ctx); requestID != "" {
+		req.Header.Set(requestid.Header, requestID)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	return nil
+}
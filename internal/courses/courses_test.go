@@ -0,0 +1,67 @@
+package courses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/app/model"
+	"github.com/nurmeden/students-service/internal/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetCourses(t *testing.T) {
+	var gotIDs string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/courses", r.URL.Path)
+		gotIDs = r.URL.Query().Get("ids")
+		w.Write([]byte(`{"data":[{"id":"c1","name":"Algebra","description":"","students":["s1"]}]}`))
+	}))
+	defer server.Close()
+	client := NewClient(config.CoursesConfig{BaseURL: server.URL + "/api/"}, server.Client())
+
+	courses, err := client.GetCourses(context.Background(), []string{"c1", "c2"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "c1,c2", gotIDs)
+	assert.Equal(t, []model.Course{{ID: "c1", Name: "Algebra", Description: "", Students: []string{"s1"}}}, courses)
+}
+
+func TestClient_GetStudentCourses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/courses/s1/courses", r.URL.Path)
+		assert.Equal(t, "req-1", r.Header.Get(requestid.Header))
+		w.Write([]byte(`{"data":[{"id":"c1","name":"Algebra","description":"","students":["s1"]}]}`))
+	}))
+	defer server.Close()
+	client := NewClient(config.CoursesConfig{BaseURL: server.URL + "/api"}, server.Client())
+
+	resp, err := client.GetStudentCourses(requestid.NewContext(context.Background(), "req-1"), "s1")
+
+	require.NoError(t, err)
+	assert.Equal(t, []model.Course{{ID: "c1", Name: "Algebra", Description: "", Students: []string{"s1"}}}, resp.CourseData)
+}
+
+func TestClient_GetCoursesUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client := NewClient(config.CoursesConfig{BaseURL: server.URL}, server.Client())
+
+	_, err := client.GetCourses(context.Background(), []string{"c1"})
+
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestClient_GetCoursesWithoutIDs(t *testing.T) {
+	client := NewClient(config.CoursesConfig{BaseURL: "http://127.0.0.1:1"}, http.DefaultClient)
+
+	courses, err := client.GetCourses(context.Background(), nil)
+
+	require.NoError(t, err)
+	assert.Empty(t, courses)
+}
@@ -8,8 +8,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func SetupDatabase(ctx context.Context) (*mongo.Client, error) {
-	co := options.Client().ApplyURI("mongodb://localhost:27017")
+func SetupDatabase(ctx context.Context, uri string) (*mongo.Client, error) {
+	co := options.Client().ApplyURI(uri)
 	client, err := mongo.Connect(ctx, co)
 	if err != nil {
 		fmt.Printf("Failed to connect to MongoDB: %v", err)
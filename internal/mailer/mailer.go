@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email such as password reset links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by cfg.Driver. The file and log drivers
+// are meant for development and tests; Config.Validate refuses them in
+// production mode.
+func New(cfg config.MailerConfig, logger *logrus.Logger) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.FilePath), nil
+	case DriverLog, "":
+		return NewLogMailer(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nurmeden/students-service/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_FileDriver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	m, err := New(config.MailerConfig{Driver: DriverFile, FilePath: path}, logrus.New())
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "student@example.com", Subject: "Hello", Body: "first"}))
+	require.NoError(t, m.Send(context.Background(), Message{To: "student@example.com", Subject: "Hello", Body: "second"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "To: student@example.com"))
+	assert.Contains(t, string(data), "second")
+}
+
+func TestNew_UnknownDriver(t *testing.T) {
+	_, err := New(config.MailerConfig{Driver: "carrier-pigeon"}, logrus.New())
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/nurmeden/students-service/config"
+	"github.com/nurmeden/students-service/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, clientSecret string) (*Client, *oidctest.Provider) {
+	t.Helper()
+	provider := oidctest.NewProvider(t, "students-service", clientSecret)
+	provider.Claims = jwt.MapClaims{"sub": "u-42", "email": "student@uni.example", "email_verified": true, "given_name": "Aigerim", "family_name": "Sadykova"}
+	client := NewClient(config.OIDCConfig{
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "students-service",
+		ClientSecret: clientSecret,
+		RedirectURL:  "http://localhost/api/auth/oidc/callback",
+	}, provider.Server.Client())
+	return client, provider
+}
+
+// signIn runs the authorization code flow up to the token exchange.
+func signIn(t *testing.T, client *Client, provider *oidctest.Provider, nonce string) *Tokens {
+	t.Helper()
+	ctx := context.Background()
+	verifier, err := NewRandom()
+	require.NoError(t, err)
+
+	authURL, err := client.AuthCodeURL(ctx, "state-1", nonce, CodeChallenge(verifier))
+	require.NoError(t, err)
+	code, state, err := provider.Authorize(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", state)
+
+	tokens, err := client.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+	return tokens
+}
+
+func TestClient_CodeFlow(t *testing.T) {
+	for _, secret := range []string{"s3cret", ""} {
+		client, provider := newTestClient(t, secret)
+
+		tokens := signIn(t, client, provider, "nonce-1")
+		claims, err := client.Verify(context.Background(), tokens.IDToken, "nonce-1")
+		require.NoError(t, err)
+		assert.Equal(t, &Claims{Subject: "u-42", Email: "student@uni.example", EmailVerified: true, GivenName: "Aigerim", FamilyName: "Sadykova"}, claims)
+	}
+}
+
+func TestClient_AuthCodeURL(t *testing.T) {
+	client, provider := newTestClient(t, "s3cret")
+
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", "challenge")
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, provider.Issuer()+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "openid email profile", parsed.Query().Get("scope"))
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+}
+
+func TestClient_ExchangeRejectsWrongVerifier(t *testing.T) {
+	client, provider := newTestClient(t, "s3cret")
+	ctx := context.Background()
+
+	authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallenge("right-verifier"))
+	require.NoError(t, err)
+	code, _, err := provider.Authorize(authURL)
+	require.NoError(t, err)
+
+	_, err = client.Exchange(ctx, code, "wrong-verifier")
+	assert.Error(t, err)
+}
+
+func TestClient_DiscoveryIssuerMismatch(t *testing.T) {
+	provider := oidctest.NewProvider(t, "students-service", "")
+	client := NewClient(config.OIDCConfig{IssuerURL: provider.Issuer() + "/other", ClientID: "students-service"}, provider.Server.Client())
+
+	_, err := client.Provider(context.Background())
+	assert.Error(t, err)
+}
+
+func TestClient_VerifyRejects(t *testing.T) {
+	client, provider := newTestClient(t, "s3cret")
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"iss": provider.Issuer(), "aud": "students-service", "sub": "u-42", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix(), "nonce": "nonce-1"}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		token  func(jwt.MapClaims) string
+	}{
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{name: "several audiences without azp", mutate: func(c jwt.MapClaims) { c["aud"] = []string{"students-service", "other-client"} }},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() }},
+		{name: "wrong nonce", mutate: func(c jwt.MapClaims) { c["nonce"] = "nonce-2" }},
+		{name: "no subject", mutate: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "unsigned", token: func(c jwt.MapClaims) string {
+			signed, _ := jwt.NewWithClaims(jwt.SigningMethodNone, c).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return signed
+		}},
+		{name: "signed with the client id as hmac key", token: func(c jwt.MapClaims) string {
+			signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("students-service"))
+			return signed
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			if tt.mutate != nil {
+				tt.mutate(claims)
+			}
+			raw := provider.SignIDToken(claims)
+			if tt.token != nil {
+				raw = tt.token(claims)
+			}
+			_, err := client.Verify(context.Background(), raw, "nonce-1")
+			assert.True(t, errors.Is(err, ErrInvalidIDToken), "got %v", err)
+		})
+	}
+
+	claims := valid()
+	claims["aud"] = []string{"students-service", "other-client"}
+	claims["azp"] = "students-service"
+	_, err := client.Verify(context.Background(), provider.SignIDToken(claims), "nonce-1")
+	assert.NoError(t, err, "several audiences are fine with the client as authorized party")
+}
@@ -0,0 +1,113 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// clockSkew is how far the provider's clock may be off from ours.
+const clockSkew = time.Minute
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// allowedAlgs are the ID token algorithms the client accepts. HMAC and
+// "none" are refused: a token must be signed with one of the provider's
+// published keys.
+var allowedAlgs = []string{"RS256", "RS384", "RS512", "ES256"}
+
+// Claims are the ID token claims the service uses.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+}
+
+// Verify checks the signature, issuer, audience, lifetime and nonce of a raw
+// ID token as OpenID Connect Core 3.1.3.7 requires.
+func (c *Client) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	if _, err := c.Provider(ctx); err != nil {
+		return nil, err
+	}
+
+	parser := &jwt.Parser{ValidMethods: allowedAlgs, SkipClaimsValidation: true}
+	token, err := parser.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	if iss, _ := claims["iss"].(string); iss != c.Issuer() {
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidIDToken, iss)
+	}
+	if !c.audienceValid(claims) {
+		return nil, fmt.Errorf("%w: audience", ErrInvalidIDToken)
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+	if iat, ok := claims["iat"].(float64); !ok || time.Unix(int64(iat), 0).After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce", ErrInvalidIDToken)
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.GivenName, _ = claims["given_name"].(string)
+	result.FamilyName, _ = claims["family_name"].(string)
+	result.Name, _ = claims["name"].(string)
+	if result.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	return result, nil
+}
+
+// audienceValid requires the client ID among the audiences and, when there
+// are several, as the authorized party.
+func (c *Client) audienceValid(claims jwt.MapClaims) bool {
+	var audiences []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+
+	found := false
+	for _, a := range audiences {
+		if a == c.cfg.ClientID {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != c.cfg.ClientID {
+		return false
+	}
+	return len(audiences) == 1 || claims["azp"] != nil
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fallback counts in primary and, while primary fails, in secondary. It
+// logs when it switches either way rather than on every request.
+type Fallback struct {
+	primary   Limiter
+	secondary Limiter
+	logger    *logrus.Logger
+
+	mu       sync.Mutex
+	degraded bool
+}
+
+func NewFallback(primary, secondary Limiter, logger *logrus.Logger) *Fallback {
+	return &Fallback{primary: primary, secondary: secondary, logger: logger}
+}
+
+func (f *Fallback) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	r, err := f.primary.Allow(ctx, key, limit)
+	f.mu.Lock()
+	switched := (err != nil) != f.degraded
+	f.degraded = err != nil
+	f.mu.Unlock()
+
+	if err == nil {
+		if switched {
+			f.logger.Info("Rate limiting recovered, counting in Redis again")
+		}
+		return r, nil
+	}
+	if switched {
+		f.logger.WithError(err).Error("Rate limiting failed, counting in process")
+	}
+	return f.secondary.Allow(ctx, key, limit)
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many calls Local makes between sweeps of expired
+// counters.
+const sweepEvery = 1024
+
+// Local keeps the counters in process. Each instance counts on its own, so
+// behind several instances clients get the limit once per instance.
+type Local struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	counters map[string]*counter
+	calls    int
+}
+
+type counter struct {
+	index    int64
+	previous int
+	current  int
+	expires  time.Time
+}
+
+func NewLocal() *Local {
+	return &Local{now: time.Now, counters: map[string]*counter{}}
+}
+
+func (l *Local) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := l.now()
+	index, elapsed, weight := window(now, limit)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	c, ok := l.counters[key]
+	if !ok {
+		c = &counter{index: index}
+		l.counters[key] = c
+	}
+	switch {
+	case c.index == index-1:
+		c.previous, c.current = c.current, 0
+	case c.index != index:
+		c.previous, c.current = 0, 0
+	}
+	c.index = index
+	c.expires = now.Add(2*limit.Window - elapsed)
+
+	if estimate(c.previous, c.current, weight)+1 > float64(limit.Requests) {
+		return result(limit, false, c.previous, c.current, elapsed), nil
+	}
+	c.current++
+	return result(limit, true, c.previous, c.current, elapsed), nil
+}
+
+func (l *Local) sweep(now time.Time) {
+	l.calls++
+	if l.calls < sweepEvery {
+		return
+	}
+	l.calls = 0
+	for key, c := range l.counters {
+		if now.After(c.expires) {
+			delete(l.counters, key)
+		}
+	}
+}
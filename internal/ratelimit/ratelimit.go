@@ -0,0 +1,88 @@
+// Package ratelimit counts requests in sliding windows, in Redis so the
+// limits hold across instances, or in process. The window is a sliding
+// window counter: the count of the previous fixed window, weighted by how
+// much of it still overlaps the sliding window, plus the count of the
+// current one. A request is allowed if, counting it, that estimate stays
+// within the limit. Refused requests are not counted.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result is the outcome of one request. Reset is when the current window
+// ends and RetryAfter, for refused requests, when the next one would be
+// allowed.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter counts the requests of key against limit.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// window locates now in the fixed windows of limit: the index of the
+// current window, how far into it now is, and the weight of the previous
+// window.
+func window(now time.Time, limit Limit) (index int64, elapsed time.Duration, weight float64) {
+	nanos := now.UnixNano()
+	index = nanos / int64(limit.Window)
+	elapsed = time.Duration(nanos % int64(limit.Window))
+	return index, elapsed, 1 - float64(elapsed)/float64(limit.Window)
+}
+
+func estimate(previous, current int, weight float64) float64 {
+	return float64(previous)*weight + float64(current)
+}
+
+// result builds the Result from the counts of the previous and current
+// windows, the current one including the request if it was allowed.
+func result(limit Limit, allowed bool, previous, current int, elapsed time.Duration) Result {
+	weight := 1 - float64(elapsed)/float64(limit.Window)
+	count := int(math.Ceil(estimate(previous, current, weight)))
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: limit.Requests - count,
+		Reset:     limit.Window - elapsed,
+	}
+	if r.Remaining < 0 {
+		r.Remaining = 0
+	}
+	if !allowed {
+		r.RetryAfter = retryAfter(limit, previous, current, elapsed)
+	}
+	return r
+}
+
+// retryAfter is how long until the weighted count drops below the limit.
+func retryAfter(limit Limit, previous, current int, elapsed time.Duration) time.Duration {
+	window := float64(limit.Window)
+	requests := float64(limit.Requests)
+	if current >= limit.Requests {
+		// The current window becomes the previous one and has to fade
+		// enough for one more request.
+		fraction := 1 - (requests-1)/float64(current)
+		return limit.Window - elapsed + time.Duration(fraction*window)
+	}
+	// The previous window has to fade enough.
+	fraction := 1 - (requests-1-float64(current))/float64(previous)
+	wait := time.Duration(fraction*window) - elapsed
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
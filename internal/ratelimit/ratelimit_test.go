@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock starts at the beginning of a minute.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newClock() *clock {
+	return &clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+}
+
+func TestLimiters(t *testing.T) {
+	newLimiters := map[string]func(t *testing.T, c *clock) Limiter{
+		"Local": func(t *testing.T, c *clock) Limiter {
+			l := NewLocal()
+			l.now = c.Now
+			return l
+		},
+		"Redis": func(t *testing.T, c *clock) Limiter {
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			r := NewRedis(client)
+			r.now = c.Now
+			return r
+		},
+	}
+	limit := Limit{Requests: 3, Window: time.Minute}
+	ctx := context.Background()
+
+	for name, newLimiter := range newLimiters {
+		t.Run(name, func(t *testing.T) {
+			c := newClock()
+			l := newLimiter(t, c)
+
+			for i := 0; i < 3; i++ {
+				r, err := l.Allow(ctx, "auth:ip:1.2.3.4", limit)
+				require.NoError(t, err)
+				assert.True(t, r.Allowed)
+				assert.Equal(t, 2-i, r.Remaining)
+				assert.Equal(t, time.Minute, r.Reset)
+			}
+			r, err := l.Allow(ctx, "auth:ip:1.2.3.4", limit)
+			require.NoError(t, err)
+			assert.False(t, r.Allowed)
+			assert.Zero(t, r.Remaining)
+			assert.Equal(t, time.Minute+20*time.Second, r.RetryAfter, "a third of the full window has to fade")
+
+			other, err := l.Allow(ctx, "auth:ip:5.6.7.8", limit)
+			require.NoError(t, err)
+			assert.True(t, other.Allowed, "keys are counted apart")
+
+			c.now = c.now.Add(70 * time.Second)
+			r, err = l.Allow(ctx, "auth:ip:1.2.3.4", limit)
+			require.NoError(t, err)
+			assert.False(t, r.Allowed, "the previous window still weighs 5/6")
+
+			c.now = c.now.Add(15 * time.Second)
+			r, err = l.Allow(ctx, "auth:ip:1.2.3.4", limit)
+			require.NoError(t, err)
+			assert.True(t, r.Allowed, "the previous window weighs 7/12 by now")
+
+			c.now = c.now.Add(3 * time.Minute)
+			r, err = l.Allow(ctx, "auth:ip:1.2.3.4", limit)
+			require.NoError(t, err)
+			assert.Equal(t, 2, r.Remaining, "old windows are forgotten")
+		})
+	}
+}
+
+type failingLimiter struct{ err error }
+
+func (f *failingLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, f.err
+}
+
+func TestFallback(t *testing.T) {
+	primary := &failingLimiter{err: errors.New("dial tcp: connection refused")}
+	local := NewLocal()
+	f := NewFallback(primary, local, logrus.New())
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	r, err := f.Allow(context.Background(), "api:user:s1", limit)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	r, err = f.Allow(context.Background(), "api:user:s1", limit)
+	require.NoError(t, err)
+	assert.False(t, r.Allowed, "the fallback counts while the primary fails")
+
+	primary.err = nil
+	r, err = f.Allow(context.Background(), "api:user:s1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{}, r, "the primary answers again once it recovers")
+}
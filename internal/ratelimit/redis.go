@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const keyPrefix = "ratelimit:"
+
+// allowScript checks and counts a request atomically. It returns whether
+// the request is allowed and the counts of the previous and current
+// windows.
+var allowScript = redis.NewScript(`
+local previous = tonumber(redis.call("GET", KEYS[1]) or "0")
+local current = tonumber(redis.call("GET", KEYS[2]) or "0")
+local requests = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+if previous * weight + current + 1 > requests then
+	return {0, previous, current}
+end
+current = redis.call("INCR", KEYS[2])
+if current == 1 then
+	redis.call("PEXPIRE", KEYS[2], ARGV[3])
+end
+return {1, previous, current}
+`)
+
+// Redis keeps the counters in Redis, shared by all instances.
+type Redis struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client, now: time.Now}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	index, elapsed, weight := window(r.now(), limit)
+	keys := []string{
+		keyPrefix + key + ":" + strconv.FormatInt(index-1, 10),
+		keyPrefix + key + ":" + strconv.FormatInt(index, 10),
+	}
+	// The current window is needed until the end of the next one.
+	ttl := 2 * limit.Window / time.Millisecond
+	reply, err := allowScript.Run(r.client.WithContext(ctx), keys, limit.Requests, strconv.FormatFloat(weight, 'f', 6, 64), int64(ttl)).Result()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit %s: %w", key, err)
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 3 {
+		return Result{}, fmt.Errorf("rate limit %s: unexpected reply %v", key, reply)
+	}
+	allowed, _ := values[0].(int64)
+	previous, _ := values[1].(int64)
+	current, _ := values[2].(int64)
+	return result(limit, allowed == 1, int(previous), int(current), elapsed), nil
+}
@@ -0,0 +1,50 @@
+// Package requestid carries the ID of a request in its context, so the log
+// lines and outbound calls made on behalf of the request can name it.
+package requestid
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Header carries request IDs between services.
+	Header = "X-Request-ID"
+
+	// LogField is the field of log lines that holds the request ID.
+	LogField = "requestID"
+)
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// FromContext returns the request ID of ctx, or "" if it has none.
+func FromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
+
+// Hook adds the request ID to the log entries made with WithContext, unless
+// they already name one.
+type Hook struct{}
+
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (Hook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if _, ok := entry.Data[LogField]; ok {
+		return nil
+	}
+	if requestID := FromContext(entry.Context); requestID != "" {
+		entry.Data[LogField] = requestID
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHook(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	logger.AddHook(Hook{})
+
+	ctx := NewContext(context.Background(), "req-1")
+	logger.WithContext(ctx).Info("with context")
+	assert.Contains(t, out.String(), "requestID=req-1")
+
+	out.Reset()
+	logger.WithContext(ctx).WithField(LogField, "req-2").Info("explicit ID")
+	assert.Contains(t, out.String(), "requestID=req-2")
+	assert.NotContains(t, out.String(), "req-1")
+
+	out.Reset()
+	logger.WithContext(context.Background()).Info("no ID")
+	logger.Info("no context")
+	assert.NotContains(t, out.String(), LogField)
+}